@@ -0,0 +1,78 @@
+package http
+
+import (
+	"net/http"
+	"net/url"
+)
+
+// APIKeyLocation is where WithAPIKey sends the key.
+type APIKeyLocation int
+
+const (
+	// APIKeyInHeader sends the key as a request header.
+	APIKeyInHeader APIKeyLocation = iota
+	// APIKeyInQuery sends the key as a query parameter.
+	APIKeyInQuery
+)
+
+type apiKey struct {
+	key  string
+	in   APIKeyLocation
+	name string
+}
+
+//
+// WithAPIKey will send key in the header or query parameter called name,
+// replacing the client's WithDefaultAPIKey.
+//
+// The key is redacted from DryRun and Explain output, and from the URL of
+// transport errors.
+//
+func WithAPIKey(key string, in APIKeyLocation, name string) RequestOption {
+	return func(r *Request) {
+		r.apiKey = &apiKey{key: key, in: in, name: name}
+	}
+}
+
+// WithDefaultAPIKey will send key, like WithAPIKey, with every request of the client that does not set its own.
+func WithDefaultAPIKey(key string, in APIKeyLocation, name string) ClientOption {
+	return func(c *clientConfig) {
+		c.apiKey = &apiKey{key: key, in: in, name: name}
+	}
+}
+
+// params returns the query parameters to send, adding the key to a copy of params if it goes in the query.
+func (k *apiKey) params(params url.Values) url.Values {
+	if k == nil || k.in != APIKeyInQuery {
+		return params
+	}
+	ret := make(url.Values, len(params)+1)
+	for name, vs := range params {
+		ret[name] = append([]string(nil), vs...)
+	}
+	ret.Set(k.name, k.key)
+	return ret
+}
+
+// setHeader adds the key to h if it goes in a header.
+func (k *apiKey) setHeader(h http.Header) {
+	if k != nil && k.in == APIKeyInHeader {
+		h.Set(k.name, k.key)
+	}
+}
+
+// secretHeader returns the name of the header holding the key, if any.
+func (k *apiKey) secretHeader() string {
+	if k != nil && k.in == APIKeyInHeader {
+		return k.name
+	}
+	return ""
+}
+
+// secretParam returns the name of the query parameter holding the key, if any.
+func (k *apiKey) secretParam() string {
+	if k != nil && k.in == APIKeyInQuery {
+		return k.name
+	}
+	return ""
+}
@@ -0,0 +1,68 @@
+package http
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+)
+
+// basicAuth returns the Authorization header value for HTTP Basic authentication, as (*http.Request).SetBasicAuth sets it.
+func basicAuth(username, password string) string {
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte(username+":"+password))
+}
+
+// WithBasicAuth will authenticate the request with HTTP Basic authentication, replacing any Authorization header.
+func WithBasicAuth(username, password string) RequestOption {
+	return func(r *Request) {
+		r.Header.Set("Authorization", basicAuth(username, password))
+	}
+}
+
+// WithBearerToken will authenticate the request with the Bearer token, replacing any Authorization header.
+func WithBearerToken(token string) RequestOption {
+	return func(r *Request) {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+}
+
+//
+// WithDefaultBasicAuth will authenticate every request of the client with
+// HTTP Basic authentication.
+//
+// A request can replace the credentials with WithBasicAuth.
+//
+func WithDefaultBasicAuth(username, password string) ClientOption {
+	return func(c *clientConfig) {
+		c.authorization = basicAuth(username, password)
+	}
+}
+
+//
+// WithTokenSource will call source before every request of the client to
+// get a Bearer token for its Authorization header, so that rotating tokens
+// are never stale.
+//
+// source is called concurrently by concurrent requests. Requests that
+// already set Authorization, e.g. with WithBearerToken, skip it, and its
+// error fails the request.
+//
+func WithTokenSource(source func(ctx context.Context) (string, error)) ClientOption {
+	return func(c *clientConfig) {
+		c.tokenSource = source
+	}
+}
+
+// authorize sets the Authorization header h of req from the token source of the client, if it has none.
+func (c *client) authorize(ctx context.Context, req *Request, h http.Header) error {
+	if c.config.tokenSource == nil || h.Get("Authorization") != "" {
+		return nil
+	}
+	token, err := c.config.tokenSource(ctx)
+	if err != nil {
+		return fmt.Errorf("getting token for %s: %w", req.URL, err)
+	}
+	h.Set("Authorization", "Bearer "+token)
+	req.tokenAuthorized = true
+	return nil
+}
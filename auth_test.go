@@ -0,0 +1,261 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWithBasicAuth(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Values("Authorization"); len(got) != 1 {
+			t.Errorf("Authorization = %q, want one value", got)
+		}
+		user, pass, _ := r.BasicAuth()
+		w.Write([]byte(user + ":" + pass))
+	}))
+	defer srv.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	tests := []struct {
+		name    string
+		cli     Client
+		options []RequestOption
+		want    string
+	}{
+		{"request", NewClient(), []RequestOption{WithHeader("Authorization", "Bearer token"), WithBasicAuth("alice", "pa:ss")}, "alice:pa:ss"},
+		{"client", NewClient(WithDefaultBasicAuth("svc", "secret")), nil, "svc:secret"},
+		{"request overrides client", NewClient(WithDefaultBasicAuth("svc", "secret")), []RequestOption{WithBasicAuth("bob", "")}, "bob:"},
+	}
+	for _, tt := range tests {
+		var got string
+		if err := tt.cli.Get(ctx, srv.URL, append(tt.options, WithStringResponse(&got))...); err != nil || got != tt.want {
+			t.Errorf("%s: Get() = %q, %v, want %q", tt.name, got, err, tt.want)
+		}
+	}
+
+	// A mock client sends the client credentials too.
+	for _, tt := range tests[1:] {
+		var got *http.Request
+		mock := NewMockClient(func(ctx context.Context, r *Request) error {
+			got = &http.Request{Header: r.Header}
+			return nil
+		}, WithDefaultBasicAuth("svc", "secret"))
+		if err := mock.Get(ctx, "http://example.com", tt.options...); err != nil {
+			t.Fatalf("mock %s: Get() error = %v", tt.name, err)
+		}
+		if user, pass, _ := got.BasicAuth(); user+":"+pass != tt.want {
+			t.Errorf("mock %s: Authorization = %q, want %q", tt.name, got.Header.Get("Authorization"), tt.want)
+		}
+	}
+}
+
+func TestWithBearerToken(t *testing.T) {
+	t.Parallel()
+	pr, err := NewClient().DryRun(context.Background(), "GET", "http://example.com", WithBearerToken("old"), WithBearerToken("s3cret"))
+	if err != nil {
+		t.Fatalf("DryRun() error = %v", err)
+	}
+	if got := pr.Header.Values("Authorization"); len(got) != 1 || got[0] != redacted {
+		t.Errorf("DryRun() Authorization = %q, want one redacted value", got)
+	}
+
+	req, err := NewClient().(*client).newRequest("GET", "http://example.com", []RequestOption{WithBearerToken("old"), WithBearerToken("s3cret")})
+	if err != nil || req.Header.Get("Authorization") != "Bearer s3cret" {
+		t.Errorf("Authorization = %q, %v, want Bearer s3cret", req.Header.Get("Authorization"), err)
+	}
+}
+
+func TestWithTokenSource(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Header.Get("Authorization")))
+	}))
+	defer srv.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	var calls int32
+	cli := NewClient(WithTokenSource(func(ctx context.Context) (string, error) {
+		return fmt.Sprintf("token-%d", atomic.AddInt32(&calls, 1)), nil
+	}))
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var got string
+			if err := cli.Get(ctx, srv.URL, WithStringResponse(&got)); err != nil || !strings.HasPrefix(got, "Bearer token-") {
+				t.Errorf("Get() = %q, %v, want a fresh Bearer token", got, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if calls != 10 {
+		t.Errorf("token source called %d times, want 10", calls)
+	}
+
+	var got string
+	if err := cli.Get(ctx, srv.URL, WithBearerToken("explicit"), WithStringResponse(&got)); err != nil || got != "Bearer explicit" || calls != 10 {
+		t.Errorf("Get() = %q, %v after %d token source calls, want the explicit token", got, err, calls)
+	}
+
+	errExpired := errors.New("refresh token expired")
+	cli = NewClient(WithTokenSource(func(ctx context.Context) (string, error) {
+		return "", errExpired
+	}))
+	if err := cli.Get(ctx, srv.URL); !errors.Is(err, errExpired) {
+		t.Errorf("Get() error = %v, want %v", err, errExpired)
+	}
+}
+
+func TestWithAPIKey(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%s|%s|%s", r.Header.Get("X-Api-Key"), r.URL.Query().Get("api_key"), r.URL.Query().Get("q"))
+	}))
+	defer srv.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	cli := NewClient(WithDefaultAPIKey("default-key", APIKeyInHeader, "X-Api-Key"))
+	tests := []struct {
+		name    string
+		options []RequestOption
+		want    string
+	}{
+		{"client default", nil, "default-key||"},
+		{"query override", []RequestOption{WithParam("q", "1"), WithAPIKey("query-key", APIKeyInQuery, "api_key")}, "|query-key|1"},
+		{"header override", []RequestOption{WithAPIKey("header-key", APIKeyInHeader, "X-Api-Key")}, "header-key||"},
+	}
+	for _, tt := range tests {
+		var got string
+		if err := cli.Get(ctx, srv.URL, append(tt.options, WithStringResponse(&got))...); err != nil || got != tt.want {
+			t.Errorf("%s: Get() = %q, %v, want %q", tt.name, got, err, tt.want)
+		}
+	}
+}
+
+func TestWithAPIKey_redacted(t *testing.T) {
+	t.Parallel()
+	cli := NewClient(WithDefaultAPIKey("header-secret", APIKeyInHeader, "X-Api-Key"))
+	pr, err := cli.DryRun(context.Background(), "GET", "http://example.com/")
+	if err != nil || pr.Header.Get("X-Api-Key") != redacted {
+		t.Errorf("DryRun() header = %v, %v, want X-Api-Key redacted", pr.Header, err)
+	}
+
+	query := WithAPIKey("query-secret", APIKeyInQuery, "key")
+	pr, err = cli.DryRun(context.Background(), "GET", "http://example.com/", WithParam("q", "1"), query)
+	if err != nil || strings.Contains(pr.URL, "query-secret") || !strings.Contains(pr.URL, "q=1") {
+		t.Errorf("DryRun() URL = %s, %v, want the key redacted", pr.URL, err)
+	}
+
+	err = cli.Get(context.Background(), "http://127.0.0.1:1/", query)
+	if err == nil || strings.Contains(err.Error(), "query-secret") {
+		t.Errorf("Get() error = %v, want a transport error without the key", err)
+	}
+}
+
+func TestWithReauth(t *testing.T) {
+	t.Parallel()
+	var current atomic.Value
+	current.Store("token-1")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if r.Header.Get("Authorization") != "Bearer token-2" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write(body)
+	}))
+	defer srv.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	var reauths int32
+	release := make(chan struct{})
+	cli := NewClient(
+		WithTokenSource(func(ctx context.Context) (string, error) {
+			return current.Load().(string), nil
+		}),
+		WithReauth(func(ctx context.Context) error {
+			atomic.AddInt32(&reauths, 1)
+			<-release
+			current.Store("token-2")
+			return nil
+		}))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			var got map[string]int
+			if err := cli.Post(ctx, srv.URL, WithJSONBody(map[string]int{"i": i}), WithJSONResponse(&got)); err != nil || got["i"] != i {
+				t.Errorf("Post(%d) = %v, %v, want the replayed body", i, got, err)
+			}
+		}(i)
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	if reauths != 1 {
+		t.Errorf("reauth called %d times, want once for the burst", reauths)
+	}
+
+	stream := struct{ *strings.Reader }{strings.NewReader("streamed")}
+	current.Store("token-1")
+	var bse *BadStatusError
+	if err := cli.Post(ctx, srv.URL, WithBodyReader(stream, -1)); !errors.As(err, &bse) || bse.Code != http.StatusUnauthorized {
+		t.Errorf("Post() error = %v, want the original 401 for a streamed body", err)
+	}
+}
+
+func TestWithReauth_replayOptions(t *testing.T) {
+	t.Parallel()
+	var replays, followed int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/elsewhere":
+			atomic.AddInt32(&followed, 1)
+		case r.Header.Get("Authorization") != "Bearer token-2":
+			w.WriteHeader(http.StatusUnauthorized)
+		case atomic.AddInt32(&replays, 1) == 1:
+			// The first replay fails, to be retried by the request's policy.
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			http.Redirect(w, r, "/elsewhere", http.StatusFound)
+		}
+	}))
+	defer srv.Close()
+
+	var current atomic.Value
+	current.Store("token-1")
+	cli := NewClient(
+		WithTokenSource(func(ctx context.Context) (string, error) {
+			return current.Load().(string), nil
+		}),
+		WithReauth(func(ctx context.Context) error {
+			current.Store("token-2")
+			return nil
+		}))
+
+	var status int
+	err := cli.Get(context.Background(), srv.URL, WithRetries(2, WithRetryBackoff(time.Millisecond, time.Millisecond)), WithNoRedirects(), WithExpectStatus(http.StatusFound), WithStatusCode(&status))
+	if err != nil || status != http.StatusFound {
+		t.Errorf("Get() = %d, %v, want the retried replay's 302 unfollowed", status, err)
+	}
+	if n := atomic.LoadInt32(&followed); n != 0 {
+		t.Errorf("the replay followed the redirect %d times, want WithNoRedirects to apply to it", n)
+	}
+}
@@ -0,0 +1,78 @@
+package http
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+//
+// WithBaseURL will resolve the relative URLs given to Get, Post and the other
+// methods against baseURL, so that call sites can pass paths such as
+// "/users/42". The path is appended to the path of baseURL, keeping a prefix
+// such as /v2 whether or not either side has a slash. Absolute URLs are used
+// unchanged.
+//
+// A malformed or relative baseURL makes NewClient panic.
+//
+func WithBaseURL(baseURL string) ClientOption {
+	u, err := parseBaseURL(baseURL)
+	return func(c *clientConfig) {
+		if err != nil {
+			panic("invalid base URL: " + err.Error())
+		}
+		c.baseURL = u
+	}
+}
+
+// parseBaseURL parses an absolute URL to resolve relative URLs against.
+func parseBaseURL(baseURL string) (*url.URL, error) {
+	u, err := url.Parse(baseURL)
+	if err == nil && (!u.IsAbs() || u.Host == "") {
+		err = fmt.Errorf("%q is not an absolute URL", baseURL)
+	}
+	return u, err
+}
+
+// resolveURL returns rawURL resolved against the next WithEndpoints endpoint, taking its turn if take is set, or the WithBaseURL URL, if any.
+func (c *client) resolveURL(rawURL string, take bool) string {
+	switch {
+	case c.config.endpoints != nil && isRelativeURL(rawURL):
+		return resolveURL(c.pickEndpoint(take), rawURL)
+	case c.config.baseURL != nil:
+		return resolveURL(c.config.baseURL, rawURL)
+	}
+	return rawURL
+}
+
+// isRelativeURL reports whether rawURL is a valid URL without a scheme or host.
+func isRelativeURL(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	return err == nil && !u.IsAbs() && u.Host == ""
+}
+
+// resolveURL returns rawURL resolved against base, unless it is absolute.
+func resolveURL(base *url.URL, rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.IsAbs() || u.Host != "" {
+		// Invalid URLs are reported when the request is sent.
+		return rawURL
+	}
+
+	resolved := *base
+	resolved.Fragment = u.Fragment
+	if u.Path != "" {
+		joined := strings.TrimSuffix(base.EscapedPath(), "/") + "/" + strings.TrimPrefix(u.EscapedPath(), "/")
+		if resolved.Path, err = url.PathUnescape(joined); err != nil {
+			return rawURL
+		}
+		resolved.RawPath = joined
+	}
+	if u.RawQuery != "" {
+		if resolved.RawQuery != "" {
+			resolved.RawQuery += "&"
+		}
+		resolved.RawQuery += u.RawQuery
+	}
+	return resolved.String()
+}
@@ -0,0 +1,70 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWithBaseURL_resolve(t *testing.T) {
+	t.Parallel()
+	for _, tt := range []struct {
+		base, url, want string
+	}{
+		{base: "https://api.example.com/v2", url: "/users/42", want: "https://api.example.com/v2/users/42"},
+		{base: "https://api.example.com/v2/", url: "/users/42", want: "https://api.example.com/v2/users/42"},
+		{base: "https://api.example.com/v2/", url: "users/42", want: "https://api.example.com/v2/users/42"},
+		{base: "https://api.example.com/v2", url: "users/42/", want: "https://api.example.com/v2/users/42/"},
+		{base: "https://api.example.com", url: "/users", want: "https://api.example.com/users"},
+		{base: "https://api.example.com/v2", url: "/users?page=2&q=a%20b", want: "https://api.example.com/v2/users?page=2&q=a%20b"},
+		{base: "https://api.example.com/v2?key=k", url: "/users?page=2", want: "https://api.example.com/v2/users?key=k&page=2"},
+		{base: "https://api.example.com/v2", url: "?page=2", want: "https://api.example.com/v2?page=2"},
+		{base: "https://api.example.com/v2", url: "", want: "https://api.example.com/v2"},
+		{base: "https://api.example.com/v2/", url: "", want: "https://api.example.com/v2/"},
+		{base: "https://api.example.com/v2", url: "/files/a%2Fb", want: "https://api.example.com/v2/files/a%2Fb"},
+		{base: "https://api.example.com/v2", url: "http://other.example.com/x", want: "http://other.example.com/x"},
+		{base: "https://api.example.com/v2", url: "//other.example.com/x", want: "//other.example.com/x"},
+	} {
+		c := newClient(http.Client{}, []ClientOption{WithBaseURL(tt.base)})
+		if got := c.resolveURL(tt.url, true); got != tt.want {
+			t.Errorf("resolveURL(%q) against %q = %q, want %q", tt.url, tt.base, got, tt.want)
+		}
+	}
+}
+
+func TestWithBaseURL(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.URL.RequestURI()))
+	}))
+	defer srv.Close()
+	ctx := context.Background()
+	cli := NewClient(WithBaseURL(srv.URL + "/v2/"))
+
+	var got string
+	if err := cli.Get(ctx, "/users/42", WithParam("fields", "name"), WithStringResponse(&got)); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if got != "/v2/users/42?fields=name" {
+		t.Errorf("request URI = %q, want /v2/users/42?fields=name", got)
+	}
+	if err := cli.Get(ctx, srv.URL+"/other", WithStringResponse(&got)); err != nil || got != "/other" {
+		t.Errorf("Get() of absolute URL = %q, %v, want /other", got, err)
+	}
+}
+
+func TestWithBaseURL_invalid(t *testing.T) {
+	t.Parallel()
+	for _, base := range []string{"/v2", "api.example.com/v2", "https://api.example.com/%zz"} {
+		func() {
+			defer func() {
+				if p := recover(); p == nil || !strings.Contains(p.(string), "invalid base URL") {
+					t.Errorf("NewClient(WithBaseURL(%q)) panic = %v, want invalid base URL", base, p)
+				}
+			}()
+			NewClient(WithBaseURL(base))
+		}()
+	}
+}
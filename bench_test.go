@@ -0,0 +1,72 @@
+package http
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// cannedTransport answers every request with the same response without touching the network.
+type cannedTransport struct {
+	body string
+}
+
+func (ct cannedTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	if r.Body != nil {
+		ioutil.ReadAll(r.Body)
+		r.Body.Close()
+	}
+	return &http.Response{
+		StatusCode:    http.StatusOK,
+		Header:        http.Header{"Content-Type": {"application/json"}},
+		Body:          ioutil.NopCloser(strings.NewReader(ct.body)),
+		ContentLength: int64(len(ct.body)),
+		Request:       r,
+	}, nil
+}
+
+func BenchmarkGet_minimal(b *testing.B) {
+	cli := newClient(http.Client{Transport: cannedTransport{}}, nil)
+	ctx := context.Background()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if err := cli.Get(ctx, "http://example.com/items"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkGet_json_response(b *testing.B) {
+	cli := newClient(http.Client{Transport: cannedTransport{body: `{"id":1,"name":"item"}`}}, nil)
+	ctx := context.Background()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var resp struct {
+			ID   int
+			Name string
+		}
+		if err := cli.Get(ctx, "http://example.com/items",
+			WithHeader("Authorization", "Bearer token"),
+			WithHeader("X-Request-Id", "abc"),
+			WithParam("page", "1"),
+			WithJSONResponse(&resp)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkPost_json_body(b *testing.B) {
+	cli := newClient(http.Client{Transport: cannedTransport{}}, nil)
+	ctx := context.Background()
+	body := map[string]string{"name": "item"}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if err := cli.Post(ctx, "http://example.com/items",
+			WithHeader("Authorization", "Bearer token"),
+			WithJSONBody(body)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,286 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is the error of a request refused by NewCircuitBreakerClient without being sent.
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+// BreakerState is the state of one circuit of NewCircuitBreakerClient.
+type BreakerState int
+
+const (
+	// BreakerClosed lets requests through, counting failures.
+	BreakerClosed BreakerState = iota
+	// BreakerOpen refuses requests until the cooldown ends.
+	BreakerOpen
+	// BreakerHalfOpen lets a few trial requests through to decide whether to close again.
+	BreakerHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	}
+	return fmt.Sprintf("BreakerState(%d)", int(s))
+}
+
+// BreakerConfig controls NewCircuitBreakerClient.
+type BreakerConfig struct {
+	// FailureThreshold is the number of consecutive failures opening a circuit. Zero means 5.
+	FailureThreshold int
+	// Cooldown is how long a circuit stays open before trial requests. Zero means 30s.
+	Cooldown time.Duration
+	// HalfOpenRequests is the number of trial requests that must all succeed to close a circuit. Zero means 1.
+	HalfOpenRequests int
+	// Key returns the circuit of a request URL. Nil means its host, with relative URLs sharing one circuit.
+	Key func(rawURL string) string
+	// IsFailure reports whether a request error counts against its circuit. Nil means DefaultBreakerFailure.
+	IsFailure func(err error) bool
+	// IsNeutral reports whether a request error counts neither for nor against its circuit. Nil means DefaultBreakerNeutral.
+	IsNeutral func(err error) bool
+	// OnStateChange, if set, is called when the circuit of key changes state.
+	OnStateChange func(key string, from, to BreakerState)
+}
+
+//
+// DefaultBreakerFailure reports whether err shows the server failing: a
+// transport error, a timeout, or a 5xx *BadStatusError.
+//
+// Other statuses are not failures, as the server handled the request.
+// Decoding errors, invalid options and requests canceled by the caller are
+// not either; DefaultBreakerNeutral leaves them out of the count.
+//
+func DefaultBreakerFailure(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, ErrInvalidOption) || errors.Is(err, ErrConflictingOptions) {
+		return false
+	}
+	var bse *BadStatusError
+	if errors.As(err, &bse) {
+		return bse.Code >= 500
+	}
+	var de *DecodeError
+	return !errors.As(err, &de)
+}
+
+//
+// DefaultBreakerNeutral reports whether err says nothing of the health of the
+// server: a request canceled by the caller, invalid options, or a body that
+// could not be decoded.
+//
+// These requests neither count as failures nor reset the failure count, and
+// a neutral trial request of a half-open circuit frees its place for another.
+//
+func DefaultBreakerNeutral(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, ErrInvalidOption) || errors.Is(err, ErrConflictingOptions) {
+		return true
+	}
+	var de *DecodeError
+	return errors.As(err, &de)
+}
+
+//
+// NewCircuitBreakerClient wraps inner to stop sending requests to a server
+// that keeps failing.
+//
+// Each host has a circuit. After cfg.FailureThreshold consecutive failures
+// it opens, and requests fail at once with an error wrapping ErrCircuitOpen.
+// Once cfg.Cooldown has passed it lets cfg.HalfOpenRequests trial requests
+// through: it closes if they all succeed, and opens again on the first
+// failure.
+//
+// cfg.Key can group requests differently, e.g. by base URL, and
+// cfg.IsFailure and cfg.IsNeutral can change which errors count as failures
+// and which are ignored.
+// cfg.OnStateChange reports every transition, e.g. to export metrics.
+//
+func NewCircuitBreakerClient(inner Client, cfg BreakerConfig) Client {
+	if cfg.FailureThreshold <= 0 {
+		cfg.FailureThreshold = 5
+	}
+	if cfg.Cooldown <= 0 {
+		cfg.Cooldown = 30 * time.Second
+	}
+	if cfg.HalfOpenRequests <= 0 {
+		cfg.HalfOpenRequests = 1
+	}
+	if cfg.Key == nil {
+		cfg.Key = hostKey
+	}
+	if cfg.IsFailure == nil {
+		cfg.IsFailure = DefaultBreakerFailure
+	}
+	if cfg.IsNeutral == nil {
+		cfg.IsNeutral = DefaultBreakerNeutral
+	}
+	return &breakerClient{Client: inner, cfg: cfg, circuits: map[string]*circuit{}, now: time.Now}
+}
+
+// hostKey returns the host of rawURL, or "" if it has none.
+func hostKey(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return u.Host
+}
+
+type breakerClient struct {
+	Client
+	cfg BreakerConfig
+	now func() time.Time
+
+	mu       sync.Mutex
+	circuits map[string]*circuit
+}
+
+type circuit struct {
+	state    BreakerState
+	failures int
+	openedAt time.Time
+	// trials and successes count the trial requests of a half-open circuit.
+	trials    int
+	successes int
+	// generation changes with the state, so results of requests let through in an earlier state are ignored.
+	generation int
+}
+
+// transition is a state change to report to OnStateChange.
+type transition struct {
+	from, to BreakerState
+}
+
+func (bc *breakerClient) Get(ctx context.Context, url string, options ...RequestOption) error {
+	return bc.guard(url, func() error { return bc.Client.Get(ctx, url, options...) })
+}
+
+func (bc *breakerClient) Post(ctx context.Context, url string, options ...RequestOption) error {
+	return bc.guard(url, func() error { return bc.Client.Post(ctx, url, options...) })
+}
+
+func (bc *breakerClient) Do(ctx context.Context, method, url string, options ...RequestOption) error {
+	return bc.guard(url, func() error { return bc.Client.Do(ctx, method, url, options...) })
+}
+
+// guard sends a request with send if the circuit of url allows it, and records the outcome.
+func (bc *breakerClient) guard(url string, send func() error) error {
+	key := bc.cfg.Key(url)
+	generation, t, err := bc.admit(key)
+	bc.notify(key, t)
+	if err != nil {
+		return err
+	}
+	err = send()
+	result := outcomeSuccess
+	switch {
+	case bc.cfg.IsNeutral(err):
+		result = outcomeNeutral
+	case bc.cfg.IsFailure(err):
+		result = outcomeFailure
+	}
+	bc.notify(key, bc.record(key, generation, result))
+	return err
+}
+
+// outcome is how the result of a request counts for its circuit.
+type outcome int
+
+const (
+	outcomeSuccess outcome = iota
+	outcomeFailure
+	// outcomeNeutral frees the place of a trial request without counting it.
+	outcomeNeutral
+)
+
+// admit returns the generation of the circuit of key if it lets a request through, or an error wrapping ErrCircuitOpen.
+func (bc *breakerClient) admit(key string) (int, *transition, error) {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	c := bc.circuits[key]
+	if c == nil {
+		c = &circuit{}
+		bc.circuits[key] = c
+	}
+
+	var t *transition
+	if c.state == BreakerOpen && bc.now().Sub(c.openedAt) >= bc.cfg.Cooldown {
+		t = c.setState(BreakerHalfOpen)
+	}
+	switch c.state {
+	case BreakerOpen:
+		return 0, t, fmt.Errorf("%w for %s", ErrCircuitOpen, breakerName(key))
+	case BreakerHalfOpen:
+		if c.trials >= bc.cfg.HalfOpenRequests {
+			return 0, t, fmt.Errorf("%w for %s, awaiting trial requests", ErrCircuitOpen, breakerName(key))
+		}
+		c.trials++
+	}
+	return c.generation, t, nil
+}
+
+// record counts the outcome of a request admitted at generation.
+func (bc *breakerClient) record(key string, generation int, result outcome) *transition {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	c := bc.circuits[key]
+	if c.generation != generation {
+		return nil
+	}
+	switch {
+	case result == outcomeNeutral:
+		if c.state == BreakerHalfOpen {
+			c.trials--
+		}
+	case c.state == BreakerClosed && result == outcomeSuccess:
+		c.failures = 0
+	case c.state == BreakerClosed:
+		if c.failures++; c.failures >= bc.cfg.FailureThreshold {
+			c.openedAt = bc.now()
+			return c.setState(BreakerOpen)
+		}
+	case c.state == BreakerHalfOpen && result == outcomeFailure:
+		c.openedAt = bc.now()
+		return c.setState(BreakerOpen)
+	case c.state == BreakerHalfOpen:
+		if c.successes++; c.successes >= bc.cfg.HalfOpenRequests {
+			return c.setState(BreakerClosed)
+		}
+	}
+	return nil
+}
+
+func (c *circuit) setState(state BreakerState) *transition {
+	t := &transition{from: c.state, to: state}
+	c.state = state
+	c.failures, c.trials, c.successes = 0, 0, 0
+	c.generation++
+	return t
+}
+
+// notify reports t to OnStateChange, outside the lock so the callback may use the client.
+func (bc *breakerClient) notify(key string, t *transition) {
+	if t != nil && bc.cfg.OnStateChange != nil {
+		bc.cfg.OnStateChange(key, t.from, t.to)
+	}
+}
+
+func breakerName(key string) string {
+	if key == "" {
+		return "relative URLs"
+	}
+	return key
+}
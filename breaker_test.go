@@ -0,0 +1,157 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreakerClient(t *testing.T) {
+	t.Parallel()
+	var status int32 = http.StatusInternalServerError
+	var hits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.WriteHeader(int(atomic.LoadInt32(&status)))
+	}))
+	defer srv.Close()
+	ctx := context.Background()
+	clock := newFakeClock()
+
+	var transitions []string
+	c := NewCircuitBreakerClient(NewClient(), BreakerConfig{
+		FailureThreshold: 3,
+		Cooldown:         time.Minute,
+		OnStateChange: func(key string, from, to BreakerState) {
+			if key != srv.Listener.Addr().String() {
+				t.Errorf("OnStateChange() key = %q, want the server host", key)
+			}
+			transitions = append(transitions, from.String()+"->"+to.String())
+		},
+	})
+	c.(*breakerClient).now = clock.Now
+
+	// Closed: failures go through until the threshold opens the circuit.
+	for i := 0; i < 3; i++ {
+		if err := c.Get(ctx, srv.URL); errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("Get() #%d error = %v while closed", i+1, err)
+		}
+	}
+	// Open: refused without reaching the server.
+	if err := c.Get(ctx, srv.URL); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Get() error = %v while open, want ErrCircuitOpen", err)
+	}
+	if n := atomic.LoadInt32(&hits); n != 3 {
+		t.Errorf("server got %d requests, want 3", n)
+	}
+
+	// Half-open: a failing trial opens the circuit again.
+	clock.Advance(time.Minute)
+	if err := c.Get(ctx, srv.URL); errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Get() error = %v after the cooldown, want a trial request", err)
+	}
+	if err := c.Get(ctx, srv.URL); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Get() error = %v after a failed trial, want ErrCircuitOpen", err)
+	}
+
+	// Half-open: a successful trial closes it.
+	clock.Advance(time.Minute)
+	atomic.StoreInt32(&status, http.StatusOK)
+	for i := 0; i < 2; i++ {
+		if err := c.Get(ctx, srv.URL); err != nil {
+			t.Errorf("Get() #%d error = %v, want success once closed", i+1, err)
+		}
+	}
+
+	want := []string{"closed->open", "open->half-open", "half-open->open", "open->half-open", "half-open->closed"}
+	if len(transitions) != len(want) {
+		t.Fatalf("transitions = %v, want %v", transitions, want)
+	}
+	for i := range want {
+		if transitions[i] != want[i] {
+			t.Errorf("transitions = %v, want %v", transitions, want)
+			break
+		}
+	}
+}
+
+func TestCircuitBreakerClientClassification(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	for _, tt := range []struct {
+		name     string
+		status   int
+		cfg      BreakerConfig
+		wantOpen bool
+	}{
+		{name: "5xx fails", status: http.StatusServiceUnavailable, wantOpen: true},
+		{name: "4xx succeeds", status: http.StatusNotFound},
+		{name: "custom", status: http.StatusTooManyRequests, cfg: BreakerConfig{IsFailure: func(err error) bool {
+			var bse *BadStatusError
+			return errors.As(err, &bse) && bse.Code == http.StatusTooManyRequests
+		}}, wantOpen: true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewMockClient(func(ctx context.Context, req *Request) error {
+				return &BadStatusError{Code: tt.status}
+			})
+			tt.cfg.FailureThreshold = 2
+			bc := NewCircuitBreakerClient(c, tt.cfg)
+			bc.Get(ctx, "http://a.example/x")
+			bc.Get(ctx, "http://a.example/y")
+			err := bc.Get(ctx, "http://a.example/z")
+			if got := errors.Is(err, ErrCircuitOpen); got != tt.wantOpen {
+				t.Errorf("Get() error = %v, want circuit open %v", err, tt.wantOpen)
+			}
+			// Other hosts have their own circuit.
+			if err := bc.Get(ctx, "http://b.example/"); errors.Is(err, ErrCircuitOpen) {
+				t.Errorf("Get() error = %v for another host, want its circuit closed", err)
+			}
+		})
+	}
+}
+
+func TestCircuitBreakerClientNeutral(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	var result error
+	c := NewCircuitBreakerClient(NewMockClient(func(ctx context.Context, req *Request) error {
+		return result
+	}), BreakerConfig{FailureThreshold: 2, Cooldown: time.Minute})
+	clock := newFakeClock()
+	c.(*breakerClient).now = clock.Now
+
+	failure := &BadStatusError{Code: http.StatusServiceUnavailable}
+	canceled := fmt.Errorf("sending: %w", context.Canceled)
+
+	// Closed: a cancellation between failures does not reset their count.
+	for _, err := range []error{failure, canceled, failure} {
+		result = err
+		c.Get(ctx, "http://a.example/")
+	}
+	if err := c.Get(ctx, "http://a.example/"); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Get() error = %v, want the circuit opened by the failures around the cancellation", err)
+	}
+
+	// Half-open: a canceled trial neither closes the circuit nor keeps its place.
+	clock.Advance(time.Minute)
+	result = canceled
+	if err := c.Get(ctx, "http://a.example/"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Get() error = %v, want the canceled trial", err)
+	}
+	if state := c.(*breakerClient).circuits["a.example"].state; state != BreakerHalfOpen {
+		t.Errorf("state after a canceled trial = %v, want half-open", state)
+	}
+	result = failure
+	if err := c.Get(ctx, "http://a.example/"); errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Get() error = %v, want another trial in place of the canceled one", err)
+	}
+	if err := c.Get(ctx, "http://a.example/"); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Get() error = %v after a failed trial, want ErrCircuitOpen", err)
+	}
+}
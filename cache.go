@@ -0,0 +1,405 @@
+package http
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+//
+// Cache stores responses for WithCache, by a key naming the method and URL
+// of the request.
+//
+// Implementations must be safe for concurrent use. The client does not
+// change a CachedResponse after storing it, and callers must not either.
+//
+type Cache interface {
+	Get(key string) (*CachedResponse, bool)
+	Set(key string, resp *CachedResponse)
+	Delete(key string)
+}
+
+// CachedResponse is a response stored in a Cache.
+type CachedResponse struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+	// Expires is when the response stops being fresh. It is zero for a response always revalidated.
+	Expires time.Time
+}
+
+//
+// WithCache will answer GET requests from responses stored in cache while
+// they are fresh, as their Cache-Control max-age or Expires header allows,
+// instead of sending them. Outputs receive a cached response as they would
+// the original one.
+//
+// Responses with no-store, with bodies over 1 MiB or the WithMaxResponseBytes
+// limit, or to other methods, are not stored, and neither are responses
+// without an expiry unless they have an ETag or Last-Modified validator.
+// Once a response with a validator is stale, or has no-cache, the request
+// is sent with If-None-Match or If-Modified-Since: a 304 Not Modified
+// refreshes the stored response and answers the request with it, as if the
+// server had sent it again. A malformed Last-Modified, or one later than
+// the Date of the response, is ignored, and without an ETag the request is
+// sent unconditionally. Requests with conditional headers of their own,
+// such as from WithConditional, or for a Range get the server's answer
+// unchanged, and signed requests, with WithRequestSigner, always go to the
+// server.
+//
+// A request with Cache-Control no-store is sent whatever is stored, and one
+// with no-cache is revalidated. Vary is ignored, so requests to the same URL
+// with different headers share the stored response, unless they have
+// different credentials, such as an Authorization header or an API key.
+//
+// Responses to the requests using the cache get a Cache-Status header,
+// which WithResponseHeaders can read: "gohttp; hit" when answered from the
+// cache, and "gohttp; fwd=stale; fwd-status=304" when revalidated.
+//
+// A nil cache means a MemoryCache of 1000 responses.
+//
+func WithCache(cache Cache) ClientOption {
+	return func(c *clientConfig) {
+		if cache == nil {
+			cache = NewMemoryCache(defaultCacheEntries)
+		}
+		c.cache = cache
+	}
+}
+
+//
+// WithConditional will send the request with If-Modified-Since lastKnown,
+// for callers keeping the last response themselves instead of using
+// WithCache, and accept a 304 Not Modified response like a successful one.
+// Use WithStatusCode to tell whether the resource changed.
+//
+// A zero lastKnown, or one in the future of the client clock, as a skewed
+// clock could give, sends the request unconditionally.
+//
+func WithConditional(lastKnown time.Time) RequestOption {
+	return func(r *Request) {
+		r.lastKnown = lastKnown
+	}
+}
+
+// setConditional makes req conditional on its WithConditional time, unless that is zero or after now.
+func (req *Request) setConditional(now time.Time) {
+	if req.lastKnown.IsZero() || req.lastKnown.After(now) {
+		return
+	}
+	req.Header.Set("If-Modified-Since", req.lastKnown.UTC().Format(http.TimeFormat))
+	req.allowedStatuses = append(req.allowedStatuses, http.StatusNotModified)
+}
+
+// CacheStatusHeader is the header WithCache adds to responses to tell how the cache answered them, as RFC 9211 describes.
+const CacheStatusHeader = "Cache-Status"
+
+// maxCachedBodyBytes bounds the body of a stored response. Longer ones are passed on without being stored.
+const maxCachedBodyBytes = 1 << 20
+
+// defaultCacheEntries is the size of the MemoryCache of WithCache(nil).
+const defaultCacheEntries = 1000
+
+// sendCached sends r like sendShared, answering it from the WithCache cache when it can.
+func (c *client) sendCached(ctx context.Context, req *Request, r *http.Request) (*http.Response, error) {
+	if c.config.cache == nil || r.Method != http.MethodGet || isConditional(r.Header) || len(req.signers) > 0 {
+		return c.sendShared(ctx, req, r)
+	}
+	key := cacheKey(req, r)
+	directives := parseCacheControl(r.Header.Get("Cache-Control"))
+	if _, ok := directives["no-store"]; ok {
+		return c.sendShared(ctx, req, r)
+	}
+	_, noCache := directives["no-cache"]
+	now := c.clockOrDefault().Now()
+	cached, ok := c.config.cache.Get(key)
+	if ok && !noCache && now.Before(cached.Expires) {
+		return cached.response(r, "gohttp; hit"), nil
+	}
+
+	fwd := "uri-miss"
+	send := r
+	if ok {
+		fwd = "stale"
+		if noCache {
+			fwd = "request"
+		}
+		etag := cached.Header.Get("ETag")
+		modified, hasModified := lastModified(cached.Header)
+		if etag != "" || hasModified {
+			send = r.Clone(ctx)
+		}
+		if etag != "" {
+			send.Header.Set("If-None-Match", etag)
+		}
+		if hasModified {
+			send.Header.Set("If-Modified-Since", modified.Format(http.TimeFormat))
+		}
+	}
+	resp, err := c.sendShared(ctx, req, send)
+	if err != nil {
+		return nil, err
+	}
+	now = c.clockOrDefault().Now()
+	status := "gohttp; fwd=" + fwd
+	if ok {
+		status += "; fwd-status=" + strconv.Itoa(resp.StatusCode)
+	}
+	if send != r && resp.StatusCode == http.StatusNotModified {
+		discardResponse(resp)
+		cached = cached.revalidate(resp.Header, now)
+		c.config.cache.Set(key, cached)
+		return cached.response(r, status), nil
+	}
+
+	expires, ok := freshUntil(resp, now)
+	if !ok {
+		resp.Header.Set(CacheStatusHeader, status)
+		return resp, nil
+	}
+	limit := int64(maxCachedBodyBytes)
+	if req.maxResponseBytes > 0 && req.maxResponseBytes < limit {
+		limit = req.maxResponseBytes
+	}
+	body, ok, err := bufferBody(resp, limit)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		resp.Header.Set(CacheStatusHeader, status)
+		return resp, nil
+	}
+	cached = &CachedResponse{StatusCode: resp.StatusCode, Header: resp.Header.Clone(), Body: body, Expires: expires}
+	c.config.cache.Set(key, cached)
+	return cached.response(r, status+"; stored"), nil
+}
+
+//
+// cacheKey returns the key of r in the cache: its method and URL, and a hash
+// of its credentials, so requests with different credentials do not share
+// responses. The credentials themselves are left out, as a Cache may store
+// its keys elsewhere.
+//
+func cacheKey(req *Request, r *http.Request) string {
+	h := sha256.New()
+	var credentials bool
+	for _, name := range append(sensitiveHeaders[:len(sensitiveHeaders):len(sensitiveHeaders)], req.apiKey.secretHeader()) {
+		for _, v := range r.Header.Values(name) {
+			credentials = true
+			io.WriteString(h, name+": "+v+"\n")
+		}
+	}
+	param := req.apiKey.secretParam()
+	for _, v := range r.URL.Query()[param] {
+		credentials = true
+		io.WriteString(h, param+"="+v+"\n")
+	}
+
+	key := r.Method + " " + redactURL(r.URL, param)
+	if credentials {
+		key += " " + hex.EncodeToString(h.Sum(nil))
+	}
+	return key
+}
+
+// isConditional reports whether h makes a request conditional or partial, leaving its 304 or 206 to the caller.
+func isConditional(h http.Header) bool {
+	for _, k := range []string{"If-None-Match", "If-Modified-Since", "If-Match", "If-Unmodified-Since", "If-Range", "Range"} {
+		if h.Get(k) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// revalidate returns a copy of cr updated with the header of a 304 Not Modified response to it, received at now.
+func (cr *CachedResponse) revalidate(header http.Header, now time.Time) *CachedResponse {
+	updated := *cr
+	updated.Header = cr.Header.Clone()
+	for k, vs := range header {
+		if k != "Content-Length" {
+			updated.Header[k] = vs
+		}
+	}
+	updated.Expires, _ = freshUntil(&http.Response{StatusCode: cr.StatusCode, Header: updated.Header}, now)
+	return &updated
+}
+
+// response returns a copy of the stored response, answering r, with status as its Cache-Status header.
+func (cr *CachedResponse) response(r *http.Request, status string) *http.Response {
+	header := cr.Header.Clone()
+	header.Set(CacheStatusHeader, status)
+	return &http.Response{
+		Status:        strconv.Itoa(cr.StatusCode) + " " + http.StatusText(cr.StatusCode),
+		StatusCode:    cr.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(bytes.NewReader(cr.Body)),
+		ContentLength: int64(len(cr.Body)),
+		Request:       r,
+	}
+}
+
+// cacheableStatuses are the statuses whose responses are stored when they have an expiry or a validator.
+var cacheableStatuses = map[int]bool{
+	http.StatusOK:                   true,
+	http.StatusNonAuthoritativeInfo: true,
+	http.StatusNoContent:            true,
+	http.StatusMultipleChoices:      true,
+	http.StatusMovedPermanently:     true,
+	http.StatusNotFound:             true,
+	http.StatusGone:                 true,
+}
+
+// freshUntil returns when resp stops being fresh, and whether it may be stored: while fresh, or to be revalidated with its ETag or Last-Modified.
+func freshUntil(resp *http.Response, now time.Time) (time.Time, bool) {
+	if !cacheableStatuses[resp.StatusCode] {
+		return time.Time{}, false
+	}
+	directives := parseCacheControl(resp.Header.Get("Cache-Control"))
+	if _, ok := directives["no-store"]; ok {
+		return time.Time{}, false
+	}
+	_, hasModified := lastModified(resp.Header)
+	validated := resp.Header.Get("ETag") != "" || hasModified
+	if _, ok := directives["no-cache"]; ok {
+		return time.Time{}, validated
+	}
+	expires := expiry(resp.Header, directives, now)
+	return expires, validated || expires.After(now)
+}
+
+//
+// lastModified returns the Last-Modified time of a response with header, to
+// send as If-Modified-Since.
+//
+// A malformed time, or one after the Date of the response, is not used, as a
+// server with a skewed clock could then answer 304 to a changed resource.
+//
+func lastModified(header http.Header) (time.Time, bool) {
+	modified, err := http.ParseTime(header.Get("Last-Modified"))
+	if err != nil {
+		return time.Time{}, false
+	}
+	if date, err := http.ParseTime(header.Get("Date")); err == nil && modified.After(date) {
+		return time.Time{}, false
+	}
+	return modified.UTC(), true
+}
+
+// expiry returns when a response with header and Cache-Control directives, received at now, stops being fresh, or the zero time if it never is.
+func expiry(header http.Header, directives map[string]string, now time.Time) time.Time {
+	var age time.Duration
+	if secs, err := strconv.ParseInt(header.Get("Age"), 10, 64); err == nil && secs > 0 {
+		age = time.Duration(secs) * time.Second
+	}
+	if maxAge, ok := directives["max-age"]; ok {
+		secs, err := strconv.ParseInt(maxAge, 10, 64)
+		if err != nil || secs <= 0 {
+			return time.Time{}
+		}
+		return now.Add(time.Duration(secs)*time.Second - age)
+	}
+	if v := header.Get("Expires"); v != "" {
+		expires, err := http.ParseTime(v)
+		if err != nil {
+			return time.Time{}
+		}
+		// Expires is relative to the server clock, which Date gives.
+		if date, err := http.ParseTime(header.Get("Date")); err == nil {
+			expires = now.Add(expires.Sub(date) - age)
+		}
+		return expires
+	}
+	return time.Time{}
+}
+
+// parseCacheControl returns the lower-case directives of a Cache-Control header, with their unquoted values.
+func parseCacheControl(header string) map[string]string {
+	directives := map[string]string{}
+	for _, part := range strings.Split(header, ",") {
+		name, value := part, ""
+		if eq := strings.IndexByte(part, '='); eq >= 0 {
+			name, value = part[:eq], strings.Trim(strings.TrimSpace(part[eq+1:]), `"`)
+		}
+		if name = strings.ToLower(strings.TrimSpace(name)); name != "" {
+			directives[name] = value
+		}
+	}
+	return directives
+}
+
+//
+// MemoryCache is a Cache holding up to a fixed number of responses in
+// memory, evicting the least recently used one when full.
+//
+type MemoryCache struct {
+	mu         sync.Mutex
+	maxEntries int
+	order      *list.List // of *memoryCacheEntry, most recently used first
+	entries    map[string]*list.Element
+}
+
+type memoryCacheEntry struct {
+	key  string
+	resp *CachedResponse
+}
+
+// NewMemoryCache constructs an empty MemoryCache holding at most maxEntries responses.
+func NewMemoryCache(maxEntries int) *MemoryCache {
+	return &MemoryCache{maxEntries: maxEntries, order: list.New(), entries: map[string]*list.Element{}}
+}
+
+func (mc *MemoryCache) Get(key string) (*CachedResponse, bool) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	e, ok := mc.entries[key]
+	if !ok {
+		return nil, false
+	}
+	mc.order.MoveToFront(e)
+	return e.Value.(*memoryCacheEntry).resp, true
+}
+
+func (mc *MemoryCache) Set(key string, resp *CachedResponse) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	if e, ok := mc.entries[key]; ok {
+		e.Value.(*memoryCacheEntry).resp = resp
+		mc.order.MoveToFront(e)
+		return
+	}
+	mc.entries[key] = mc.order.PushFront(&memoryCacheEntry{key: key, resp: resp})
+	for mc.maxEntries > 0 && mc.order.Len() > mc.maxEntries {
+		oldest := mc.order.Back()
+		mc.order.Remove(oldest)
+		delete(mc.entries, oldest.Value.(*memoryCacheEntry).key)
+	}
+}
+
+func (mc *MemoryCache) Delete(key string) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	if e, ok := mc.entries[key]; ok {
+		mc.order.Remove(e)
+		delete(mc.entries, key)
+	}
+}
+
+// Len returns the number of responses in the cache.
+func (mc *MemoryCache) Len() int {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	return mc.order.Len()
+}
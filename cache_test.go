@@ -0,0 +1,390 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newCacheServer returns a server answering a JSON count of its requests, with the Cache-Control, Expires and Date headers given in the cc, expires and date parameters.
+func newCacheServer(t *testing.T) (*httptest.Server, *int32) {
+	t.Helper()
+	var hits int32
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&hits, 1)
+		if cc := r.URL.Query().Get("cc"); cc != "" {
+			w.Header().Set("Cache-Control", cc)
+		}
+		if expires := r.URL.Query().Get("expires"); expires != "" {
+			w.Header().Set("Expires", expires)
+			w.Header().Set("Date", r.URL.Query().Get("date"))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"hit": ` + strconv.Itoa(int(n)) + `}`))
+	})), &hits
+}
+
+func TestWithCache(t *testing.T) {
+	t.Parallel()
+	srv, hits := newCacheServer(t)
+	defer srv.Close()
+	ctx := context.Background()
+	c := newClient(http.Client{}, []ClientOption{WithCache(nil)})
+	clock := &fakeRetryClock{fakeClock: newFakeClock()}
+	c.clock = clock
+
+	get := func(t *testing.T, url string, options ...RequestOption) int {
+		t.Helper()
+		var got struct{ Hit int }
+		if err := c.Get(ctx, url, append(options, WithJSONResponse(&got))...); err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+		return got.Hit
+	}
+
+	t.Run("fresh hit", func(t *testing.T) {
+		url := srv.URL + "/flags?cc=max-age=60"
+		first := get(t, url)
+		clock.Advance(59 * time.Second)
+		if again := get(t, url); again != first {
+			t.Errorf("Get() = hit %d within max-age, want the cached hit %d", again, first)
+		}
+		var buf bytes.Buffer
+		var status int
+		var header http.Header
+		if err := c.Get(ctx, url, WithResponse(&buf), WithStatusCode(&status), WithResponseHeaders(&header)); err != nil {
+			t.Fatal(err)
+		}
+		if want := `{"hit": ` + strconv.Itoa(first) + `}`; buf.String() != want || status != http.StatusOK || header.Get("Content-Type") != "application/json" {
+			t.Errorf("cached response = %d %v %q, want 200 with the original headers and %q", status, header, buf.String(), want)
+		}
+	})
+
+	t.Run("expiry", func(t *testing.T) {
+		url := srv.URL + "/config?cc=public,+max-age=10"
+		first := get(t, url)
+		clock.Advance(10 * time.Second)
+		if again := get(t, url); again == first {
+			t.Errorf("Get() = cached hit %d after max-age, want a new response", again)
+		}
+	})
+
+	t.Run("Expires header", func(t *testing.T) {
+		query := url.Values{"expires": {clock.Now().Add(time.Minute).Format(http.TimeFormat)}, "date": {clock.Now().Format(http.TimeFormat)}}
+		url := srv.URL + "/expires?" + query.Encode()
+		first := get(t, url)
+		if again := get(t, url); again != first {
+			t.Errorf("Get() = hit %d before Expires, want the cached hit %d", again, first)
+		}
+	})
+
+	t.Run("credentials", func(t *testing.T) {
+		url := srv.URL + "/me?cc=max-age=60"
+		alice := get(t, url, WithBearerToken("alice-token"))
+		if bob := get(t, url, WithBearerToken("bob-token")); bob == alice {
+			t.Errorf("Get() with another token = hit %d, want a response of its own", bob)
+		}
+		if again := get(t, url, WithBearerToken("alice-token")); again != alice {
+			t.Errorf("Get() with the same token = hit %d, want the cached hit %d", again, alice)
+		}
+		if anonymous := get(t, url); anonymous == alice {
+			t.Errorf("Get() without credentials = hit %d, want a response of its own", anonymous)
+		}
+		mc := c.config.cache.(*MemoryCache)
+		mc.mu.Lock()
+		defer mc.mu.Unlock()
+		for key := range mc.entries {
+			if strings.Contains(key, "token") {
+				t.Errorf("cache key %q holds the credentials", key)
+			}
+		}
+	})
+
+	for _, tt := range []struct {
+		name    string
+		url     string
+		options []RequestOption
+	}{
+		{name: "no-store", url: "/private?cc=no-store"},
+		{name: "no-cache", url: "/revalidate?cc=no-cache,+max-age=60"},
+		{name: "no expiry", url: "/plain"},
+		{name: "request no-cache", url: "/forced?cc=max-age=60", options: []RequestOption{WithHeader("Cache-Control", "no-cache")}},
+		{name: "Range", url: "/partial?cc=max-age=60", options: []RequestOption{WithHeader("Range", "bytes=0-3")}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			before := atomic.LoadInt32(hits)
+			get(t, srv.URL+tt.url, tt.options...)
+			get(t, srv.URL+tt.url, tt.options...)
+			if n := atomic.LoadInt32(hits) - before; n != 2 {
+				t.Errorf("server got %d requests, want 2", n)
+			}
+		})
+	}
+
+	t.Run("over WithMaxResponseBytes", func(t *testing.T) {
+		before := atomic.LoadInt32(hits)
+		for i := 0; i < 2; i++ {
+			var rtle *ResponseTooLargeError
+			if err := c.Get(ctx, srv.URL+"/large?cc=max-age=60", WithMaxResponseBytes(4), WithResponse(&bytes.Buffer{})); !errors.As(err, &rtle) {
+				t.Errorf("Get() error = %v, want a *ResponseTooLargeError", err)
+			}
+		}
+		if n := atomic.LoadInt32(hits) - before; n != 2 {
+			t.Errorf("server got %d requests, want 2", n)
+		}
+	})
+
+	t.Run("POST not cached", func(t *testing.T) {
+		before := atomic.LoadInt32(hits)
+		c.Post(ctx, srv.URL+"/flags?cc=max-age=60")
+		c.Post(ctx, srv.URL+"/flags?cc=max-age=60")
+		if n := atomic.LoadInt32(hits) - before; n != 2 {
+			t.Errorf("server got %d requests, want 2", n)
+		}
+	})
+}
+
+// newETagServer returns a server answering its body with an ETag, and 304 Not Modified to a matching If-None-Match, with the Cache-Control given in the cc parameter.
+func newETagServer(t *testing.T, body *atomic.Value) (*httptest.Server, *int32) {
+	t.Helper()
+	var hits int32
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		b := body.Load().(string)
+		etag := `"` + b + `"`
+		w.Header().Set("ETag", etag)
+		if cc := r.URL.Query().Get("cc"); cc != "" {
+			w.Header().Set("Cache-Control", cc)
+		}
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Write([]byte(b))
+	})), &hits
+}
+
+func TestWithCache_etag(t *testing.T) {
+	t.Parallel()
+	var body atomic.Value
+	body.Store("v1")
+	srv, hits := newETagServer(t, &body)
+	defer srv.Close()
+	ctx := context.Background()
+	c := newClient(http.Client{}, []ClientOption{WithCache(nil)})
+	clock := &fakeRetryClock{fakeClock: newFakeClock()}
+	c.clock = clock
+
+	get := func(t *testing.T, url string, options ...RequestOption) (string, int, string) {
+		t.Helper()
+		var buf bytes.Buffer
+		var status int
+		var header http.Header
+		if err := c.Get(ctx, url, append(options, WithResponse(&buf), WithStatusCode(&status), WithResponseHeaders(&header))...); err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+		return buf.String(), status, header.Get(CacheStatusHeader)
+	}
+
+	t.Run("not modified", func(t *testing.T) {
+		url := srv.URL + "/config?cc=no-cache"
+		if got, _, cs := get(t, url); got != "v1" || cs != "gohttp; fwd=uri-miss; stored" {
+			t.Fatalf("first Get() = %q with Cache-Status %q, want v1 stored", got, cs)
+		}
+		before := atomic.LoadInt32(hits)
+		got, status, cs := get(t, url)
+		if got != "v1" || status != http.StatusOK || cs != "gohttp; fwd=stale; fwd-status=304" {
+			t.Errorf("revalidated Get() = %d %q with Cache-Status %q, want 200 v1 revalidated", status, got, cs)
+		}
+		if n := atomic.LoadInt32(hits) - before; n != 1 {
+			t.Errorf("server got %d requests, want 1", n)
+		}
+	})
+
+	t.Run("changed body", func(t *testing.T) {
+		url := srv.URL + "/changing?cc=no-cache"
+		get(t, url)
+		body.Store("v2")
+		defer body.Store("v1")
+		if got, status, cs := get(t, url); got != "v2" || status != http.StatusOK || cs != "gohttp; fwd=stale; fwd-status=200; stored" {
+			t.Errorf("Get() = %d %q with Cache-Status %q, want the new v2 body stored", status, got, cs)
+		}
+		if got, _, cs := get(t, url); got != "v2" || cs != "gohttp; fwd=stale; fwd-status=304" {
+			t.Errorf("Get() = %q with Cache-Status %q, want v2 revalidated", got, cs)
+		}
+	})
+
+	t.Run("refreshes freshness", func(t *testing.T) {
+		url := srv.URL + "/fresh?cc=max-age=10"
+		get(t, url)
+		clock.Advance(10 * time.Second)
+		if _, _, cs := get(t, url); cs != "gohttp; fwd=stale; fwd-status=304" {
+			t.Fatalf("Get() after max-age has Cache-Status %q, want a revalidation", cs)
+		}
+		before := atomic.LoadInt32(hits)
+		clock.Advance(9 * time.Second)
+		if got, _, cs := get(t, url); got != "v1" || cs != "gohttp; hit" {
+			t.Errorf("Get() = %q with Cache-Status %q, want a hit within the refreshed max-age", got, cs)
+		}
+		if n := atomic.LoadInt32(hits) - before; n != 0 {
+			t.Errorf("server got %d requests, want 0", n)
+		}
+	})
+
+	t.Run("caller's If-None-Match", func(t *testing.T) {
+		url := srv.URL + "/config?cc=no-cache"
+		err := c.Get(ctx, url, WithHeader("If-None-Match", `"v1"`))
+		var bse *BadStatusError
+		if !errors.As(err, &bse) || bse.Code != http.StatusNotModified {
+			t.Errorf("Get() error = %v, want the server's 304", err)
+		}
+	})
+}
+
+// newLastModifiedServer returns a server answering a count of its requests
+// with the Last-Modified and Date headers given in the lm and date
+// parameters, and 304 Not Modified to an If-Modified-Since no earlier than
+// lm. It records the last If-Modified-Since it got in ims.
+func newLastModifiedServer(t *testing.T, ims *atomic.Value) (*httptest.Server, *int32) {
+	t.Helper()
+	var hits int32
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&hits, 1)
+		lm := r.URL.Query().Get("lm")
+		w.Header().Set("Last-Modified", lm)
+		w.Header().Set("Date", r.URL.Query().Get("date"))
+		ims.Store(r.Header.Get("If-Modified-Since"))
+		modified, err := http.ParseTime(lm)
+		if since, serr := http.ParseTime(r.Header.Get("If-Modified-Since")); err == nil && serr == nil && !modified.After(since) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Write([]byte(strconv.Itoa(int(n))))
+	})), &hits
+}
+
+func TestWithCache_lastModified(t *testing.T) {
+	t.Parallel()
+	var ims atomic.Value
+	srv, hits := newLastModifiedServer(t, &ims)
+	defer srv.Close()
+	ctx := context.Background()
+	c := newClient(http.Client{}, []ClientOption{WithCache(nil)})
+	now := time.Now().UTC().Truncate(time.Second)
+	modified := now.Add(-time.Hour).Format(http.TimeFormat)
+
+	for _, tt := range []struct {
+		name       string
+		lm         string
+		wantStatus string
+		wantIMS    string
+	}{
+		{name: "not modified", lm: modified, wantStatus: "gohttp; fwd=stale; fwd-status=304", wantIMS: modified},
+		{name: "malformed", lm: "yesterday", wantStatus: "gohttp; fwd=uri-miss"},
+		{name: "after Date", lm: now.Add(time.Hour).Format(http.TimeFormat), wantStatus: "gohttp; fwd=uri-miss"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			query := url.Values{"lm": {tt.lm}, "date": {now.Format(http.TimeFormat)}}
+			url := srv.URL + "/" + strings.ReplaceAll(tt.name, " ", "-") + "?" + query.Encode()
+			var first, again string
+			if err := c.Get(ctx, url, WithStringResponse(&first)); err != nil {
+				t.Fatal(err)
+			}
+			var header http.Header
+			if err := c.Get(ctx, url, WithStringResponse(&again), WithResponseHeaders(&header)); err != nil {
+				t.Fatalf("Get() error = %v", err)
+			}
+			if got := ims.Load().(string); got != tt.wantIMS {
+				t.Errorf("server got If-Modified-Since %q, want %q", got, tt.wantIMS)
+			}
+			if cs := header.Get(CacheStatusHeader); cs != tt.wantStatus {
+				t.Errorf("Get() has Cache-Status %q, want %q", cs, tt.wantStatus)
+			}
+			if revalidated := tt.wantIMS != ""; revalidated != (again == first) {
+				t.Errorf("Get() = %q after %q, want the cached body only when revalidated", again, first)
+			}
+		})
+	}
+
+	t.Run("WithConditional", func(t *testing.T) {
+		before := atomic.LoadInt32(hits)
+		url := srv.URL + "/own?" + url.Values{"lm": {modified}, "date": {now.Format(http.TimeFormat)}}.Encode()
+		plain := newClient(http.Client{}, nil)
+		for _, tt := range []struct {
+			lastKnown time.Time
+			want      int
+		}{
+			{lastKnown: now, want: http.StatusNotModified},
+			{lastKnown: now.Add(-2 * time.Hour), want: http.StatusOK},
+			{lastKnown: time.Time{}, want: http.StatusOK},
+			{lastKnown: now.Add(24 * time.Hour), want: http.StatusOK},
+		} {
+			var status int
+			if err := plain.Get(ctx, url, WithConditional(tt.lastKnown), WithStatusCode(&status)); err != nil {
+				t.Fatalf("Get(WithConditional(%v)) error = %v", tt.lastKnown, err)
+			}
+			if status != tt.want {
+				t.Errorf("Get(WithConditional(%v)) status = %d, want %d", tt.lastKnown, status, tt.want)
+			}
+		}
+
+		// lastKnown is in the future of a client clock running behind.
+		skewed := newClient(http.Client{}, nil)
+		skewed.clock = &fakeRetryClock{fakeClock: newFakeClock()}
+		var status int
+		if err := skewed.Get(ctx, url, WithConditional(now), WithStatusCode(&status)); err != nil || status != http.StatusOK {
+			t.Errorf("Get(WithConditional(%v)) on a skewed clock = %d, %v, want %d", now, status, err, http.StatusOK)
+		}
+		if n := atomic.LoadInt32(hits) - before; n != 5 {
+			t.Errorf("server got %d requests, want 5", n)
+		}
+	})
+}
+
+func TestMockClient_WithConditional_clock(t *testing.T) {
+	t.Parallel()
+	clock := &fakeRetryClock{fakeClock: newFakeClock()}
+	var ims []string
+	mc := NewMockClient(func(ctx context.Context, r *Request) error {
+		ims = append(ims, r.Header.Get("If-Modified-Since"))
+		return nil
+	}).(*mockClient)
+	mc.clock = clock
+
+	lastKnown := clock.Now().Add(time.Hour)
+	mc.Get(context.Background(), "http://example.com", WithConditional(lastKnown))
+	clock.Advance(2 * time.Hour)
+	mc.Get(context.Background(), "http://example.com", WithConditional(lastKnown))
+
+	if want := []string{"", lastKnown.Format(http.TimeFormat)}; !reflect.DeepEqual(ims, want) {
+		t.Errorf("If-Modified-Since = %q, want %q", ims, want)
+	}
+}
+
+func TestMemoryCache(t *testing.T) {
+	t.Parallel()
+	mc := NewMemoryCache(2)
+	mc.Set("a", &CachedResponse{StatusCode: 1})
+	mc.Set("b", &CachedResponse{StatusCode: 2})
+	mc.Get("a")
+	mc.Set("c", &CachedResponse{StatusCode: 3})
+	if _, ok := mc.Get("b"); ok {
+		t.Error("Get(b) found the least recently used entry, want it evicted")
+	}
+	if resp, ok := mc.Get("a"); !ok || resp.StatusCode != 1 {
+		t.Errorf("Get(a) = %v, %v, want the recently used entry", resp, ok)
+	}
+	mc.Delete("a")
+	if mc.Len() != 1 {
+		t.Errorf("Len() = %d, want 1", mc.Len())
+	}
+}
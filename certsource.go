@@ -0,0 +1,220 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"sync/atomic"
+	"time"
+)
+
+//
+// CertificateSource provides the client certificate and root CAs used for
+// TLS handshakes, for credentials that rotate while the client is running,
+// such as SPIFFE SVIDs from the Workload API.
+//
+// Every new handshake asks the source for its current material, so rotation
+// needs no new Client. Implementations must be safe for concurrent use, and
+// must not modify a certificate or pool after returning it; publish new
+// material by returning new values instead.
+//
+type CertificateSource interface {
+	// GetClientCertificate returns the certificate to present to servers.
+	GetClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error)
+	// GetRootCAs returns the pool used to verify servers.
+	GetRootCAs() (*x509.CertPool, error)
+	// Watch calls changed after the material changes, until ctx is done.
+	Watch(ctx context.Context, changed func())
+}
+
+//
+// WithCertificateSource makes the client take its TLS client certificate and
+// root CAs from s on every new connection.
+//
+// Idle connections are closed whenever s reports a change, so that later
+// requests handshake with the new material.
+//
+// The handshakes are made by the client itself, which the transport skips
+// for https requests sent through a proxy. Combining it with WithProxy or
+// WithProxyFromEnvironment makes NewClient panic, and https requests the
+// transport would send through a proxy, such as from the environment, fail.
+//
+func WithCertificateSource(s CertificateSource) ClientOption {
+	return func(c *clientConfig) {
+		c.certSource = s
+	}
+}
+
+// errCertificateSourceProxy fails https requests that would bypass the CertificateSource through a proxy.
+var errCertificateSourceProxy = errors.New("WithCertificateSource cannot send https requests through a proxy")
+
+// handshakeTimeoutError is the error of a handshake taking longer than the TLSHandshakeTimeout of the transport.
+type handshakeTimeoutError struct{}
+
+func (handshakeTimeoutError) Timeout() bool   { return true }
+func (handshakeTimeoutError) Temporary() bool { return true }
+func (handshakeTimeoutError) Error() string   { return "net/http: TLS handshake timeout" }
+
+// useCertificateSource configures the transport of c to handshake with material from s.
+func (c *client) useCertificateSource(s CertificateSource) {
+	t := c.cloneTransport("WithCertificateSource")
+	base := t.TLSClientConfig
+	if base == nil {
+		base = &tls.Config{}
+	}
+	if t.ForceAttemptHTTP2 && len(base.NextProtos) == 0 {
+		// A custom DialTLSContext only negotiates HTTP/2 when asked to.
+		base = base.Clone()
+		base.NextProtos = []string{"h2", "http/1.1"}
+	}
+	dial := t.DialContext
+	if dial == nil {
+		var dialer net.Dialer
+		dial = dialer.DialContext
+	}
+	if proxy := t.Proxy; proxy != nil {
+		t.Proxy = func(r *http.Request) (*url.URL, error) {
+			u, err := proxy(r)
+			if u != nil && r.URL.Scheme == "https" {
+				return nil, errCertificateSourceProxy
+			}
+			return u, err
+		}
+	}
+	handshakeTimeout := t.TLSHandshakeTimeout
+
+	t.DialTLSContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		config := base.Clone()
+		if config.ServerName == "" {
+			host, _, err := net.SplitHostPort(addr)
+			if err != nil {
+				return nil, err
+			}
+			config.ServerName = host
+		}
+		roots, err := s.GetRootCAs()
+		if err != nil {
+			return nil, err
+		}
+		config.RootCAs = roots
+		config.GetClientCertificate = s.GetClientCertificate
+
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		handshakeCtx := ctx
+		if handshakeTimeout > 0 {
+			var cancel context.CancelFunc
+			handshakeCtx, cancel = context.WithTimeout(ctx, handshakeTimeout)
+			defer cancel()
+		}
+		tlsConn := tls.Client(conn, config)
+		if err := tlsConn.HandshakeContext(handshakeCtx); err != nil {
+			conn.Close()
+			if ctx.Err() == nil && handshakeCtx.Err() != nil {
+				return nil, handshakeTimeoutError{}
+			}
+			return nil, err
+		}
+		return tlsConn, nil
+	}
+	c.client.Transport = t
+
+	c.tasks.start("certificate-source", func(ctx context.Context) {
+		s.Watch(ctx, t.CloseIdleConnections)
+	})
+}
+
+//
+// FileCertificateSource is a CertificateSource reading PEM files, which are
+// checked for changes at a fixed interval.
+//
+// If the files cannot be loaded after a change, for example because they
+// are being rewritten, the previous material stays in use until the next
+// check.
+//
+type FileCertificateSource struct {
+	certFile, keyFile, caFile string
+	interval                  time.Duration
+
+	current atomic.Value // *certMaterial
+}
+
+type certMaterial struct {
+	raw   [][]byte
+	cert  *tls.Certificate
+	roots *x509.CertPool
+}
+
+// NewFileCertificateSource loads the client certificate and key, and the root CAs in caFile.
+func NewFileCertificateSource(certFile, keyFile, caFile string, interval time.Duration) (*FileCertificateSource, error) {
+	s := &FileCertificateSource{
+		certFile: certFile,
+		keyFile:  keyFile,
+		caFile:   caFile,
+		interval: interval,
+	}
+	if _, err := s.reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// GetClientCertificate returns the current client certificate.
+func (s *FileCertificateSource) GetClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	return s.current.Load().(*certMaterial).cert, nil
+}
+
+// GetRootCAs returns the current root CAs.
+func (s *FileCertificateSource) GetRootCAs() (*x509.CertPool, error) {
+	return s.current.Load().(*certMaterial).roots, nil
+}
+
+// Watch checks the files every interval until ctx is done.
+func (s *FileCertificateSource) Watch(ctx context.Context, changed func()) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if ok, _ := s.reload(); ok {
+				changed()
+			}
+		}
+	}
+}
+
+// reload loads the files, reporting whether they changed since the last load.
+func (s *FileCertificateSource) reload() (bool, error) {
+	raw, err := readTLSFiles(s.certFile, s.keyFile, s.caFile)
+	if err != nil {
+		return false, err
+	}
+	if prev, ok := s.current.Load().(*certMaterial); ok && sameFiles(prev.raw, raw) {
+		return false, nil
+	}
+
+	m, err := parseTLSFiles(raw, s.certFile, s.keyFile, s.caFile)
+	if err != nil {
+		return false, err
+	}
+	s.current.Store(m)
+	return true, nil
+}
+
+func sameFiles(a, b [][]byte) bool {
+	for i := range a {
+		if !bytes.Equal(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,268 @@
+package http
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T, name string) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue returns a PEM leaf certificate and key for 127.0.0.1 and localhost signed by ca.
+func (ca *testCA) issue(t *testing.T, usage x509.ExtKeyUsage) (certPEM, keyPEM []byte) {
+	t.Helper()
+	return ca.issueFor(t, usage, []string{"localhost"}, []net.IP{net.ParseIP("127.0.0.1")})
+}
+
+// issueFor returns a PEM leaf certificate and key for these names and addresses signed by ca.
+func (ca *testCA) issueFor(t *testing.T, usage x509.ExtKeyUsage, dnsNames []string, ips []net.IP) (certPEM, keyPEM []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "leaf"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     dnsNames,
+		IPAddresses:  ips,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+// writeClientFiles writes a client certificate signed by ca and ca itself as the root into dir.
+func writeClientFiles(t *testing.T, dir string, ca *testCA) {
+	t.Helper()
+	certPEM, keyPEM := ca.issue(t, x509.ExtKeyUsageClientAuth)
+	for name, buf := range map[string][]byte{"cert.pem": certPEM, "key.pem": keyPEM, "ca.pem": ca.pem} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), buf, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// newTestFileSource writes client files signed by ca into a new directory and loads them.
+func newTestFileSource(t *testing.T, ca *testCA, interval time.Duration) (*FileCertificateSource, string) {
+	t.Helper()
+	dir := t.TempDir()
+	writeClientFiles(t, dir, ca)
+	src, err := NewFileCertificateSource(filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem"), filepath.Join(dir, "ca.pem"), interval)
+	if err != nil {
+		t.Fatalf("NewFileCertificateSource() error = %v", err)
+	}
+	return src, dir
+}
+
+// newMTLSTestServer starts a server requiring client certificates, whose certificate and client CA are both signed by the current CA.
+func newMTLSTestServer(t *testing.T, ca *testCA) (*httptest.Server, func(*testCA)) {
+	t.Helper()
+	var current atomic.Value // *tls.Config
+	setCA := func(ca *testCA) {
+		certPEM, keyPEM := ca.issue(t, x509.ExtKeyUsageServerAuth)
+		cert, err := tls.X509KeyPair(certPEM, keyPEM)
+		if err != nil {
+			t.Fatal(err)
+		}
+		clientCAs := x509.NewCertPool()
+		clientCAs.AddCert(ca.cert)
+		current.Store(&tls.Config{
+			Certificates: []tls.Certificate{cert},
+			ClientCAs:    clientCAs,
+			ClientAuth:   tls.RequireAndVerifyClientCert,
+		})
+	}
+	setCA(ca)
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	srv.TLS = &tls.Config{
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			return current.Load().(*tls.Config), nil
+		},
+	}
+	srv.Config.SetKeepAlivesEnabled(false)
+	srv.StartTLS()
+	return srv, setCA
+}
+
+func TestCertificateSource_rotation(t *testing.T) {
+	t.Parallel()
+	ca1, ca2 := newTestCA(t, "ca1"), newTestCA(t, "ca2")
+	srv, setServerCA := newMTLSTestServer(t, ca1)
+	defer srv.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	src, dir := newTestFileSource(t, ca1, time.Hour)
+	oldSrc, _ := newTestFileSource(t, ca1, time.Hour)
+	cli := NewClient(WithCertificateSource(src))
+	defer cli.Close(ctx)
+	oldCli := NewClient(WithCertificateSource(oldSrc))
+	defer oldCli.Close(ctx)
+
+	if err := cli.Get(ctx, srv.URL); err != nil {
+		t.Fatalf("Get() before rotation error = %v", err)
+	}
+
+	// Rotate the server to ca2, then the client files.
+	setServerCA(ca2)
+	if err := cli.Get(ctx, srv.URL); err == nil {
+		t.Fatalf("Get() with stale material error = nil, want handshake failure")
+	}
+	writeClientFiles(t, dir, ca2)
+	if changed, err := src.reload(); !changed || err != nil {
+		t.Fatalf("reload() = %v, %v, want true, nil", changed, err)
+	}
+
+	if err := cli.Get(ctx, srv.URL); err != nil {
+		t.Errorf("Get() after rotation error = %v", err)
+	}
+	if err := oldCli.Get(ctx, srv.URL); err == nil {
+		t.Errorf("Get() with old material error = nil, want handshake failure")
+	}
+}
+
+func TestFileCertificateSource_watch(t *testing.T) {
+	t.Parallel()
+	ca1, ca2 := newTestCA(t, "ca1"), newTestCA(t, "ca2")
+	src, dir := newTestFileSource(t, ca1, time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	changed := make(chan struct{}, 1)
+	go src.Watch(ctx, func() {
+		select {
+		case changed <- struct{}{}:
+		default:
+		}
+	})
+
+	writeClientFiles(t, dir, ca2)
+	// The files are rewritten one at a time, so wait for a change that includes the new CA.
+	for {
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			t.Fatalf("Watch() did not report the rotation")
+		}
+		roots, _ := src.GetRootCAs()
+		if _, err := ca2.cert.Verify(x509.VerifyOptions{Roots: roots}); err == nil {
+			return
+		}
+	}
+}
+
+func TestCertificateSource_handshakeTimeout(t *testing.T) {
+	t.Parallel()
+	// The listener accepts connections but never answers the handshake.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	src, _ := newTestFileSource(t, newTestCA(t, "ca"), time.Hour)
+	cli := NewClient(WithTransport(&http.Transport{TLSHandshakeTimeout: 50 * time.Millisecond}), WithCertificateSource(src))
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	defer cli.Close(ctx)
+
+	err = cli.Get(ctx, "https://"+ln.Addr().String())
+	var ne net.Error
+	if !errors.As(err, &ne) || !ne.Timeout() || ctx.Err() != nil {
+		t.Errorf("Get() error = %v, want a handshake timeout before the context deadline", err)
+	}
+}
+
+func TestCertificateSource_proxy(t *testing.T) {
+	t.Parallel()
+	src, _ := newTestFileSource(t, newTestCA(t, "ca"), time.Hour)
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("NewClient(WithCertificateSource, WithProxy) did not panic")
+			}
+		}()
+		NewClient(WithCertificateSource(src), WithProxy("http://proxy.example:3128"))
+	}()
+
+	var proxied int32
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&proxied, 1)
+	}))
+	defer proxy.Close()
+	proxyURL, _ := url.Parse(proxy.URL)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	cli := NewClient(WithTransport(&http.Transport{Proxy: http.ProxyURL(proxyURL)}), WithCertificateSource(src))
+	defer cli.Close(ctx)
+
+	if err := cli.Get(ctx, "https://backend.example"); !errors.Is(err, errCertificateSourceProxy) {
+		t.Errorf("Get() through a transport proxy error = %v, want errCertificateSourceProxy", err)
+	}
+	if atomic.LoadInt32(&proxied) != 0 {
+		t.Error("the request reached the proxy without the certificate source")
+	}
+}
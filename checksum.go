@@ -0,0 +1,101 @@
+package http
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+)
+
+//
+// WithChecksum will hash the response body as it is read and fail the
+// request with a *ChecksumMismatchError unless its digest is expectedHex.
+//
+// algo is "sha256" or "sha512". The mismatch is reported by the read of the
+// end of the body, so WithFileResponse never renames a mismatching file into
+// place.
+//
+func WithChecksum(algo string, expectedHex string) RequestOption {
+	return func(r *Request) {
+		r.checksumAlgo = algo
+		r.checksumHex = expectedHex
+	}
+}
+
+// ChecksumMismatchError is returned when the response body does not match the digest given to WithChecksum.
+type ChecksumMismatchError struct {
+	Algorithm string
+	Got       string
+	Want      string
+}
+
+func (cme *ChecksumMismatchError) Error() string {
+	return fmt.Sprintf("response body %s checksum is %s, want %s", cme.Algorithm, cme.Got, cme.Want)
+}
+
+// newChecksumHash returns the hash for a WithChecksum algorithm.
+func newChecksumHash(algo string) (hash.Hash, bool) {
+	switch algo {
+	case "sha256":
+		return sha256.New(), true
+	case "sha512":
+		return sha512.New(), true
+	}
+	return nil, false
+}
+
+// validateChecksum checks the arguments of WithChecksum.
+func (req *Request) validateChecksum() error {
+	if req.checksumAlgo == "" {
+		return nil
+	}
+	h, ok := newChecksumHash(req.checksumAlgo)
+	if !ok {
+		return fmt.Errorf("%w: unsupported checksum algorithm %q", ErrInvalidOption, req.checksumAlgo)
+	}
+	if want, err := hex.DecodeString(req.checksumHex); err != nil || len(want) != h.Size() {
+		return fmt.Errorf("%w: %q is not a hex %s digest", ErrInvalidOption, req.checksumHex, req.checksumAlgo)
+	}
+	return nil
+}
+
+// checksumReader hashes what is read through it and verifies the digest at the end.
+type checksumReader struct {
+	r     io.Reader
+	hash  hash.Hash
+	algo  string
+	want  []byte
+	ended bool
+}
+
+func newChecksumReader(r io.Reader, algo, wantHex string) *checksumReader {
+	// Both were checked by validateChecksum.
+	h, _ := newChecksumHash(algo)
+	want, _ := hex.DecodeString(wantHex)
+	return &checksumReader{r: r, hash: h, algo: algo, want: want}
+}
+
+func (cr *checksumReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	cr.hash.Write(p[:n])
+	if err == io.EOF {
+		cr.ended = true
+		if got := cr.hash.Sum(nil); !bytes.Equal(got, cr.want) {
+			return n, &ChecksumMismatchError{Algorithm: cr.algo, Got: hex.EncodeToString(got), Want: hex.EncodeToString(cr.want)}
+		}
+	}
+	return n, err
+}
+
+// verify reads the rest of a body that was not read to the end, verifying its digest.
+func (cr *checksumReader) verify() error {
+	if cr.ended {
+		return nil
+	}
+	_, err := io.Copy(ioutil.Discard, cr)
+	return err
+}
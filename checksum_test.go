@@ -0,0 +1,81 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestGet_checksum(t *testing.T) {
+	t.Parallel()
+	body := []byte(`{"name":"artifact"}`)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(body)
+	}))
+	defer srv.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	sha256Sum := sha256.Sum256(body)
+	sha512Sum := sha512.Sum512(body)
+	good256, good512 := hex.EncodeToString(sha256Sum[:]), hex.EncodeToString(sha512Sum[:])
+	bad256 := hex.EncodeToString(make([]byte, sha256.Size))
+
+	var resp map[string]string
+	var buf bytes.Buffer
+	checksumTestCases := []struct {
+		name     string
+		options  []RequestOption
+		mismatch bool
+	}{
+		{name: "json sha256", options: []RequestOption{WithJSONResponse(&resp), WithChecksum("sha256", good256)}},
+		{name: "output sha512", options: []RequestOption{WithResponse(&buf), WithChecksum("sha512", good512)}},
+		{name: "no output", options: []RequestOption{WithChecksum("sha256", good256)}},
+		{name: "json mismatch", options: []RequestOption{WithJSONResponse(&resp), WithChecksum("sha256", bad256)}, mismatch: true},
+		{name: "output mismatch", options: []RequestOption{WithResponse(&buf), WithChecksum("sha256", bad256)}, mismatch: true},
+		{name: "no output mismatch", options: []RequestOption{WithChecksum("sha256", bad256)}, mismatch: true},
+	}
+	for _, tt := range checksumTestCases {
+		err := NewClient().Get(ctx, srv.URL, tt.options...)
+		var cme *ChecksumMismatchError
+		if tt.mismatch {
+			if !errors.As(err, &cme) || cme.Got != good256 || cme.Want != bad256 {
+				t.Errorf("%s: Get() error = %v, want *ChecksumMismatchError", tt.name, err)
+			}
+		} else if err != nil {
+			t.Errorf("%s: Get() error = %v", tt.name, err)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "artifact.json")
+	err := NewClient().Get(ctx, srv.URL, WithFileResponse(path), WithChecksum("sha256", bad256))
+	var cme *ChecksumMismatchError
+	if !errors.As(err, &cme) {
+		t.Errorf("Get(file) error = %v, want *ChecksumMismatchError", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Stat() error = %v, want the mismatching file not renamed into place", err)
+	}
+	if entries, _ := ioutil.ReadDir(filepath.Dir(path)); len(entries) != 0 {
+		t.Errorf("directory has %d entries, want the temporary file removed", len(entries))
+	}
+}
+
+func TestGet_checksum_invalid(t *testing.T) {
+	t.Parallel()
+	for _, o := range []RequestOption{WithChecksum("md5", "00"), WithChecksum("sha256", "not hex"), WithChecksum("sha256", "00")} {
+		if err := NewClient().Get(context.Background(), "http://127.0.0.1:0", o); !errors.Is(err, ErrInvalidOption) {
+			t.Errorf("Get() error = %v, want %v", err, ErrInvalidOption)
+		}
+	}
+}
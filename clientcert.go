@@ -0,0 +1,72 @@
+package http
+
+import (
+	"crypto/tls"
+	"sync"
+)
+
+//
+// WithClientCertificateProvider will ask get for the client certificate of
+// every new TLS connection, instead of presenting a fixed one, so that
+// certificates rotated while the client runs are picked up. It applies on
+// top of NewTLSClient and the other TLS options.
+//
+// ClientCertificateFiles provides certificates read from PEM files.
+//
+func WithClientCertificateProvider(get func(*tls.CertificateRequestInfo) (*tls.Certificate, error)) ClientOption {
+	return configureTLS("WithClientCertificateProvider", func(config *tls.Config) {
+		config.Certificates = nil
+		config.GetClientCertificate = get
+	})
+}
+
+//
+// ClientCertificateFiles provides the client certificate and key in PEM
+// files, such as those rotated by a sidecar, to
+// WithClientCertificateProvider.
+//
+// The files are read again for every new connection, and reloaded if they
+// changed. If they cannot be loaded, for example because they are being
+// rewritten, the previous certificate stays in use.
+//
+type ClientCertificateFiles struct {
+	certFile, keyFile string
+
+	mu   sync.Mutex
+	raw  [][]byte
+	cert *tls.Certificate
+}
+
+// NewClientCertificateFiles loads the client certificate and key in certFile and keyFile.
+func NewClientCertificateFiles(certFile, keyFile string) (*ClientCertificateFiles, error) {
+	f := &ClientCertificateFiles{certFile: certFile, keyFile: keyFile}
+	if err := f.reload(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// GetClientCertificate returns the certificate in the files, reloading them if they changed.
+func (f *ClientCertificateFiles) GetClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.reload()
+	return f.cert, nil
+}
+
+// reload loads the files if they changed since the last load. f.mu must be held, or f unshared.
+func (f *ClientCertificateFiles) reload() error {
+	raw, err := readTLSFiles(f.certFile, f.keyFile)
+	if err != nil {
+		return err
+	}
+	if f.raw != nil && sameFiles(f.raw, raw) {
+		return nil
+	}
+	cert, err := loadKeyPair(raw[0], raw[1], f.certFile, f.keyFile)
+	if err != nil {
+		return err
+	}
+	f.raw, f.cert = raw, cert
+	return nil
+}
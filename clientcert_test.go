@@ -0,0 +1,76 @@
+package http
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestWithClientCertificateProvider(t *testing.T) {
+	t.Parallel()
+	var mu sync.Mutex
+	var presented [][32]byte
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		presented = append(presented, sha256.Sum256(r.TLS.PeerCertificates[0].Raw))
+	}))
+	srv.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	srv.StartTLS()
+	defer srv.Close()
+
+	ca := newTestCA(t, "ca")
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	writePair := func() [32]byte {
+		certPEM, keyPEM := ca.issue(t, x509.ExtKeyUsageClientAuth)
+		if err := ioutil.WriteFile(certFile, certPEM, 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(keyFile, keyPEM, 0600); err != nil {
+			t.Fatal(err)
+		}
+		block, _ := pem.Decode(certPEM)
+		return sha256.Sum256(block.Bytes)
+	}
+	first := writePair()
+
+	files, err := NewClientCertificateFiles(certFile, keyFile)
+	if err != nil {
+		t.Fatalf("NewClientCertificateFiles() error = %v", err)
+	}
+	cli := NewTLSClient(srv.Client().Transport.(*http.Transport).TLSClientConfig,
+		WithClientCertificateProvider(files.GetClientCertificate),
+		WithDisableKeepAlives(),
+	)
+	ctx := context.Background()
+	if err := cli.Get(ctx, srv.URL); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	second := writePair()
+	if err := cli.Get(ctx, srv.URL); err != nil {
+		t.Fatalf("Get() after rotation error = %v", err)
+	}
+
+	// A half-written pair keeps the previous certificate in use.
+	if err := ioutil.WriteFile(keyFile, []byte("partial"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := cli.Get(ctx, srv.URL); err != nil {
+		t.Fatalf("Get() during rewrite error = %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(presented) != 3 || presented[0] != first || presented[1] != second || presented[2] != second {
+		t.Errorf("presented certificates %x, want the first, then the rotated one twice", presented)
+	}
+}
@@ -0,0 +1,65 @@
+//
+// Package compress registers the br and zstd Content-Encodings with the
+// gohttp client when imported:
+//
+//	import _ "github.com/alphaflow/gohttp/compress"
+//
+//	err := cli.Get(ctx, url, http.WithCompression("br", "zstd", "gzip"), http.WithJSONResponse(&out))
+//
+package compress
+
+import (
+	"bufio"
+	"io"
+	nethttp "net/http"
+
+	http "github.com/alphaflow/gohttp"
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+func init() {
+	http.RegisterContentEncoding("br", decodeBrotli)
+	http.RegisterContentEncoding("zstd", decodeZstd)
+}
+
+func decodeBrotli(body io.Reader) (io.Reader, error) {
+	br, empty := peek(body)
+	if empty {
+		return nethttp.NoBody, nil
+	}
+	return brotli.NewReader(br), nil
+}
+
+func decodeZstd(body io.Reader) (io.Reader, error) {
+	br, empty := peek(body)
+	if empty {
+		return nethttp.NoBody, nil
+	}
+	// A single-threaded decoder decodes as it is read, without goroutines to stop.
+	d, err := zstd.NewReader(br, zstd.WithDecoderConcurrency(1))
+	if err != nil {
+		return nil, err
+	}
+	return &zstdReader{d: d}, nil
+}
+
+// peek reports whether body is empty, returning a reader for all of it.
+func peek(body io.Reader) (*bufio.Reader, bool) {
+	br := bufio.NewReader(body)
+	_, err := br.Peek(1)
+	return br, err == io.EOF
+}
+
+// zstdReader releases the decoder once the stream is read.
+type zstdReader struct {
+	d *zstd.Decoder
+}
+
+func (zr *zstdReader) Read(p []byte) (int, error) {
+	n, err := zr.d.Read(p)
+	if err != nil {
+		zr.d.Close()
+	}
+	return n, err
+}
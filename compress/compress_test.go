@@ -0,0 +1,82 @@
+package compress
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	nethttp "net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	http "github.com/alphaflow/gohttp"
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+var payload = `{"greeting":"` + strings.Repeat("hello ", 1000) + `"}`
+
+func encode(t *testing.T, encoding string) []byte {
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	switch encoding {
+	case "br":
+		w = brotli.NewWriter(&buf)
+	case "zstd":
+		var err error
+		if w, err = zstd.NewWriter(&buf); err != nil {
+			t.Fatal(err)
+		}
+	}
+	io.WriteString(w, payload)
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestWithCompression_round_trip(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		if got := r.Header.Get("Accept-Encoding"); got != "br, zstd, gzip" {
+			t.Errorf("Accept-Encoding = %q, want br, zstd, gzip", got)
+		}
+		encoding := r.URL.Query().Get("encoding")
+		w.Header().Set("Content-Encoding", encoding)
+		if r.Method != "HEAD" {
+			w.Write(encode(t, encoding))
+		}
+	}))
+	defer srv.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+	cli := http.NewClient()
+
+	for _, encoding := range []string{"br", "zstd"} {
+		var got map[string]string
+		err := cli.Get(ctx, srv.URL, http.WithParam("encoding", encoding), http.WithCompression("br", "zstd", "gzip"), http.WithJSONResponse(&got))
+		if err != nil || `{"greeting":"`+got["greeting"]+`"}` != payload {
+			t.Errorf("Get(%s) error = %v, want the decoded payload", encoding, err)
+		}
+
+		var out bytes.Buffer
+		err = cli.Get(ctx, srv.URL, http.WithParam("encoding", encoding), http.WithCompression("br", "zstd", "gzip"), http.WithResponse(&out))
+		if err != nil || out.String() != payload {
+			t.Errorf("Get(%s) wrote %d bytes, %v, want the decoded payload", encoding, out.Len(), err)
+		}
+
+		if err := cli.Do(ctx, "HEAD", srv.URL, http.WithParam("encoding", encoding), http.WithCompression("br", "zstd", "gzip"), http.WithDiscardBody()); err != nil {
+			t.Errorf("HEAD(%s) error = %v, want an empty body", encoding, err)
+		}
+	}
+}
+
+func TestWithCompression_unregistered(t *testing.T) {
+	t.Parallel()
+	_, err := http.NewClient().DryRun(context.Background(), "GET", "http://example.com", http.WithCompression("lz4"))
+	if !errors.Is(err, http.ErrInvalidOption) {
+		t.Errorf("DryRun() error = %v, want %v", err, http.ErrInvalidOption)
+	}
+}
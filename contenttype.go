@@ -0,0 +1,64 @@
+package http
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"strings"
+)
+
+// contentTypeSnippetBytes is how much of an unexpected body a ContentTypeError holds.
+const contentTypeSnippetBytes = 64
+
+//
+// WithExpectedContentType will fail a successful request with a
+// *ContentTypeError, before its body is decoded, unless the response media
+// type is mediaType. It can be given more than once to accept any of several
+// media types.
+//
+// Parameters such as charset are ignored on both sides, so
+// "application/json; charset=utf-8" matches "application/json".
+//
+func WithExpectedContentType(mediaType string) RequestOption {
+	return func(r *Request) {
+		r.expectedContentTypes = append(r.expectedContentTypes, baseMediaType(mediaType))
+	}
+}
+
+// ContentTypeError is returned when a response does not have the media type given to WithExpectedContentType.
+type ContentTypeError struct {
+	URL         string
+	ContentType string
+	Expected    []string
+	// BodyPrefix holds the first bytes of the body, to show what was received instead.
+	BodyPrefix []byte
+}
+
+func (cte *ContentTypeError) Error() string {
+	return fmt.Sprintf("unexpected Content-Type %q from %s (want %s), body begins %q", cte.ContentType, cte.URL, strings.Join(cte.Expected, " or "), cte.BodyPrefix)
+}
+
+// checkContentType returns a *ContentTypeError if contentType is not one of the expected media types.
+func (req *Request) checkContentType(contentType string, body io.Reader) error {
+	if len(req.expectedContentTypes) == 0 {
+		return nil
+	}
+	got := baseMediaType(contentType)
+	for _, want := range req.expectedContentTypes {
+		if got == want {
+			return nil
+		}
+	}
+	prefix, _ := ioutil.ReadAll(io.LimitReader(body, contentTypeSnippetBytes))
+	return &ContentTypeError{URL: req.URL, ContentType: contentType, Expected: req.expectedContentTypes, BodyPrefix: prefix}
+}
+
+// baseMediaType returns the lower-case media type of a Content-Type, without its parameters.
+func baseMediaType(contentType string) string {
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		return mediaType
+	}
+	mediaType := strings.SplitN(contentType, ";", 2)[0]
+	return strings.ToLower(strings.TrimSpace(mediaType))
+}
@@ -0,0 +1,34 @@
+package http
+
+import (
+	"net/http"
+	"net/http/cookiejar"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+//
+// WithCookieJar will store the cookies set by responses in jar, and send
+// them with later requests to the same hosts, as a browser would.
+//
+// Cookies given to a request with WithCookie are sent alongside those from
+// the jar.
+//
+func WithCookieJar(jar http.CookieJar) ClientOption {
+	return func(c *clientConfig) {
+		c.cookieJar = jar
+	}
+}
+
+//
+// WithInMemoryCookies is WithCookieJar with a new in-memory jar, which uses
+// the public suffix list so that a response cannot set cookies for a whole
+// domain such as co.uk.
+//
+func WithInMemoryCookies() ClientOption {
+	return func(c *clientConfig) {
+		// cookiejar.New only fails for invalid options.
+		jar, _ := cookiejar.New(&cookiejar.Options{PublicSuffixList: publicsuffix.List})
+		c.cookieJar = jar
+	}
+}
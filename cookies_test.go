@@ -0,0 +1,39 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWithInMemoryCookies(t *testing.T) {
+	t.Parallel()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "s3cret", Path: "/"})
+	})
+	mux.HandleFunc("/account", func(w http.ResponseWriter, r *http.Request) {
+		if c, err := r.Cookie("session"); err != nil || c.Value != "s3cret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte("alice"))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	ctx := context.Background()
+
+	if err := NewClient().Get(ctx, srv.URL+"/account"); err == nil {
+		t.Fatalf("Get() without session error = nil, want 401")
+	}
+
+	cli := NewClient(WithInMemoryCookies())
+	if err := cli.Post(ctx, srv.URL+"/login"); err != nil {
+		t.Fatalf("Post() login error = %v", err)
+	}
+	var name string
+	if err := cli.Get(ctx, srv.URL+"/account", WithStringResponse(&name)); err != nil || name != "alice" {
+		t.Errorf("Get() after login = %q, %v, want alice", name, err)
+	}
+}
@@ -0,0 +1,184 @@
+package http
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+)
+
+// CSVOption controls how WithCSVResponse reads the rows.
+type CSVOption func(*csvConfig)
+
+type csvConfig struct {
+	skipHeader bool
+	comma      rune
+}
+
+// WithCSVSkipHeader will drop the first row of the response, holding the column names.
+func WithCSVSkipHeader() CSVOption {
+	return func(c *csvConfig) {
+		c.skipHeader = true
+	}
+}
+
+// WithCSVComma will split fields on comma instead of ','.
+func WithCSVComma(comma rune) CSVOption {
+	return func(c *csvConfig) {
+		c.comma = comma
+	}
+}
+
+//
+// WithCSVResponse will read a CSV response one row at a time, passing each
+// to onRow.
+//
+// Only one row is held in memory at a time. Reading stops at the first
+// malformed row or onRow error, and the error names the row, counting from 1.
+//
+func WithCSVResponse(onRow func(record []string) error, options ...CSVOption) RequestOption {
+	cfg := csvConfig{comma: ','}
+	for _, o := range options {
+		o(&cfg)
+	}
+	return func(r *Request) {
+		url := r.URL
+		r.Header.Add("Accept", "text/csv")
+		r.ResponseCallback = func(status int, header http.Header, body io.Reader) error {
+			return cfg.read(url, header.Get("Content-Type"), body, func(row int, record []string) error {
+				if row == 1 && cfg.skipHeader {
+					return nil
+				}
+				return onRow(record)
+			})
+		}
+	}
+}
+
+//
+// WithCSVStructResponse will read a CSV response whose first row names the
+// columns, setting the fields of a value from newRow for every other row and
+// passing it to onRow.
+//
+// Columns map to the fields with the same name, or to the name in a
+// `csv:"name"` tag. Fields may be strings, bools, integers or floats;
+// columns without a field are ignored.
+//
+func WithCSVStructResponse(newRow func() interface{}, onRow func(interface{}) error, options ...CSVOption) RequestOption {
+	cfg := csvConfig{comma: ','}
+	for _, o := range options {
+		o(&cfg)
+	}
+	return func(r *Request) {
+		url := r.URL
+		r.Header.Add("Accept", "text/csv")
+		r.ResponseCallback = func(status int, header http.Header, body io.Reader) error {
+			var columns []string
+			return cfg.read(url, header.Get("Content-Type"), body, func(row int, record []string) error {
+				if row == 1 {
+					columns = record
+					return nil
+				}
+				v := newRow()
+				if err := setCSVFields(v, columns, record); err != nil {
+					return &DecodeError{Format: "CSV", URL: url, ContentType: header.Get("Content-Type"), Err: fmt.Errorf("row %d: %w", row, err)}
+				}
+				return onRow(v)
+			})
+		}
+	}
+}
+
+// read passes the rows of body to fn, numbered from 1.
+func (cfg csvConfig) read(url, contentType string, body io.Reader, fn func(row int, record []string) error) error {
+	cr := csv.NewReader(body)
+	cr.Comma = cfg.comma
+	for row := 1; ; row++ {
+		record, err := cr.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return &DecodeError{Format: "CSV", URL: url, ContentType: contentType, Err: fmt.Errorf("row %d: %w", row, err)}
+		}
+		if err := fn(row, record); err != nil {
+			if _, ok := err.(*DecodeError); ok {
+				return err
+			}
+			return fmt.Errorf("CSV row %d of %s: %w", row, url, err)
+		}
+	}
+}
+
+// setCSVFields sets the fields of the struct v points to from the columns of record.
+func setCSVFields(v interface{}, columns, record []string) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("%T is not a pointer to a struct", v)
+	}
+	rv = rv.Elem()
+	rt := rv.Type()
+
+	fields := make(map[string]int, rt.NumField())
+	for i := 0; i < rt.NumField(); i++ {
+		f := rt.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		name := f.Name
+		if tag, ok := f.Tag.Lookup("csv"); ok {
+			if tag == "-" {
+				continue
+			}
+			name = tag
+		}
+		fields[name] = i
+	}
+
+	for i, column := range columns {
+		fi, ok := fields[column]
+		if !ok || i >= len(record) {
+			continue
+		}
+		if err := setCSVField(rv.Field(fi), record[i]); err != nil {
+			return fmt.Errorf("column %q: %w", column, err)
+		}
+	}
+	return nil
+}
+
+func setCSVField(f reflect.Value, s string) error {
+	switch f.Kind() {
+	case reflect.String:
+		f.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		f.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, f.Type().Bits())
+		if err != nil {
+			return err
+		}
+		f.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, f.Type().Bits())
+		if err != nil {
+			return err
+		}
+		f.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(s, f.Type().Bits())
+		if err != nil {
+			return err
+		}
+		f.SetFloat(n)
+	default:
+		return fmt.Errorf("unsupported field type %s", f.Type())
+	}
+	return nil
+}
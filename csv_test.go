@@ -0,0 +1,112 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newCSVTestServer(t *testing.T, body string) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Accept"); got != "text/csv" {
+			t.Errorf("Accept = %q, want text/csv", got)
+		}
+		w.Header().Set("Content-Type", "text/csv")
+		io.WriteString(w, body)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestGet_csv(t *testing.T) {
+	t.Parallel()
+	srv := newCSVTestServer(t, "name,count\nalpha,1\n\"be,ta\",2\n")
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	var got [][]string
+	err := NewClient().Get(ctx, srv.URL, WithCSVResponse(func(record []string) error {
+		got = append(got, record)
+		return nil
+	}, WithCSVSkipHeader()))
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if want := [][]string{{"alpha", "1"}, {"be,ta", "2"}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Get() rows = %v, want %v", got, want)
+	}
+}
+
+type csvReportRow struct {
+	Name    string `csv:"name"`
+	Count   int    `csv:"count"`
+	Active  bool
+	Ignored string `csv:"-"`
+}
+
+func TestGet_csv_struct(t *testing.T) {
+	t.Parallel()
+	srv := newCSVTestServer(t, "Active;count;name;extra\ntrue;3;alpha;x\nfalse;4;beta;y\n")
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	var got []csvReportRow
+	err := NewClient().Get(ctx, srv.URL, WithCSVStructResponse(
+		func() interface{} { return &csvReportRow{} },
+		func(v interface{}) error {
+			got = append(got, *v.(*csvReportRow))
+			return nil
+		}, WithCSVComma(';')))
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	want := []csvReportRow{{Name: "alpha", Count: 3, Active: true}, {Name: "beta", Count: 4}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Get() rows = %+v, want %+v", got, want)
+	}
+}
+
+func TestGet_csv_errors(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+	cli := NewClient()
+
+	var n int
+	srv := newCSVTestServer(t, "a,b\nc,d\ne\n")
+	err := cli.Get(ctx, srv.URL, WithCSVResponse(func([]string) error {
+		n++
+		return nil
+	}))
+	var de *DecodeError
+	if !errors.As(err, &de) || !strings.Contains(err.Error(), "row 3") || n != 2 {
+		t.Errorf("Get() error = %v after %d rows, want *DecodeError on row 3 after 2 rows", err, n)
+	}
+
+	srv = newCSVTestServer(t, "count\n1\nmany\n")
+	err = cli.Get(ctx, srv.URL, WithCSVStructResponse(
+		func() interface{} { return &csvReportRow{} },
+		func(interface{}) error { return nil }))
+	if !errors.As(err, &de) || !strings.Contains(err.Error(), `row 3: column "count"`) {
+		t.Errorf("Get() error = %v, want *DecodeError for the count of row 3", err)
+	}
+
+	errStop := errors.New("stop")
+	n = 0
+	srv = newCSVTestServer(t, "a\nb\nc\n")
+	err = cli.Get(ctx, srv.URL, WithCSVResponse(func([]string) error {
+		if n++; n == 2 {
+			return errStop
+		}
+		return nil
+	}))
+	if !errors.Is(err, errStop) || !strings.Contains(err.Error(), "row 2") || n != 2 {
+		t.Errorf("Get() error = %v after %d rows, want %v on row 2", err, n, errStop)
+	}
+}
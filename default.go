@@ -0,0 +1,75 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// defaultClientTimeout bounds every request made through the default client.
+const defaultClientTimeout = 30 * time.Second
+
+var (
+	defaultClient     atomic.Value // clientHolder
+	defaultClientOnce sync.Once
+)
+
+// clientHolder lets atomic.Value hold any Client implementation.
+type clientHolder struct {
+	c Client
+}
+
+//
+// DefaultClient returns the Client used by the package-level functions.
+//
+// Unless replaced with SetDefaultClient, it is created on first use with a
+// request timeout, so that a stuck server cannot hang a small tool forever.
+//
+func DefaultClient() Client {
+	defaultClientOnce.Do(func() {
+		defaultClient.CompareAndSwap(nil, clientHolder{newDefaultClient()})
+	})
+	return defaultClient.Load().(clientHolder).c
+}
+
+// newDefaultClient returns a client as DefaultClient creates it.
+func newDefaultClient() Client {
+	return newClient(http.Client{Timeout: defaultClientTimeout}, nil)
+}
+
+// SetDefaultClient replaces the Client used by the package-level functions and returns the previous one.
+// A nil c restores a client as DefaultClient creates it. It is safe to call concurrently with requests.
+func SetDefaultClient(c Client) Client {
+	// The first client is created before the swap, so that one is always returned.
+	DefaultClient()
+	if c == nil {
+		c = newDefaultClient()
+	}
+	return defaultClient.Swap(clientHolder{c}).(clientHolder).c
+}
+
+// SetDefaultClientForTest replaces the default Client until the test and its subtests complete.
+func SetDefaultClientForTest(t TB, c Client) {
+	t.Helper()
+	prev := SetDefaultClient(c)
+	t.Cleanup(func() {
+		SetDefaultClient(prev)
+	})
+}
+
+// Get makes a GET request with the default Client.
+func Get(ctx context.Context, url string, options ...RequestOption) error {
+	return DefaultClient().Get(ctx, url, options...)
+}
+
+// Post makes a POST request with the default Client.
+func Post(ctx context.Context, url string, options ...RequestOption) error {
+	return DefaultClient().Post(ctx, url, options...)
+}
+
+// Do makes a request with any HTTP method with the default Client.
+func Do(ctx context.Context, method, url string, options ...RequestOption) error {
+	return DefaultClient().Do(ctx, method, url, options...)
+}
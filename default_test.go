@@ -0,0 +1,101 @@
+package http
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestDefaultClient_swap(t *testing.T) {
+	var hitsA, hitsB int64
+	clientA := NewMockClient(func(ctx context.Context, r *Request) error {
+		atomic.AddInt64(&hitsA, 1)
+		return nil
+	})
+	clientB := NewMockClient(func(ctx context.Context, r *Request) error {
+		atomic.AddInt64(&hitsB, 1)
+		return nil
+	})
+	SetDefaultClientForTest(t, clientA)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				if err := Get(context.Background(), "http://example.com"); err != nil {
+					t.Errorf("Get() error = %v", err)
+				}
+			}
+		}()
+	}
+	for i := 0; i < 100; i++ {
+		if i%2 == 0 {
+			SetDefaultClient(clientB)
+		} else {
+			SetDefaultClient(clientA)
+		}
+	}
+	wg.Wait()
+
+	if total := atomic.LoadInt64(&hitsA) + atomic.LoadInt64(&hitsB); total != 800 {
+		t.Errorf("default clients saw %d requests, want 800", total)
+	}
+}
+
+func TestSetDefaultClientForTest_restores(t *testing.T) {
+	orig := DefaultClient()
+	mock := NewMockClient(func(ctx context.Context, r *Request) error {
+		return nil
+	})
+
+	t.Run("swapped", func(t *testing.T) {
+		SetDefaultClientForTest(t, mock)
+		if DefaultClient() != mock {
+			t.Errorf("DefaultClient() was not replaced")
+		}
+	})
+	if DefaultClient() != orig {
+		t.Errorf("DefaultClient() was not restored after the test")
+	}
+}
+
+func TestSetDefaultClient_nil(t *testing.T) {
+	SetDefaultClientForTest(t, nil)
+	cl, ok := DefaultClient().(*client)
+	if !ok || cl.client.Timeout != defaultClientTimeout {
+		t.Errorf("DefaultClient() after SetDefaultClient(nil) = %#v, want a client with the default timeout", DefaultClient())
+	}
+}
+
+func TestSetDefaultClient_concurrent(t *testing.T) {
+	orig := SetDefaultClient(nil)
+	defer SetDefaultClient(orig)
+	first := DefaultClient()
+
+	// Every client set is returned exactly once, by the next swap or at the end.
+	clients := make([]Client, 16)
+	prevs := make([]Client, len(clients))
+	var wg sync.WaitGroup
+	for i := range clients {
+		clients[i] = NewMockClient(func(ctx context.Context, r *Request) error { return nil })
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			prevs[i] = SetDefaultClient(clients[i])
+		}(i)
+	}
+	wg.Wait()
+
+	seen := map[Client]int{DefaultClient(): 1}
+	for _, prev := range prevs {
+		seen[prev]++
+	}
+	for _, c := range append(clients, first) {
+		if seen[c] != 1 {
+			t.Errorf("client %p returned %d times, want once", c, seen[c])
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package http
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"time"
+)
+
+//
+// WithDialContext will open connections with dial instead of a net.Dialer,
+// e.g. to resolve hostnames with an internal resolver. TLS is still
+// negotiated by the transport on top of the returned connection.
+//
+// Like the connection pool options, it configures a copy of the transport,
+// which must be an *http.Transport when given with WithTransport.
+//
+func WithDialContext(dial func(ctx context.Context, network, addr string) (net.Conn, error)) ClientOption {
+	return func(c *clientConfig) {
+		c.dialContext = dial
+	}
+}
+
+//
+// WithHostOverride will connect to addr whenever a request is for host,
+// while still sending host in the Host header and as the TLS server name,
+// e.g. to pin a hostname to a canary IP. host can include a port to only
+// override that port; addr can leave out the port to keep the requested one.
+//
+// It can be given more than once, and applies to connections opened by
+// WithDialContext too.
+//
+func WithHostOverride(host, addr string) ClientOption {
+	return func(c *clientConfig) {
+		if c.hostOverrides == nil {
+			c.hostOverrides = map[string]string{}
+		}
+		c.hostOverrides[host] = addr
+	}
+}
+
+//
+// WithLocalAddr will open connections from the local IP address ip, e.g. on
+// multi-homed hosts where firewall rules are keyed on source addresses. An
+// unparseable ip makes NewClient panic.
+//
+// A WithDialContext dialer takes precedence, and must bind the address
+// itself.
+//
+func WithLocalAddr(ip string) ClientOption {
+	addr := net.ParseIP(ip)
+	return func(c *clientConfig) {
+		if addr == nil {
+			panic("invalid local address " + strconv.Quote(ip))
+		}
+		c.localAddr = &net.TCPAddr{IP: addr}
+	}
+}
+
+// dialFunc is the signature of http.Transport.DialContext.
+type dialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// useDialer configures the transport of c to dial according to the dialing options.
+func (c *client) useDialer() {
+	option := "WithDialContext"
+	if c.config.dialContext == nil {
+		switch {
+		case c.config.localAddr != nil:
+			option = "WithLocalAddr"
+		case c.config.dnsCache != nil:
+			option = "WithDNSCache"
+		default:
+			option = "WithHostOverride"
+		}
+	}
+	t := c.cloneTransport(option)
+	dial := dialFunc(c.config.dialContext)
+	if dial == nil {
+		// The defaults of http.DefaultTransport.
+		dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+		if c.config.localAddr != nil {
+			dialer.LocalAddr = c.config.localAddr
+		}
+		dial = dialer.DialContext
+	}
+	if c.config.dnsCache != nil {
+		dial = c.config.dnsCache.wrap(dial)
+	}
+	if len(c.config.hostOverrides) > 0 {
+		dial = overrideHosts(dial, c.config.hostOverrides)
+	}
+	t.DialContext = dial
+	c.client.Transport = t
+}
+
+// overrideHosts returns a dial function connecting to the overridden address of hosts in overrides.
+func overrideHosts(dial dialFunc, overrides map[string]string) dialFunc {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if to, ok := overrides[addr]; ok {
+			return dial(ctx, network, to)
+		}
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return dial(ctx, network, addr)
+		}
+		to, ok := overrides[host]
+		if !ok {
+			return dial(ctx, network, addr)
+		}
+		if _, _, err := net.SplitHostPort(to); err != nil {
+			to = net.JoinHostPort(to, port)
+		}
+		return dial(ctx, network, to)
+	}
+}
@@ -0,0 +1,95 @@
+package http
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestWithHostOverride(t *testing.T) {
+	t.Parallel()
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Host))
+	})
+	plain := httptest.NewServer(handler)
+	defer plain.Close()
+	tlsSrv := httptest.NewTLSServer(handler)
+	defer tlsSrv.Close()
+	_, plainPort, _ := net.SplitHostPort(strings.TrimPrefix(plain.URL, "http://"))
+	_, tlsPort, _ := net.SplitHostPort(strings.TrimPrefix(tlsSrv.URL, "https://"))
+
+	for _, tt := range []struct {
+		name string
+		cli  Client
+		url  string
+	}{
+		{name: "plain", cli: NewClient(WithHostOverride("api.internal", "127.0.0.1")), url: "http://api.internal:" + plainPort},
+		{name: "plain with port", cli: NewClient(WithHostOverride("api.internal:80", "127.0.0.1:"+plainPort)), url: "http://api.internal"},
+		// The httptest certificate is for example.com, so TLS verifies the original host.
+		{name: "TLS", cli: NewTLSClient(tlsSrv.Client().Transport.(*http.Transport).TLSClientConfig, WithHostOverride("example.com", "127.0.0.1")), url: "https://example.com:" + tlsPort},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var host string
+			if err := tt.cli.Get(context.Background(), tt.url, WithStringResponse(&host)); err != nil {
+				t.Fatalf("Get() error = %v", err)
+			}
+			if want := strings.SplitN(tt.url, "//", 2)[1]; host != want {
+				t.Errorf("server saw Host %q, want %q", host, want)
+			}
+		})
+	}
+}
+
+func TestWithDialContext(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	var dials int32
+	var dialed atomic.Value
+	var dialer net.Dialer
+	cli := NewClient(
+		WithDialContext(func(ctx context.Context, network, addr string) (net.Conn, error) {
+			atomic.AddInt32(&dials, 1)
+			dialed.Store(addr)
+			return dialer.DialContext(ctx, network, addr)
+		}),
+		WithHostOverride("canary.internal", strings.TrimPrefix(srv.URL, "http://")),
+	)
+	if err := cli.Get(context.Background(), "http://canary.internal/"); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if atomic.LoadInt32(&dials) != 1 || dialed.Load() != strings.TrimPrefix(srv.URL, "http://") {
+		t.Errorf("custom dialer called %d times with %v, want once with the overridden address", dials, dialed.Load())
+	}
+}
+
+func TestWithLocalAddr(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, _ := net.SplitHostPort(r.RemoteAddr)
+		w.Write([]byte(host))
+	}))
+	defer srv.Close()
+	if conn, err := (&net.Dialer{LocalAddr: &net.TCPAddr{IP: net.ParseIP("127.0.0.2")}}).Dial("tcp", strings.TrimPrefix(srv.URL, "http://")); err != nil {
+		t.Skipf("loopback alias 127.0.0.2 unavailable: %v", err)
+	} else {
+		conn.Close()
+	}
+
+	var remote string
+	if err := NewClient(WithLocalAddr("127.0.0.2")).Get(context.Background(), srv.URL, WithStringResponse(&remote)); err != nil || remote != "127.0.0.2" {
+		t.Errorf("server saw RemoteAddr %q, %v, want 127.0.0.2", remote, err)
+	}
+
+	defer func() {
+		if p, _ := recover().(string); !strings.Contains(p, `invalid local address "10.0.0.300"`) {
+			t.Errorf("NewClient() panic = %q, want invalid local address", p)
+		}
+	}()
+	NewClient(WithLocalAddr("10.0.0.300"))
+}
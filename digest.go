@@ -0,0 +1,180 @@
+package http
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"strings"
+)
+
+//
+// WithDigestAuth will answer a 401 response carrying a
+// WWW-Authenticate: Digest challenge (RFC 7616) by sending the request once
+// more with the Authorization header computed from the credentials.
+//
+// The MD5 and SHA-256 algorithms, and their -sess variants, are supported
+// with qop=auth. The request is only retried if its body can be sent again,
+// as a WithJSONBody body can; otherwise the 401 is returned.
+//
+func WithDigestAuth(username, password string) RequestOption {
+	return func(r *Request) {
+		r.digestAuth = &digestCredentials{username: username, password: password}
+	}
+}
+
+type digestCredentials struct {
+	username string
+	password string
+}
+
+// digestChallenge holds the parameters of a WWW-Authenticate: Digest header.
+type digestChallenge map[string]string
+
+// retry sends r again with send, answering the Digest challenge of resp, returning resp if it cannot.
+func (dc *digestCredentials) retry(r *http.Request, resp *http.Response, send func(*http.Request) (*http.Response, error)) (*http.Response, error) {
+	if resp.StatusCode != http.StatusUnauthorized || !canReplay(r) {
+		return resp, nil
+	}
+	challenge, ok := selectDigestChallenge(resp.Header.Values("WWW-Authenticate"))
+	if !ok {
+		return resp, nil
+	}
+	auth, err := dc.authorization(challenge, r.Method, r.URL.RequestURI())
+	if err != nil {
+		return nil, err
+	}
+
+	retry, err := replayRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	retry.Header.Set("Authorization", auth)
+
+	discardResponse(resp)
+	return send(retry)
+}
+
+// selectDigestChallenge returns the strongest supported Digest challenge among the WWW-Authenticate headers.
+func selectDigestChallenge(headers []string) (digestChallenge, bool) {
+	var best digestChallenge
+	for _, h := range headers {
+		scheme := strings.SplitN(strings.TrimSpace(h), " ", 2)
+		if len(scheme) != 2 || !strings.EqualFold(scheme[0], "Digest") {
+			continue
+		}
+		c := digestChallenge(parseAuthParams(scheme[1]))
+		if c.newHash() == nil || !c.supportsQOP() {
+			continue
+		}
+		if best == nil || strings.HasPrefix(strings.ToUpper(c["algorithm"]), "SHA-256") {
+			best = c
+		}
+	}
+	return best, best != nil
+}
+
+// newHash returns the hash function of the challenge algorithm, or nil if it is unsupported.
+func (c digestChallenge) newHash() func() hash.Hash {
+	switch strings.ToUpper(c["algorithm"]) {
+	case "", "MD5", "MD5-SESS":
+		return md5.New
+	case "SHA-256", "SHA-256-SESS":
+		return sha256.New
+	}
+	return nil
+}
+
+func (c digestChallenge) supportsQOP() bool {
+	if c["qop"] == "" {
+		return true
+	}
+	for _, qop := range strings.Split(c["qop"], ",") {
+		if strings.TrimSpace(qop) == "auth" {
+			return true
+		}
+	}
+	return false
+}
+
+// authorization returns the Authorization header answering the challenge for a request.
+func (dc *digestCredentials) authorization(c digestChallenge, method, uri string) (string, error) {
+	newHash := c.newHash()
+	h := func(s string) string {
+		sum := newHash()
+		io.WriteString(sum, s)
+		return hex.EncodeToString(sum.Sum(nil))
+	}
+
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	cnonce := hex.EncodeToString(buf)
+	const nc = "00000001"
+
+	ha1 := h(dc.username + ":" + c["realm"] + ":" + dc.password)
+	if strings.HasSuffix(strings.ToUpper(c["algorithm"]), "-SESS") {
+		ha1 = h(ha1 + ":" + c["nonce"] + ":" + cnonce)
+	}
+	ha2 := h(method + ":" + uri)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `Digest username=%q, realm=%q, nonce=%q, uri=%q`, dc.username, c["realm"], c["nonce"], uri)
+	if c["algorithm"] != "" {
+		fmt.Fprintf(&b, `, algorithm=%s`, c["algorithm"])
+	}
+	if c["qop"] != "" {
+		fmt.Fprintf(&b, `, response=%q, qop=auth, nc=%s, cnonce=%q`, h(ha1+":"+c["nonce"]+":"+nc+":"+cnonce+":auth:"+ha2), nc, cnonce)
+	} else {
+		fmt.Fprintf(&b, `, response=%q`, h(ha1+":"+c["nonce"]+":"+ha2))
+	}
+	if opaque, ok := c["opaque"]; ok {
+		fmt.Fprintf(&b, `, opaque=%q`, opaque)
+	}
+	return b.String(), nil
+}
+
+// parseAuthParams parses the comma-separated name=value parameters of an authentication header.
+func parseAuthParams(s string) map[string]string {
+	params := map[string]string{}
+	for s = strings.TrimSpace(s); s != ""; {
+		eq := strings.IndexByte(s, '=')
+		if eq < 0 {
+			break
+		}
+		name := strings.ToLower(strings.TrimSpace(s[:eq]))
+		s = strings.TrimSpace(s[eq+1:])
+
+		var value string
+		if strings.HasPrefix(s, `"`) {
+			var b strings.Builder
+			i := 1
+			for ; i < len(s) && s[i] != '"'; i++ {
+				if s[i] == '\\' && i+1 < len(s) {
+					i++
+				}
+				b.WriteByte(s[i])
+			}
+			value = b.String()
+			if i < len(s) {
+				i++
+			}
+			s = s[i:]
+		} else {
+			end := strings.IndexByte(s, ',')
+			if end < 0 {
+				end = len(s)
+			}
+			value = strings.TrimSpace(s[:end])
+			s = s[end:]
+		}
+		params[name] = value
+		s = strings.TrimLeft(s, ", ")
+	}
+	return params
+}
@@ -0,0 +1,107 @@
+package http
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newDigestServer challenges every request without valid Digest credentials for alice:secret.
+func newDigestServer(t *testing.T, algorithm string) (*httptest.Server, *int32) {
+	var requests int32
+	newHash := map[string]func() hash.Hash{"MD5": md5.New, "SHA-256": sha256.New}[algorithm]
+	h := func(s string) string {
+		sum := newHash()
+		io.WriteString(sum, s)
+		return hex.EncodeToString(sum.Sum(nil))
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		body, _ := ioutil.ReadAll(r.Body)
+		auth := r.Header.Get("Authorization")
+		if strings.HasPrefix(auth, "Digest ") {
+			p := parseAuthParams(strings.TrimPrefix(auth, "Digest "))
+			ha1 := h("alice:devices:secret")
+			ha2 := h(r.Method + ":" + r.URL.RequestURI())
+			want := h(ha1 + ":abc123:" + p["nc"] + ":" + p["cnonce"] + ":auth:" + ha2)
+			if p["uri"] == r.URL.RequestURI() && p["response"] == want && p["opaque"] == "xyz" {
+				w.Write(body)
+				return
+			}
+		}
+		w.Header().Add("WWW-Authenticate", `Basic realm="devices"`)
+		w.Header().Add("WWW-Authenticate", `Digest realm="devices", qop="auth,auth-int", algorithm=`+algorithm+`, nonce="abc123", opaque="xyz"`)
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &requests
+}
+
+func TestWithDigestAuth(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+	cli := NewClient()
+
+	for _, algorithm := range []string{"MD5", "SHA-256"} {
+		srv, requests := newDigestServer(t, algorithm)
+		var got map[string]string
+		err := cli.Post(ctx, srv.URL+"/config?x=1", WithJSONBody(map[string]string{"a": "b"}), WithDigestAuth("alice", "secret"), WithJSONResponse(&got))
+		if err != nil || got["a"] != "b" {
+			t.Errorf("%s: Post() = %v, %v, want the echoed body", algorithm, got, err)
+		}
+		if *requests != 2 {
+			t.Errorf("%s: server saw %d requests, want the challenge and one retry", algorithm, *requests)
+		}
+
+		var bse *BadStatusError
+		if err := cli.Get(ctx, srv.URL, WithDigestAuth("alice", "wrong")); !errors.As(err, &bse) || bse.Code != http.StatusUnauthorized {
+			t.Errorf("%s: Get() error = %v, want 401 for the wrong password", algorithm, err)
+		}
+	}
+}
+
+func TestParseAuthParams(t *testing.T) {
+	t.Parallel()
+	got := parseAuthParams(`realm="a, \"b\"", qop=auth, nonce="n"`)
+	if got["realm"] != `a, "b"` || got["qop"] != "auth" || got["nonce"] != "n" {
+		t.Errorf("parseAuthParams() = %q", got)
+	}
+}
+
+func TestWithDigestAuth_replayOptions(t *testing.T) {
+	t.Parallel()
+	var followed int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/elsewhere":
+			atomic.AddInt32(&followed, 1)
+		case strings.HasPrefix(r.Header.Get("Authorization"), "Digest "):
+			http.Redirect(w, r, "/elsewhere", http.StatusFound)
+		default:
+			w.Header().Set("WWW-Authenticate", `Digest realm="devices", qop="auth", nonce="abc123"`)
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer srv.Close()
+
+	var status int
+	err := NewClient().Get(context.Background(), srv.URL, WithDigestAuth("alice", "secret"), WithNoRedirects(), WithExpectStatus(http.StatusFound), WithStatusCode(&status))
+	if err != nil || status != http.StatusFound {
+		t.Errorf("Get() = %d, %v, want the replay's 302 unfollowed", status, err)
+	}
+	if n := atomic.LoadInt32(&followed); n != 0 {
+		t.Errorf("the replay followed the redirect %d times, want WithNoRedirects to apply to it", n)
+	}
+}
@@ -0,0 +1,162 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+// maxNegativeDNSTTL bounds how long a failed lookup is cached, so that a resolver brownout does not outlast itself.
+const maxNegativeDNSTTL = time.Second
+
+// dnsLookupTimeout bounds a shared lookup, which goes on when the request starting it gives up.
+const dnsLookupTimeout = 10 * time.Second
+
+// HostResolver looks up the addresses of a host, as a *net.Resolver does.
+type HostResolver interface {
+	LookupHost(ctx context.Context, host string) ([]string, error)
+}
+
+//
+// DNSCache remembers the addresses hostnames resolve to for a TTL, so that
+// clients dialing the same hosts do not resolve them again for every
+// connection. Failed lookups are remembered for at most a second.
+//
+// Concurrent lookups of the same host share a single query, which runs on
+// its own goroutine for at most 10 seconds. The query is not a background
+// task of any client, so Client.Close does not wait for it. A DNSCache is
+// safe for concurrent use and can be shared by several clients.
+//
+type DNSCache struct {
+	ttl      time.Duration
+	resolver HostResolver
+	now      func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]dnsEntry
+	calls   map[string]*dnsCall
+}
+
+type dnsEntry struct {
+	addrs   []string
+	err     error
+	expires time.Time
+}
+
+type dnsCall struct {
+	done  chan struct{}
+	addrs []string
+	err   error
+}
+
+// NewDNSCache constructs a DNSCache resolving hosts with resolver, or net.DefaultResolver if it is nil.
+func NewDNSCache(ttl time.Duration, resolver HostResolver) *DNSCache {
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	return &DNSCache{
+		ttl:      ttl,
+		resolver: resolver,
+		now:      time.Now,
+		entries:  map[string]dnsEntry{},
+		calls:    map[string]*dnsCall{},
+	}
+}
+
+//
+// WithDNSCache will resolve the hosts the client connects to through a new
+// DNSCache keeping addresses for ttl.
+//
+// Use WithDNSCacheStore to share the cache or flush it.
+//
+func WithDNSCache(ttl time.Duration) ClientOption {
+	return func(c *clientConfig) {
+		c.dnsCache = NewDNSCache(ttl, nil)
+	}
+}
+
+// WithDNSCacheStore will resolve the hosts the client connects to through cache, like WithDNSCache.
+func WithDNSCacheStore(cache *DNSCache) ClientOption {
+	return func(c *clientConfig) {
+		c.dnsCache = cache
+	}
+}
+
+// Flush forgets every cached lookup, so that the next connections resolve their hosts again.
+func (dc *DNSCache) Flush() {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+	dc.entries = map[string]dnsEntry{}
+}
+
+// lookup returns the addresses of host, from the cache if they have not expired.
+func (dc *DNSCache) lookup(ctx context.Context, host string) ([]string, error) {
+	dc.mu.Lock()
+	if e, ok := dc.entries[host]; ok && dc.now().Before(e.expires) {
+		dc.mu.Unlock()
+		return e.addrs, e.err
+	}
+	call, ok := dc.calls[host]
+	if !ok {
+		call = &dnsCall{done: make(chan struct{})}
+		dc.calls[host] = call
+		// The lookup is shared, so it must not end with the caller starting it.
+		lookupCtx, cancel := context.WithTimeout(detachedContext{ctx}, dnsLookupTimeout)
+		go func() {
+			defer cancel()
+			dc.resolve(lookupCtx, host, call)
+		}()
+	}
+	dc.mu.Unlock()
+
+	select {
+	case <-call.done:
+		return call.addrs, call.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// resolve performs the lookup of call and caches its outcome.
+func (dc *DNSCache) resolve(ctx context.Context, host string, call *dnsCall) {
+	call.addrs, call.err = dc.resolver.LookupHost(ctx, host)
+
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+	delete(dc.calls, host)
+	ttl := dc.ttl
+	if call.err != nil && ttl > maxNegativeDNSTTL {
+		ttl = maxNegativeDNSTTL
+	}
+	// A lookup cut short says nothing about the host.
+	if !errors.Is(call.err, context.Canceled) && !errors.Is(call.err, context.DeadlineExceeded) {
+		dc.entries[host] = dnsEntry{addrs: call.addrs, err: call.err, expires: dc.now().Add(ttl)}
+	}
+	close(call.done)
+}
+
+// wrap returns a dial function connecting to the cached addresses of the host in addr, in turn.
+func (dc *DNSCache) wrap(dial dialFunc) dialFunc {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil || net.ParseIP(host) != nil {
+			return dial(ctx, network, addr)
+		}
+		addrs, err := dc.lookup(ctx, host)
+		if err != nil {
+			return nil, &net.OpError{Op: "dial", Net: network, Err: err}
+		}
+		var conn net.Conn
+		for _, ip := range addrs {
+			if conn, err = dial(ctx, network, net.JoinHostPort(ip, port)); err == nil {
+				return conn, nil
+			}
+		}
+		if err == nil {
+			err = &net.DNSError{Err: "no addresses", Name: host, IsNotFound: true}
+		}
+		return nil, err
+	}
+}
@@ -0,0 +1,175 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// stubResolver resolves hosts from a map, counting lookups and optionally holding them until release is closed or the lookup is canceled.
+type stubResolver struct {
+	mu      sync.Mutex
+	hosts   map[string][]string
+	lookups map[string]int
+	release chan struct{}
+}
+
+func (sr *stubResolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	sr.mu.Lock()
+	sr.lookups[host]++
+	addrs, ok := sr.hosts[host]
+	release := sr.release
+	sr.mu.Unlock()
+	if release != nil {
+		select {
+		case <-release:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	if !ok {
+		return nil, errors.New("no such host")
+	}
+	return addrs, nil
+}
+
+func (sr *stubResolver) count(host string) int {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+	return sr.lookups[host]
+}
+
+func TestWithDNSCache(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	port := srv.URL[strings.LastIndex(srv.URL, ":")+1:]
+	ctx := context.Background()
+
+	resolver := &stubResolver{hosts: map[string][]string{"api.internal": {"127.0.0.1"}}, lookups: map[string]int{}}
+	cache := NewDNSCache(time.Minute, resolver)
+	cli := NewClient(WithDNSCacheStore(cache), WithDisableKeepAlives())
+
+	for i := 0; i < 5; i++ {
+		if err := cli.Get(ctx, "http://api.internal:"+port); err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+	}
+	if n := resolver.count("api.internal"); n != 1 {
+		t.Errorf("lookups after 5 requests = %d, want 1", n)
+	}
+
+	cache.Flush()
+	if err := cli.Get(ctx, "http://api.internal:"+port); err != nil {
+		t.Fatalf("Get() after Flush() error = %v", err)
+	}
+	if n := resolver.count("api.internal"); n != 2 {
+		t.Errorf("lookups after Flush() = %d, want 2", n)
+	}
+}
+
+func TestDNSCache_expiry(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	resolver := &stubResolver{hosts: map[string][]string{"api.internal": {"10.0.0.1"}}, lookups: map[string]int{}}
+	cache := NewDNSCache(time.Minute, resolver)
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+
+	for _, tt := range []struct {
+		host    string
+		advance time.Duration
+		wantErr bool
+		want    int
+	}{
+		{host: "api.internal", want: 1},
+		{host: "api.internal", advance: 30 * time.Second, want: 1},
+		{host: "api.internal", advance: 31 * time.Second, want: 2},
+		{host: "missing.internal", wantErr: true, want: 1},
+		{host: "missing.internal", advance: 500 * time.Millisecond, wantErr: true, want: 1},
+		// Failures expire after a second rather than the TTL.
+		{host: "missing.internal", advance: 600 * time.Millisecond, wantErr: true, want: 2},
+	} {
+		now = now.Add(tt.advance)
+		_, err := cache.lookup(ctx, tt.host)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("lookup(%q) error = %v, want error %v", tt.host, err, tt.wantErr)
+		}
+		if n := resolver.count(tt.host); n != tt.want {
+			t.Errorf("lookups of %q after %v = %d, want %d", tt.host, tt.advance, n, tt.want)
+		}
+	}
+}
+
+func TestDNSCache_concurrent(t *testing.T) {
+	t.Parallel()
+	resolver := &stubResolver{
+		hosts:   map[string][]string{"api.internal": {"10.0.0.1"}},
+		lookups: map[string]int{},
+		release: make(chan struct{}),
+	}
+	cache := NewDNSCache(time.Minute, resolver)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if addrs, err := cache.lookup(context.Background(), "api.internal"); err != nil || len(addrs) != 1 {
+				t.Errorf("lookup() = %v, %v", addrs, err)
+			}
+		}()
+	}
+	// Let every lookup join the first before it completes.
+	for resolver.count("api.internal") == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(resolver.release)
+	wg.Wait()
+	if n := resolver.count("api.internal"); n != 1 {
+		t.Errorf("lookups = %d, want 1 shared by concurrent callers", n)
+	}
+}
+
+func TestDNSCache_cancel(t *testing.T) {
+	t.Parallel()
+	resolver := &stubResolver{
+		hosts:   map[string][]string{"api.internal": {"10.0.0.1"}},
+		lookups: map[string]int{},
+		release: make(chan struct{}),
+	}
+	cache := NewDNSCache(time.Minute, resolver)
+
+	// The caller starting the lookup gives up; the one waiting for it still gets the addresses.
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan error)
+	go func() {
+		_, err := cache.lookup(ctx, "api.internal")
+		first <- err
+	}()
+	for resolver.count("api.internal") == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	second := make(chan []string)
+	go func() {
+		addrs, _ := cache.lookup(context.Background(), "api.internal")
+		second <- addrs
+	}()
+	cancel()
+	if err := <-first; !errors.Is(err, context.Canceled) {
+		t.Errorf("canceled lookup() error = %v, want context.Canceled", err)
+	}
+	close(resolver.release)
+	if addrs := <-second; len(addrs) != 1 {
+		t.Errorf("lookup() = %v, want the shared lookup's addresses", addrs)
+	}
+	if n := resolver.count("api.internal"); n != 1 {
+		t.Errorf("lookups = %d, want 1", n)
+	}
+}
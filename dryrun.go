@@ -0,0 +1,111 @@
+package http
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+)
+
+// ErrInvalidOption is returned for an option with invalid arguments, and by DryRun for an option that would panic when sending the request.
+var ErrInvalidOption = errors.New("invalid request option")
+
+//
+// PreparedRequest describes the request that would be sent, as returned by
+// DryRun.
+//
+// Sensitive headers are redacted, so it is safe to display or log.
+//
+type PreparedRequest struct {
+	Method     string      `json:"method"`
+	URL        string      `json:"url"`
+	Header     http.Header `json:"header"`
+	BodySize   int64       `json:"body_size"`
+	BodySHA256 string      `json:"body_sha256,omitempty"`
+	// Policies names the client-level behaviors that apply to the request.
+	Policies []string `json:"policies,omitempty"`
+}
+
+func (c *client) DryRun(ctx context.Context, method, url string, options ...RequestOption) (pr *PreparedRequest, err error) {
+	defer recoverOptionPanic(&err)
+	req, r, policies, err := c.dryRun(ctx, method, url, options)
+	if err != nil {
+		return nil, err
+	}
+	return describeRequest(req, r, policies)
+}
+
+// dryRun builds the request that would be sent, applying the client-level policies it names.
+func (c *client) dryRun(ctx context.Context, method, url string, options []RequestOption) (*Request, *http.Request, []string, error) {
+	req, err := c.previewRequest(method, url, options)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	r, err := req.prepareSigned(ctx)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	var policies []string
+	if req.envelope != nil {
+		policies = append(policies, "response-envelope")
+	}
+	if c.config.certSource != nil {
+		policies = append(policies, "certificate-source")
+	}
+	if hsts, ok := c.client.Transport.(*hstsTransport); ok {
+		policies = append(policies, "hsts")
+		if r.URL.Scheme == "http" && hsts.store.match(r.URL.Hostname()) {
+			if hsts.noUpgrade {
+				return nil, nil, nil, &HSTSError{Host: r.URL.Hostname(), URL: r.URL.String()}
+			}
+			r = upgradeToHTTPS(r)
+		}
+	}
+	return req, r, policies, nil
+}
+
+func (mc *mockClient) DryRun(ctx context.Context, method, url string, options ...RequestOption) (pr *PreparedRequest, err error) {
+	defer recoverOptionPanic(&err)
+	req, err := mc.newRequest(method, url, options)
+	if err != nil {
+		return nil, err
+	}
+	r, err := req.prepareSigned(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return describeRequest(req, r, nil)
+}
+
+// recoverOptionPanic turns a panic raised while applying options into an ErrInvalidOption.
+func recoverOptionPanic(err *error) {
+	if p := recover(); p != nil {
+		*err = fmt.Errorf("%w: %v", ErrInvalidOption, p)
+	}
+}
+
+func describeRequest(req *Request, r *http.Request, policies []string) (*PreparedRequest, error) {
+	pr := PreparedRequest{
+		Method:   r.Method,
+		URL:      redactURL(r.URL, req.apiKey.secretParam()),
+		Header:   redactHeader(r.Header, req.apiKey.secretHeader()),
+		Policies: policies,
+	}
+	if req.BodyReader != nil {
+		// Reading a streamed body would consume it.
+		pr.BodySize = r.ContentLength
+	} else if r.Body != nil {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			return nil, err
+		}
+		sum := sha256.Sum256(body)
+		pr.BodySize = int64(len(body))
+		pr.BodySHA256 = hex.EncodeToString(sum[:])
+	}
+	return &pr, nil
+}
@@ -0,0 +1,110 @@
+package http
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+type failingTransport struct {
+	t *testing.T
+}
+
+func (ft failingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	ft.t.Errorf("unexpected request to %s", r.URL)
+	return nil, errors.New("unexpected request")
+}
+
+func TestDryRun(t *testing.T) {
+	t.Parallel()
+	cli := newClient(http.Client{Transport: failingTransport{t}}, []ClientOption{
+		WithResponseEnvelope(EnvelopeConfig{DataPath: "/data"}),
+	})
+
+	var resp map[string]string
+	pr, err := cli.DryRun(context.Background(), "POST", "http://example.com/items",
+		WithParam("debug", "1"),
+		WithHeader("Authorization", "Bearer secret"),
+		WithJSONBody(map[string]string{"hello": "world"}),
+		WithJSONResponse(&resp))
+	if err != nil {
+		t.Fatalf("DryRun() error = %v", err)
+	}
+
+	body := []byte(`{"hello":"world"}`)
+	sum := sha256.Sum256(body)
+	want := &PreparedRequest{
+		Method: "POST",
+		URL:    "http://example.com/items?debug=1",
+		Header: http.Header{
+			"Authorization": {"REDACTED"},
+			"Content-Type":  {"application/json"},
+			"Accept":        {"application/json"},
+			"User-Agent":    {DefaultUserAgent},
+		},
+		BodySize:   int64(len(body)),
+		BodySHA256: hex.EncodeToString(sum[:]),
+		Policies:   []string{"response-envelope"},
+	}
+	if !reflect.DeepEqual(pr, want) {
+		t.Errorf("DryRun() = %+v, want %+v", pr, want)
+	}
+	if _, err := json.Marshal(pr); err != nil {
+		t.Errorf("json.Marshal() error = %v", err)
+	}
+}
+
+func TestDryRun_errors(t *testing.T) {
+	t.Parallel()
+	cli := newClient(http.Client{Transport: failingTransport{t}}, nil)
+
+	var x, j map[string]string
+	dryRunErrorTestCases := []struct {
+		name    string
+		method  string
+		url     string
+		options []RequestOption
+		wantErr error
+	}{
+		{
+			name:    "conflicting options",
+			method:  "GET",
+			url:     "http://example.com",
+			options: []RequestOption{WithXMLResponse(&x), WithJSONResponse(&j)},
+			wantErr: ErrConflictingOptions,
+		},
+		{
+			name:    "body on GET",
+			method:  "GET",
+			url:     "http://example.com",
+			options: []RequestOption{WithJSONBody("hello")},
+			wantErr: ErrInvalidOption,
+		},
+		{
+			name:    "unmarshalable body",
+			method:  "POST",
+			url:     "http://example.com",
+			options: []RequestOption{WithJSONBody(make(chan int))},
+		},
+		{
+			name:   "bad url",
+			method: "GET",
+			url:    "://example.com",
+		},
+	}
+	for _, tt := range dryRunErrorTestCases {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, c := range []Client{cli, NewMockClient(nil)} {
+				_, err := c.DryRun(context.Background(), tt.method, tt.url, tt.options...)
+				if err == nil || (tt.wantErr != nil && !errors.Is(err, tt.wantErr)) {
+					t.Errorf("%T.DryRun() error = %v, want %v", c, err, tt.wantErr)
+				}
+			}
+		})
+	}
+}
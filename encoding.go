@@ -0,0 +1,128 @@
+package http
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+//
+// ContentDecoder returns a reader decompressing body, which is encoded with a
+// Content-Encoding registered with RegisterContentEncoding.
+//
+// An empty body must be read as empty rather than fail, as it is for HEAD
+// requests.
+//
+type ContentDecoder func(body io.Reader) (io.Reader, error)
+
+var contentEncodings = struct {
+	sync.RWMutex
+	decoders map[string]ContentDecoder
+}{decoders: map[string]ContentDecoder{
+	"gzip":    decodeGzip,
+	"x-gzip":  decodeGzip,
+	"deflate": decodeDeflate,
+}}
+
+//
+// RegisterContentEncoding makes responses with this Content-Encoding
+// decompressed with decode, and lets WithCompression advertise it.
+//
+// gzip and deflate are built in. Packages adding an encoding, such as
+// github.com/alphaflow/gohttp/compress, register it from an init function.
+//
+func RegisterContentEncoding(name string, decode ContentDecoder) {
+	contentEncodings.Lock()
+	defer contentEncodings.Unlock()
+	contentEncodings.decoders[strings.ToLower(name)] = decode
+}
+
+func contentDecoder(name string) ContentDecoder {
+	contentEncodings.RLock()
+	defer contentEncodings.RUnlock()
+	return contentEncodings.decoders[name]
+}
+
+//
+// WithCompression will advertise these encodings, in order of preference,
+// in the Accept-Encoding header.
+//
+// Each must be registered with RegisterContentEncoding, or the request fails
+// with ErrInvalidOption.
+//
+func WithCompression(encodings ...string) RequestOption {
+	return func(r *Request) {
+		r.acceptEncodings = append(r.acceptEncodings, encodings...)
+		r.Header.Set("Accept-Encoding", strings.Join(r.acceptEncodings, ", "))
+	}
+}
+
+// validateCompression checks the arguments of WithCompression.
+func (req *Request) validateCompression() error {
+	for _, encoding := range req.acceptEncodings {
+		if contentDecoder(strings.ToLower(encoding)) == nil {
+			return fmt.Errorf("%w: no decoder registered for Content-Encoding %q", ErrInvalidOption, encoding)
+		}
+	}
+	return nil
+}
+
+//
+// WithRawEncoding will pass a compressed response body to the outputs as it
+// was received, instead of decompressing it.
+//
+// Go's transport already decompresses the responses to the Accept-Encoding
+// it adds itself. This client also decompresses the rest, e.g. when
+// Accept-Encoding was set with WithHeader or the transport has
+// DisableCompression set.
+//
+func WithRawEncoding() RequestOption {
+	return func(r *Request) {
+		r.rawEncoding = true
+	}
+}
+
+// decodeContentEncoding returns a reader decompressing body according to the Content-Encoding of httpResp.
+func (req *Request) decodeContentEncoding(httpResp *http.Response, body io.Reader) (io.Reader, error) {
+	encoding := strings.ToLower(strings.TrimSpace(httpResp.Header.Get("Content-Encoding")))
+	if req.rawEncoding || encoding == "" || encoding == "identity" {
+		return body, nil
+	}
+	decode := contentDecoder(encoding)
+	if decode == nil {
+		return body, nil
+	}
+	decoded, err := decode(body)
+	if err != nil {
+		return nil, fmt.Errorf("decompressing %s response from %s: %w", encoding, req.URL, err)
+	}
+	return decoded, nil
+}
+
+func decodeGzip(body io.Reader) (io.Reader, error) {
+	zr, err := gzip.NewReader(body)
+	if err == io.EOF {
+		return http.NoBody, nil
+	}
+	return zr, err
+}
+
+func decodeDeflate(body io.Reader) (io.Reader, error) {
+	// deflate is meant to be zlib-wrapped, but some servers send a raw stream.
+	br := bufio.NewReader(body)
+	if header, err := br.Peek(2); err == nil && isZlibHeader(header) {
+		return zlib.NewReader(br)
+	}
+	return flate.NewReader(br), nil
+}
+
+// isZlibHeader reports whether b starts with a zlib header using the deflate method.
+func isZlibHeader(b []byte) bool {
+	return b[0]&0x0f == 8 && (uint16(b[0])<<8|uint16(b[1]))%31 == 0
+}
@@ -0,0 +1,89 @@
+package http
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func compress(t *testing.T, encoding, s string) []byte {
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	switch encoding {
+	case "gzip":
+		w = gzip.NewWriter(&buf)
+	case "deflate":
+		w = zlib.NewWriter(&buf)
+	case "raw-deflate":
+		w, _ = flate.NewWriter(&buf, flate.DefaultCompression)
+	}
+	io.WriteString(w, s)
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestGet_content_encoding(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		encoding := r.URL.Query().Get("encoding")
+		w.Header().Set("Content-Encoding", encoding)
+		if encoding == "raw-deflate" {
+			w.Header().Set("Content-Encoding", "deflate")
+		}
+		w.Write(compress(t, encoding, `{"hello":"world"}`))
+	}))
+	defer srv.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+	// DisableCompression keeps the transport from decompressing gzip itself.
+	cli := newClient(http.Client{Transport: &http.Transport{DisableCompression: true}}, nil)
+
+	for _, encoding := range []string{"gzip", "deflate", "raw-deflate"} {
+		var got map[string]string
+		if err := cli.Get(ctx, srv.URL, WithParam("encoding", encoding), WithHeader("Accept-Encoding", "gzip, deflate"), WithJSONResponse(&got)); err != nil || got["hello"] != "world" {
+			t.Errorf("Get(%s) = %v, %v, want the decompressed JSON", encoding, got, err)
+		}
+
+		var out bytes.Buffer
+		if err := cli.Get(ctx, srv.URL, WithParam("encoding", encoding), WithResponse(&out)); err != nil || out.String() != `{"hello":"world"}` {
+			t.Errorf("Get(%s) wrote %q, %v, want the decompressed body", encoding, out.String(), err)
+		}
+	}
+
+	var raw []byte
+	if err := cli.Get(ctx, srv.URL, WithParam("encoding", "gzip"), WithRawEncoding(), WithBytesResponse(&raw)); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if !bytes.Equal(raw, compress(t, "gzip", `{"hello":"world"}`)) {
+		t.Errorf("Get() with WithRawEncoding = %q, want the gzip bytes", raw)
+	}
+}
+
+func TestGet_content_encoding_max_bytes(t *testing.T) {
+	t.Parallel()
+	body := compress(t, "gzip", strings.Repeat("a", 10<<20))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Write(body)
+	}))
+	defer srv.Close()
+	cli := newClient(http.Client{Transport: &http.Transport{DisableCompression: true}}, nil)
+
+	var rtle *ResponseTooLargeError
+	err := cli.Get(context.Background(), srv.URL, WithMaxResponseBytes(64<<10), WithResponse(ioutil.Discard))
+	if !errors.As(err, &rtle) || rtle.Limit != 64<<10 {
+		t.Errorf("Get() of a %d byte body decompressing past the limit error = %v, want a *ResponseTooLargeError", len(body), err)
+	}
+}
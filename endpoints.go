@@ -0,0 +1,68 @@
+package http
+
+import (
+	"net/url"
+	"sync/atomic"
+)
+
+type endpointSet struct {
+	raw  []string
+	urls []*url.URL
+	next uint32
+}
+
+//
+// WithEndpoints will spread the requests with relative URLs, such as
+// "/users/42", over these endpoints in turn, resolving each against the
+// next endpoint as WithBaseURL does. Absolute URLs are used unchanged.
+//
+// Endpoints that the WithEndpointHealth callback reports unhealthy are
+// skipped; if every endpoint is, they are all used in turn anyway. A
+// malformed or relative endpoint, or WithBaseURL given too, makes NewClient
+// panic.
+//
+func WithEndpoints(urls ...string) ClientOption {
+	set := endpointSet{raw: urls}
+	var err error
+	for _, raw := range urls {
+		var u *url.URL
+		if u, err = parseBaseURL(raw); err != nil {
+			break
+		}
+		set.urls = append(set.urls, u)
+	}
+	return func(c *clientConfig) {
+		if err != nil {
+			panic("invalid endpoint: " + err.Error())
+		}
+		if len(set.urls) == 0 {
+			panic("WithEndpoints requires at least one endpoint")
+		}
+		// Each client counts its own turns.
+		c.endpoints = &endpointSet{raw: set.raw, urls: set.urls}
+	}
+}
+
+// WithEndpointHealth will skip the WithEndpoints endpoints for which healthy, given the endpoint as passed to WithEndpoints, returns false.
+func WithEndpointHealth(healthy func(endpoint string) bool) ClientOption {
+	return func(c *clientConfig) {
+		c.endpointHealth = healthy
+	}
+}
+
+// pickEndpoint returns the next healthy WithEndpoints endpoint, taking its turn if take is set.
+func (c *client) pickEndpoint(take bool) *url.URL {
+	set := c.config.endpoints
+	n := int(atomic.LoadUint32(&set.next))
+	if take {
+		n = int(atomic.AddUint32(&set.next, 1) - 1)
+	}
+	if healthy := c.config.endpointHealth; healthy != nil {
+		for i := 0; i < len(set.urls); i++ {
+			if e := (n + i) % len(set.urls); healthy(set.raw[e]) {
+				return set.urls[e]
+			}
+		}
+	}
+	return set.urls[n%len(set.urls)]
+}
@@ -0,0 +1,113 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestWithEndpoints(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	var status [3]int32
+	var urls []string
+	for i, name := range []string{"a", "b", "c"} {
+		srv := newRegionServer(t, name, &status[i])
+		defer srv.Close()
+		urls = append(urls, srv.URL+"/api")
+	}
+
+	count := func(c Client, n int) map[string]int {
+		var mu sync.Mutex
+		var wg sync.WaitGroup
+		counts := map[string]int{}
+		for i := 0; i < n; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				var got string
+				if err := c.Get(ctx, "/ping", WithStringResponse(&got)); err != nil {
+					t.Errorf("Get() error = %v", err)
+					return
+				}
+				mu.Lock()
+				defer mu.Unlock()
+				counts[got]++
+			}()
+		}
+		wg.Wait()
+		return counts
+	}
+
+	t.Run("even distribution", func(t *testing.T) {
+		counts := count(NewClient(WithEndpoints(urls...)), 30)
+		for _, name := range []string{"a", "b", "c"} {
+			if n := counts[name+" /api/ping"]; n != 10 {
+				t.Errorf("endpoint %s got %d requests, want 10 (all: %v)", name, n, counts)
+			}
+		}
+	})
+
+	t.Run("unhealthy skipped", func(t *testing.T) {
+		atomic.StoreInt32(&status[1], http.StatusServiceUnavailable)
+		defer atomic.StoreInt32(&status[1], 0)
+		healthy := func(endpoint string) bool { return endpoint != urls[1] }
+		counts := count(NewClient(WithEndpoints(urls...), WithEndpointHealth(healthy)), 30)
+		if counts["b /api/ping"] != 0 || counts["a /api/ping"]+counts["c /api/ping"] != 30 {
+			t.Errorf("requests per endpoint = %v, want none for the unhealthy b", counts)
+		}
+	})
+
+	t.Run("DryRun and Explain take no turn", func(t *testing.T) {
+		c := NewClient(WithEndpoints(urls...))
+		for i := 0; i < 2; i++ {
+			pr, err := c.DryRun(ctx, http.MethodGet, "/ping")
+			if err != nil || pr.URL != urls[0]+"/ping" {
+				t.Errorf("DryRun() URL = %v, %v, want %q", pr, err, urls[0]+"/ping")
+			}
+			if e, err := c.Explain(ctx, http.MethodGet, "/ping"); err != nil || e.Request.URL != urls[0]+"/ping" {
+				t.Errorf("Explain() = %v, %v, want the URL %q", e, err, urls[0]+"/ping")
+			}
+		}
+		var got string
+		if err := c.Get(ctx, "/ping", WithStringResponse(&got)); err != nil || got != "a /api/ping" {
+			t.Errorf("Get() = %q, %v, want the first endpoint", got, err)
+		}
+		if pr, err := c.DryRun(ctx, http.MethodGet, "/ping"); err != nil || pr.URL != urls[1]+"/ping" {
+			t.Errorf("DryRun() after Get() = %v, %v, want the URL %q", pr, err, urls[1]+"/ping")
+		}
+	})
+
+	t.Run("absolute URL", func(t *testing.T) {
+		var got string
+		c := NewClient(WithEndpoints(urls[0]))
+		if err := c.Get(ctx, strings.TrimSuffix(urls[2], "/api")+"/x", WithStringResponse(&got)); err != nil || got != "c /x" {
+			t.Errorf("Get() = %q, %v, want the absolute URL used as is", got, err)
+		}
+	})
+}
+
+func TestWithEndpointsInvalid(t *testing.T) {
+	t.Parallel()
+	for _, tt := range []struct {
+		name string
+		opts []ClientOption
+		want string
+	}{
+		{name: "relative", opts: []ClientOption{WithEndpoints("http://a.example", "/b")}, want: "invalid endpoint"},
+		{name: "none", opts: []ClientOption{WithEndpoints()}, want: "at least one endpoint"},
+		{name: "with base URL", opts: []ClientOption{WithEndpoints("http://a.example"), WithBaseURL("http://b.example")}, want: "conflict"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if p := recover(); p == nil || !strings.Contains(p.(string), tt.want) {
+					t.Errorf("NewClient() panic = %v, want one mentioning %q", p, tt.want)
+				}
+			}()
+			NewClient(tt.opts...)
+		})
+	}
+}
@@ -0,0 +1,192 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+//
+// EnvelopeConfig describes a JSON envelope wrapped around every response,
+// such as {"ok": true, "data": {...}, "error": {...}}.
+//
+// Paths are JSON Pointers (RFC 6901), e.g. "/data".
+//
+type EnvelopeConfig struct {
+	// DataPath locates the payload decoded into the WithJSONResponse target.
+	DataPath string
+	// SuccessPath locates the success indicator. If empty, every envelope is successful.
+	SuccessPath string
+	// SuccessValue is the value at SuccessPath of a successful envelope. Defaults to true.
+	SuccessValue interface{}
+	// ErrorPath locates the error object of an unsuccessful envelope.
+	ErrorPath string
+	// NewError returns a pointer to decode the error object into.
+	// Defaults to a *map[string]interface{}.
+	NewError func() interface{}
+}
+
+//
+// WithResponseEnvelope unwraps the JSON envelope described by cfg from every
+// response decoded with WithJSONResponse.
+//
+// Only the data sub-document is decoded into the target, and an unsuccessful
+// envelope is returned as an *EnvelopeError even when the HTTP status is 2xx.
+//
+func WithResponseEnvelope(cfg EnvelopeConfig) ClientOption {
+	return func(c *clientConfig) {
+		c.envelope = &cfg
+	}
+}
+
+// describe summarizes the envelope for Explain.
+func (cfg *EnvelopeConfig) describe() string {
+	detail := fmt.Sprintf("data at %q", cfg.DataPath)
+	if cfg.SuccessPath != "" {
+		var want interface{} = true
+		if cfg.SuccessValue != nil {
+			want = cfg.SuccessValue
+		}
+		detail += fmt.Sprintf(", success when %q is %v", cfg.SuccessPath, want)
+	}
+	return detail
+}
+
+// WithoutEnvelope decodes the whole response body, ignoring the client's envelope configuration.
+func WithoutEnvelope() RequestOption {
+	return func(r *Request) {
+		r.envelope = nil
+	}
+}
+
+// EnvelopeError is returned when a response envelope reports failure.
+type EnvelopeError struct {
+	URL string
+	// Detail is the decoded error object, as returned by EnvelopeConfig.NewError.
+	Detail interface{}
+	// Raw is the undecoded error object, if the envelope had one.
+	Raw json.RawMessage
+}
+
+func (ee *EnvelopeError) Error() string {
+	return fmt.Sprintf("unsuccessful response envelope from %s: %s", ee.URL, ee.Raw)
+}
+
+// unwrap returns the data sub-document of the envelope in buf.
+func (cfg *EnvelopeConfig) unwrap(url, contentType string, buf []byte) ([]byte, error) {
+	malformed := func(err error) error {
+		return &DecodeError{Format: "JSON envelope", URL: url, ContentType: contentType, Err: err}
+	}
+	if !json.Valid(buf) {
+		return nil, malformed(errors.New("invalid JSON"))
+	}
+
+	if cfg.SuccessPath != "" {
+		raw, ok, err := jsonPointer(buf, cfg.SuccessPath)
+		if err != nil {
+			return nil, malformed(err)
+		}
+		if !ok {
+			return nil, malformed(fmt.Errorf("missing success indicator %s", cfg.SuccessPath))
+		}
+		if !cfg.isSuccess(raw) {
+			return nil, cfg.envelopeError(url, buf)
+		}
+	}
+
+	data, ok, err := jsonPointer(buf, cfg.DataPath)
+	if err != nil {
+		return nil, malformed(err)
+	}
+	if !ok {
+		return []byte("null"), nil
+	}
+	return data, nil
+}
+
+func (cfg *EnvelopeConfig) isSuccess(raw json.RawMessage) bool {
+	var want interface{} = true
+	if cfg.SuccessValue != nil {
+		// Round trip through JSON so that e.g. 1 and 1.0 compare equal.
+		j, err := json.Marshal(cfg.SuccessValue)
+		if err != nil || json.Unmarshal(j, &want) != nil {
+			return false
+		}
+	}
+	var got interface{}
+	if err := json.Unmarshal(raw, &got); err != nil {
+		return false
+	}
+	return reflect.DeepEqual(got, want)
+}
+
+func (cfg *EnvelopeConfig) envelopeError(url string, buf []byte) error {
+	var ee = EnvelopeError{URL: url}
+	if cfg.ErrorPath == "" {
+		return &ee
+	}
+	raw, ok, err := jsonPointer(buf, cfg.ErrorPath)
+	if err != nil || !ok {
+		return &ee
+	}
+	ee.Raw = raw
+
+	if cfg.NewError != nil {
+		ee.Detail = cfg.NewError()
+	} else {
+		ee.Detail = &map[string]interface{}{}
+	}
+	if err := json.Unmarshal(raw, ee.Detail); err != nil {
+		ee.Detail = nil
+	}
+	return &ee
+}
+
+// jsonPointer returns the value at the RFC 6901 pointer ptr within doc.
+func jsonPointer(doc []byte, ptr string) (json.RawMessage, bool, error) {
+	if ptr == "" {
+		return doc, true, nil
+	}
+	if ptr[0] != '/' {
+		return nil, false, fmt.Errorf("invalid JSON pointer %q", ptr)
+	}
+
+	unescape := strings.NewReplacer("~1", "/", "~0", "~")
+	for _, token := range strings.Split(ptr[1:], "/") {
+		token = unescape.Replace(token)
+		trimmed := bytes.TrimSpace(doc)
+		if len(trimmed) == 0 {
+			return nil, false, nil
+		}
+
+		switch trimmed[0] {
+		case '{':
+			var obj map[string]json.RawMessage
+			if err := json.Unmarshal(trimmed, &obj); err != nil {
+				return nil, false, err
+			}
+			v, ok := obj[token]
+			if !ok {
+				return nil, false, nil
+			}
+			doc = v
+		case '[':
+			var arr []json.RawMessage
+			if err := json.Unmarshal(trimmed, &arr); err != nil {
+				return nil, false, err
+			}
+			i, err := strconv.Atoi(token)
+			if err != nil || i < 0 || i >= len(arr) {
+				return nil, false, nil
+			}
+			doc = arr[i]
+		default:
+			return nil, false, nil
+		}
+	}
+	return doc, true, nil
+}
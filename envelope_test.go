@@ -0,0 +1,122 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestGet_envelope(t *testing.T) {
+	t.Parallel()
+	var currentHandler http.HandlerFunc
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		currentHandler(w, r)
+	}))
+	defer srv.Close()
+
+	type payloadType struct {
+		Name string
+	}
+	type errorType struct {
+		Code    string
+		Message string
+	}
+	cli := NewClient(WithResponseEnvelope(EnvelopeConfig{
+		DataPath:    "/data",
+		SuccessPath: "/ok",
+		ErrorPath:   "/error",
+		NewError:    func() interface{} { return &errorType{} },
+	}))
+
+	envelopeTestCases := []struct {
+		name      string
+		respBody  string
+		options   []RequestOption
+		wantResp  payloadType
+		wantErrAs interface{}
+		wantErr   error
+	}{
+		{
+			name:     "successful envelope",
+			respBody: `{"ok": true, "data": {"name": "alex"}}`,
+			wantResp: payloadType{Name: "alex"},
+		},
+		{
+			name:      "unsuccessful envelope",
+			respBody:  `{"ok": false, "error": {"code": "E42", "message": "nope"}}`,
+			wantErrAs: new(*EnvelopeError),
+			wantErr: &EnvelopeError{
+				URL:    srv.URL,
+				Detail: &errorType{Code: "E42", Message: "nope"},
+				Raw:    []byte(`{"code": "E42", "message": "nope"}`),
+			},
+		},
+		{
+			name:      "malformed envelope",
+			respBody:  `{"data": {"name": "alex"}}`,
+			wantErrAs: new(*DecodeError),
+		},
+		{
+			name:      "not json",
+			respBody:  `<html></html>`,
+			wantErrAs: new(*DecodeError),
+		},
+		{
+			name:     "without envelope",
+			respBody: `{"name": "alex"}`,
+			options:  []RequestOption{WithoutEnvelope()},
+			wantResp: payloadType{Name: "alex"},
+		},
+	}
+	for _, tt := range envelopeTestCases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+			defer cancel()
+
+			currentHandler = func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(tt.respBody))
+			}
+
+			var resp payloadType
+			err := cli.Get(ctx, srv.URL, append(tt.options, WithJSONResponse(&resp))...)
+			if tt.wantErrAs == nil && err != nil {
+				t.Errorf("Get() error = %v", err)
+			}
+			if tt.wantErrAs != nil && !errors.As(err, tt.wantErrAs) {
+				t.Errorf("Get() error = %#v, want %T", err, tt.wantErrAs)
+			}
+			if tt.wantErr != nil && !reflect.DeepEqual(err, tt.wantErr) {
+				t.Errorf("Get() error = %#v, want %#v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(tt.wantResp, resp) {
+				t.Errorf("Get() %v, want %v", resp, tt.wantResp)
+			}
+		})
+	}
+}
+
+func TestJSONPointer(t *testing.T) {
+	t.Parallel()
+	doc := []byte(`{"a": {"b/c": [1, {"d~e": "x"}]}}`)
+	pointerTestCases := []struct {
+		ptr    string
+		want   string
+		wantOK bool
+	}{
+		{ptr: "", want: string(doc), wantOK: true},
+		{ptr: "/a/b~1c/0", want: "1", wantOK: true},
+		{ptr: "/a/b~1c/1/d~0e", want: `"x"`, wantOK: true},
+		{ptr: "/a/b~1c/2"},
+		{ptr: "/missing"},
+	}
+	for _, tt := range pointerTestCases {
+		got, ok, err := jsonPointer(doc, tt.ptr)
+		if err != nil || ok != tt.wantOK || string(got) != tt.want {
+			t.Errorf("jsonPointer(%q) = %s, %v, %v, want %s, %v", tt.ptr, got, ok, err, tt.want, tt.wantOK)
+		}
+	}
+}
@@ -0,0 +1,228 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+//
+// Explanation describes what a request would do, as returned by Explain.
+//
+// Layers are listed in the order a request passes through them, from the
+// outermost to the network.
+//
+type Explanation struct {
+	Request *PreparedRequest `json:"request"`
+	Layers  []ExplainedLayer `json:"layers"`
+}
+
+// ExplainedLayer is one behavior applying to an explained request.
+type ExplainedLayer struct {
+	Name   string `json:"name"`
+	Detail string `json:"detail"`
+}
+
+// String renders the explanation for people, one line per layer.
+func (e *Explanation) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s\n", e.Request.Method, e.Request.URL)
+	for _, l := range e.Layers {
+		fmt.Fprintf(&b, "  %-20s %s\n", l.Name, l.Detail)
+	}
+	return b.String()
+}
+
+func (c *client) Explain(ctx context.Context, method, url string, options ...RequestOption) (e *Explanation, err error) {
+	defer recoverOptionPanic(&err)
+	req, r, policies, err := c.dryRun(ctx, method, url, options)
+	if err != nil {
+		return nil, err
+	}
+	pr, err := describeRequest(req, r, policies)
+	if err != nil {
+		return nil, err
+	}
+
+	e = &Explanation{Request: pr}
+	policy := c.retryPolicy(req)
+	e.add("timeout", c.timeoutDetail(ctx, policy))
+	if c.config.endpoints != nil && isRelativeURL(url) {
+		detail := fmt.Sprintf("%d endpoints in turn", len(c.config.endpoints.urls))
+		if c.config.endpointHealth != nil {
+			detail += ", skipping unhealthy ones"
+		}
+		e.add("endpoints", detail)
+	}
+	switch {
+	case req.noRedirects:
+		e.add("redirects", "not followed")
+	case c.config.redirects != nil:
+		e.add("redirects", c.config.redirects.describe())
+	case c.client.CheckRedirect == nil:
+		e.add("redirects", "follows up to 10 redirects")
+	default:
+		e.add("redirects", "custom redirect policy")
+	}
+	if c.config.cache != nil {
+		e.add("cache", c.cacheDetail(req, r))
+	}
+	if c.config.singleflight != nil {
+		if req.shareable() {
+			e.add("singleflight", "shares the response of identical requests in flight")
+		} else {
+			e.add("singleflight", "bypassed, sent on its own")
+		}
+	}
+	if policy != nil && policy.MaxAttempts > 1 {
+		e.add("retry", policy.describe())
+	}
+	if policy := c.hedgePolicy(req); policy != nil {
+		e.add("hedging", policy.describe())
+	}
+	if IsInsecure(c) {
+		e.add("tls", "certificate verification disabled")
+	}
+	if c.config.authorization != "" {
+		e.add("authorization", "client default Basic credentials")
+	}
+	if c.config.tokenSource != nil {
+		if req.Header.Get("Authorization") != "" {
+			e.add("token-source", "skipped, Authorization set by the request")
+		} else {
+			e.add("token-source", "Bearer token fetched before sending")
+		}
+	}
+	if req.envelope != nil {
+		e.add("response-envelope", req.envelope.describe())
+	} else if c.config.envelope != nil {
+		e.add("response-envelope", "bypassed by WithoutEnvelope")
+	}
+	if c.config.hsts != nil {
+		if strings.HasPrefix(req.URL, "http://") && r.URL.Scheme == "https" {
+			e.add("hsts", "known HSTS host, upgraded to https")
+		} else {
+			e.add("hsts", "upgrades http:// requests to known HSTS hosts")
+		}
+	}
+	if c.config.faults != nil {
+		e.add("fault-map", "test faults are injected before the network")
+	}
+	if c.config.certSource != nil {
+		e.add("certificate-source", "client certificate and root CAs from the source on every handshake")
+	}
+	if c.config.dnsCache != nil {
+		e.add("dns-cache", fmt.Sprintf("host lookups cached for %v, each bounded by %v", c.config.dnsCache.ttl, dnsLookupTimeout))
+	}
+	e.add("transport", fmt.Sprintf("%T", networkTransport(c.client.Transport)))
+	e.add("response", req.responseDetail())
+	return e, nil
+}
+
+func (mc *mockClient) Explain(ctx context.Context, method, url string, options ...RequestOption) (e *Explanation, err error) {
+	defer recoverOptionPanic(&err)
+	req, err := mc.newRequest(method, url, options)
+	if err != nil {
+		return nil, err
+	}
+	r, err := req.prepareSigned(ctx)
+	if err != nil {
+		return nil, err
+	}
+	pr, err := describeRequest(req, r, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	e = &Explanation{Request: pr}
+	e.add("mock", "handled by the mock client")
+	e.add("response", req.responseDetail())
+	return e, nil
+}
+
+func (e *Explanation) add(name, detail string) {
+	e.Layers = append(e.Layers, ExplainedLayer{Name: name, Detail: detail})
+}
+
+// networkTransport returns the transport beneath the wrappers added by the client.
+func networkTransport(rt http.RoundTripper) http.RoundTripper {
+	for {
+		switch t := rt.(type) {
+		case *hstsTransport:
+			rt = t.next
+		case *faultTransport:
+			rt = t.next
+		default:
+			return transportOrDefault(rt)
+		}
+	}
+}
+
+// timeoutDetail describes the client timeout, the deadline of ctx and the attempt timeout of policy, if any.
+func (c *client) timeoutDetail(ctx context.Context, policy *RetryPolicy) string {
+	var parts []string
+	if c.client.Timeout > 0 {
+		parts = append(parts, fmt.Sprintf("client timeout %v", c.client.Timeout))
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		parts = append(parts, fmt.Sprintf("context deadline in %v", time.Until(deadline).Round(time.Millisecond)))
+	}
+	if policy != nil && policy.AttemptTimeout > 0 {
+		parts = append(parts, fmt.Sprintf("attempt timeout %v", policy.AttemptTimeout))
+	}
+	if len(parts) == 0 {
+		return "none"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// cacheDetail describes how WithCache would handle req, sent as r.
+func (c *client) cacheDetail(req *Request, r *http.Request) string {
+	switch {
+	case r.Method != http.MethodGet:
+		return "bypassed for " + r.Method
+	case isConditional(r.Header):
+		return "bypassed, conditional or partial request"
+	case len(req.signers) > 0:
+		return "bypassed, signed request"
+	}
+	if _, ok := parseCacheControl(r.Header.Get("Cache-Control"))["no-store"]; ok {
+		return "bypassed, Cache-Control no-store"
+	}
+	return "fresh responses stored under " + cacheKey(req, r)
+}
+
+// responseDetail describes how the response body would be handled.
+func (req *Request) responseDetail() string {
+	parts := req.outputs()
+	if len(parts) == 0 {
+		parts = []string{"body ignored"}
+	}
+	if req.jsonStrict {
+		parts = append(parts, "unknown JSON fields rejected")
+	}
+	if req.jsonNumbers {
+		parts = append(parts, "JSON numbers kept as json.Number")
+	}
+	if len(req.xssiPrefixes) > 0 {
+		parts = append(parts, fmt.Sprintf("%d extra XSSI prefixes", len(req.xssiPrefixes)))
+	}
+	if len(req.expectedContentTypes) > 0 {
+		parts = append(parts, "Content-Type must be "+strings.Join(req.expectedContentTypes, " or "))
+	}
+	if len(req.teeWriters) > 0 {
+		parts = append(parts, fmt.Sprintf("teed to %d writers", len(req.teeWriters)))
+	}
+	if req.rawEncoding {
+		parts = append(parts, "Content-Encoding kept")
+	}
+	if req.maxResponseBytes > 0 {
+		parts = append(parts, fmt.Sprintf("at most %d bytes", req.maxResponseBytes))
+	}
+	if req.downloadProgress != nil {
+		parts = append(parts, "download progress reported")
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,153 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExplain(t *testing.T) {
+	t.Parallel()
+	store := NewHSTSStore(10)
+	store.record("secure.example.com", "max-age=60")
+	cli := newClient(http.Client{Transport: failingTransport{t}}, []ClientOption{
+		WithResponseEnvelope(EnvelopeConfig{DataPath: "/data"}),
+		WithHSTSStore(store),
+	})
+
+	var resp map[string]string
+	explainTestCases := []struct {
+		name    string
+		url     string
+		options []RequestOption
+		want    []ExplainedLayer
+	}{
+		{
+			name:    "client defaults",
+			url:     "http://example.com/items",
+			options: []RequestOption{WithJSONResponse(&resp)},
+			want: []ExplainedLayer{
+				{Name: "timeout", Detail: "none"},
+				{Name: "redirects", Detail: "follows up to 10 redirects"},
+				{Name: "response-envelope", Detail: `data at "/data"`},
+				{Name: "hsts", Detail: "upgrades http:// requests to known HSTS hosts"},
+				{Name: "transport", Detail: "http.failingTransport"},
+				{Name: "response", Detail: "WithJSONResponse"},
+			},
+		},
+		{
+			name:    "bypass and overrides",
+			url:     "http://secure.example.com/items",
+			options: []RequestOption{WithJSONResponse(&resp), WithoutEnvelope(), WithMaxResponseBytes(100)},
+			want: []ExplainedLayer{
+				{Name: "timeout", Detail: "none"},
+				{Name: "redirects", Detail: "follows up to 10 redirects"},
+				{Name: "response-envelope", Detail: "bypassed by WithoutEnvelope"},
+				{Name: "hsts", Detail: "known HSTS host, upgraded to https"},
+				{Name: "transport", Detail: "http.failingTransport"},
+				{Name: "response", Detail: "WithJSONResponse, at most 100 bytes"},
+			},
+		},
+	}
+	for _, tt := range explainTestCases {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := cli.Explain(context.Background(), "GET", tt.url, tt.options...)
+			if err != nil {
+				t.Fatalf("Explain() error = %v", err)
+			}
+			if !reflect.DeepEqual(e.Layers, tt.want) {
+				t.Errorf("Explain() layers = %+v, want %+v", e.Layers, tt.want)
+			}
+			if s := e.String(); !strings.HasPrefix(s, "GET "+e.Request.URL+"\n") || strings.Count(s, "\n") != len(tt.want)+1 {
+				t.Errorf("Explain().String() = %q, want a header and one line per layer", s)
+			}
+		})
+	}
+}
+
+func TestMockClient_explain(t *testing.T) {
+	t.Parallel()
+	cli := NewMockClient(func(ctx context.Context, r *Request) error {
+		t.Errorf("unexpected request to %s", r.URL)
+		return nil
+	})
+	var s string
+	e, err := cli.Explain(context.Background(), "GET", "http://example.com", WithStringResponse(&s))
+	if err != nil {
+		t.Fatalf("Explain() error = %v", err)
+	}
+	want := []ExplainedLayer{
+		{Name: "mock", Detail: "handled by the mock client"},
+		{Name: "response", Detail: "WithStringResponse"},
+	}
+	if !reflect.DeepEqual(e.Layers, want) {
+		t.Errorf("Explain() layers = %+v, want %+v", e.Layers, want)
+	}
+}
+
+func TestExplain_policies(t *testing.T) {
+	t.Parallel()
+	cli := newClient(http.Client{}, []ClientOption{
+		WithEndpoints("http://a.example.com", "http://b.example.com"),
+		WithCache(nil),
+		WithSingleflight(),
+		WithDNSCache(time.Minute),
+		WithRetry(RetryPolicy{MaxAttempts: 3, BaseDelay: 100 * time.Millisecond, AttemptTimeout: 2 * time.Second, MaxElapsedTime: 10 * time.Second, Budget: NewRetryBudget(0.1, time.Minute)}),
+	})
+
+	for _, tt := range []struct {
+		name    string
+		method  string
+		options []RequestOption
+		want    map[string]string
+	}{
+		{
+			name:   "GET",
+			method: http.MethodGet,
+			want: map[string]string{
+				"timeout":      "attempt timeout 2s",
+				"endpoints":    "2 endpoints in turn",
+				"cache":        "fresh responses stored under GET http://a.example.com/items",
+				"singleflight": "shares the response of identical requests in flight",
+				"retry":        "up to 3 attempts, backoff from 100ms, 2s per attempt, no retry past 10s, budget of 10% of requests retried over 1m0s",
+				"dns-cache":    "host lookups cached for 1m0s, each bounded by 10s",
+			},
+		},
+		{
+			name:    "signed",
+			method:  http.MethodGet,
+			options: []RequestOption{WithRequestSigner(HMACSigner([]byte("secret")))},
+			want: map[string]string{
+				"cache":        "bypassed, signed request",
+				"singleflight": "bypassed, sent on its own",
+			},
+		},
+		{
+			name:   "PUT",
+			method: http.MethodPut,
+			want:   map[string]string{"cache": "bypassed for PUT"},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := cli.Explain(context.Background(), tt.method, "/items", tt.options...)
+			if err != nil {
+				t.Fatalf("Explain() error = %v", err)
+			}
+			got := map[string]string{}
+			for _, l := range e.Layers {
+				got[l.Name] = l.Detail
+			}
+			for name, want := range tt.want {
+				if got[name] != want {
+					t.Errorf("Explain() layer %s = %q, want %q", name, got[name], want)
+				}
+			}
+		})
+	}
+	if pr, err := cli.DryRun(context.Background(), http.MethodGet, "/items"); err != nil || pr.URL != "http://a.example.com/items" {
+		t.Errorf("DryRun() after Explain() = %v, %v, want the first endpoint still next", pr, err)
+	}
+}
@@ -0,0 +1,183 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// FailoverOption controls a client from NewFailoverClient.
+type FailoverOption func(*failoverConfig)
+
+type failoverConfig struct {
+	statuses map[int]bool
+	reprobe  time.Duration
+}
+
+// WithFailoverStatuses will fail over on responses with these statuses, instead of 500, 502, 503 and 504.
+func WithFailoverStatuses(codes ...int) FailoverOption {
+	return func(c *failoverConfig) {
+		c.statuses = map[int]bool{}
+		for _, code := range codes {
+			c.statuses[code] = true
+		}
+	}
+}
+
+// WithPrimaryReprobe will try the first endpoint again this long after failing over from it, instead of after 30s.
+func WithPrimaryReprobe(interval time.Duration) FailoverOption {
+	return func(c *failoverConfig) {
+		c.reprobe = interval
+	}
+}
+
+//
+// NewFailoverClient wraps inner to send requests with relative URLs, such
+// as "/users/42", to the first of endpoints that works, in order. URLs are
+// resolved against an endpoint as WithBaseURL does; absolute URLs are sent
+// unchanged.
+//
+// A request that fails to connect, or gets a 500, 502, 503 or 504 status,
+// is sent to the next endpoint, and the one that answers is used for later
+// requests. The first endpoint is tried again every 30 seconds while
+// another is in use. The request context bounds the attempts together: no
+// endpoint is tried once it is done, and the last error is returned.
+//
+// As with WithRetry, a request that is not idempotent only fails over when
+// it could not be sent, and one with a WithBodyReader body, which is read
+// once, never does. Every endpoint gets the same Idempotency-Key.
+//
+// Empty or malformed endpoints make NewFailoverClient panic.
+//
+func NewFailoverClient(endpoints []string, inner Client, opts ...FailoverOption) Client {
+	if len(endpoints) == 0 {
+		panic("NewFailoverClient requires at least one endpoint")
+	}
+	fc := &failoverClient{Client: inner, now: time.Now}
+	for _, endpoint := range endpoints {
+		u, err := parseBaseURL(endpoint)
+		if err != nil {
+			panic("invalid failover endpoint: " + err.Error())
+		}
+		fc.endpoints = append(fc.endpoints, u)
+	}
+	fc.cfg = failoverConfig{
+		statuses: map[int]bool{500: true, 502: true, 503: true, 504: true},
+		reprobe:  30 * time.Second,
+	}
+	for _, o := range opts {
+		o(&fc.cfg)
+	}
+	return fc
+}
+
+type failoverClient struct {
+	Client
+	endpoints []*url.URL
+	cfg       failoverConfig
+	now       func() time.Time
+
+	mu sync.Mutex
+	// current is the index of the endpoint in use, and failedOver when it stopped being the first.
+	current    int
+	failedOver time.Time
+}
+
+func (fc *failoverClient) Get(ctx context.Context, url string, options ...RequestOption) error {
+	return fc.failover(ctx, http.MethodGet, url, options, func(url string, options []RequestOption) error {
+		return fc.Client.Get(ctx, url, options...)
+	})
+}
+
+func (fc *failoverClient) Post(ctx context.Context, url string, options ...RequestOption) error {
+	return fc.failover(ctx, http.MethodPost, url, options, func(url string, options []RequestOption) error {
+		return fc.Client.Post(ctx, url, options...)
+	})
+}
+
+func (fc *failoverClient) Do(ctx context.Context, method, url string, options ...RequestOption) error {
+	return fc.failover(ctx, method, url, options, func(url string, options []RequestOption) error {
+		return fc.Client.Do(ctx, method, url, options...)
+	})
+}
+
+// failover sends a request to rawURL with send, resolved against each endpoint in turn until one answers.
+func (fc *failoverClient) failover(ctx context.Context, method, rawURL string, options []RequestOption, send func(url string, options []RequestOption) error) error {
+	if !isRelativeURL(rawURL) {
+		return send(rawURL, options)
+	}
+
+	// sent is the request the inner client built from the options, which only run there.
+	var sent *Request
+	options = append(options[:len(options):len(options)], func(r *Request) { sent = r })
+
+	start := fc.start()
+	var err error
+	for i := 0; i < len(fc.endpoints); i++ {
+		endpoint := (start + i) % len(fc.endpoints)
+		if i > 0 && ctx.Err() != nil {
+			break
+		}
+		sent = nil
+		err = send(resolveURL(fc.endpoints[endpoint], rawURL), options)
+		req := sent
+		if req == nil {
+			// An inner Client not applying the options only tells the method.
+			req = &Request{Method: method, Header: http.Header{}}
+		}
+		if !fc.shouldFailOver(ctx, req, err) {
+			fc.use(endpoint)
+			return err
+		}
+		// Every endpoint is sent the same request, so an Idempotency-Key generated by an option is fixed here.
+		if key := req.Header.Get("Idempotency-Key"); i == 0 && key != "" {
+			options = append(options[:len(options):len(options)], WithIdempotencyKey(key))
+		}
+	}
+	return err
+}
+
+// start returns the endpoint to try first: the one in use, or the first one when it is due to be probed.
+func (fc *failoverClient) start() int {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	if fc.current != 0 && fc.now().Sub(fc.failedOver) >= fc.cfg.reprobe {
+		// Until the probe ends, other requests keep using the current endpoint.
+		fc.failedOver = fc.now()
+		return 0
+	}
+	return fc.current
+}
+
+// use makes endpoint the one in use, after it answered a request.
+func (fc *failoverClient) use(endpoint int) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	if endpoint != fc.current {
+		fc.current = endpoint
+		fc.failedOver = fc.now()
+	}
+}
+
+//
+// shouldFailOver reports whether req, failing with err, may work on another
+// endpoint without repeating an effect it had on this one.
+//
+// A streamed body cannot be sent again, and a request that is not
+// idempotent only fails over if it was never sent.
+//
+func (fc *failoverClient) shouldFailOver(ctx context.Context, req *Request, err error) bool {
+	if err == nil || ctx.Err() != nil || req.BodyReader != nil {
+		return false
+	}
+	safe := idempotent(req.Method, req.Header) || req.idempotentRetry
+	var bse *BadStatusError
+	if errors.As(err, &bse) {
+		return safe && fc.cfg.statuses[bse.Code]
+	}
+	var ue *url.Error
+	return errors.As(err, &ue) && (safe || notSent(err))
+}
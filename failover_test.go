@@ -0,0 +1,206 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newRegionServer returns a server answering its name, or failing with *status while it is not zero.
+func newRegionServer(t *testing.T, name string, status *int32) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if code := atomic.LoadInt32(status); code != 0 {
+			w.WriteHeader(int(code))
+			return
+		}
+		w.Write([]byte(name + " " + r.URL.RequestURI()))
+	}))
+}
+
+func TestNewFailoverClient(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	var primaryStatus, secondaryStatus int32
+	primary := newRegionServer(t, "primary", &primaryStatus)
+	defer primary.Close()
+	secondary := newRegionServer(t, "secondary", &secondaryStatus)
+	defer secondary.Close()
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+
+	clock := newFakeClock()
+	newFailoverClient := func(endpoints ...string) *failoverClient {
+		fc := NewFailoverClient(endpoints, NewClient(), WithPrimaryReprobe(time.Minute)).(*failoverClient)
+		fc.now = clock.Now
+		return fc
+	}
+	get := func(c Client, url string) (string, error) {
+		var got string
+		err := c.Get(ctx, url, WithStringResponse(&got))
+		return got, err
+	}
+
+	t.Run("primary down", func(t *testing.T) {
+		c := newFailoverClient(down.URL+"/v1", secondary.URL+"/v1")
+		for i := 0; i < 2; i++ {
+			if got, err := get(c, "/users?id=1"); err != nil || got != "secondary /v1/users?id=1" {
+				t.Errorf("Get() #%d = %q, %v, want the secondary", i+1, got, err)
+			}
+		}
+		if c.current != 1 {
+			t.Errorf("endpoint in use = %d, want the secondary", c.current)
+		}
+	})
+
+	t.Run("primary recovers", func(t *testing.T) {
+		atomic.StoreInt32(&primaryStatus, http.StatusServiceUnavailable)
+		defer atomic.StoreInt32(&primaryStatus, 0)
+		c := newFailoverClient(primary.URL, secondary.URL)
+		if got, err := get(c, "/a"); err != nil || got != "secondary /a" {
+			t.Errorf("Get() = %q, %v, want the secondary while the primary fails", got, err)
+		}
+
+		atomic.StoreInt32(&primaryStatus, 0)
+		if got, _ := get(c, "/b"); got != "secondary /b" {
+			t.Errorf("Get() = %q before the reprobe interval, want the secondary", got)
+		}
+		clock.Advance(time.Minute)
+		if got, _ := get(c, "/c"); got != "primary /c" {
+			t.Errorf("Get() = %q after the reprobe interval, want the recovered primary", got)
+		}
+		if got, _ := get(c, "/d"); got != "primary /d" {
+			t.Errorf("Get() = %q, want the primary to stay in use", got)
+		}
+	})
+
+	t.Run("both down", func(t *testing.T) {
+		atomic.StoreInt32(&secondaryStatus, http.StatusBadGateway)
+		defer atomic.StoreInt32(&secondaryStatus, 0)
+		c := newFailoverClient(down.URL, secondary.URL)
+		_, err := get(c, "/x")
+		var bse *BadStatusError
+		if !errors.As(err, &bse) || bse.Code != http.StatusBadGateway {
+			t.Errorf("Get() error = %v, want the last endpoint's 502", err)
+		}
+	})
+
+	t.Run("status not failed over", func(t *testing.T) {
+		atomic.StoreInt32(&primaryStatus, http.StatusNotFound)
+		defer atomic.StoreInt32(&primaryStatus, 0)
+		c := newFailoverClient(primary.URL, secondary.URL)
+		var bse *BadStatusError
+		if _, err := get(c, "/x"); !errors.As(err, &bse) || bse.Code != http.StatusNotFound {
+			t.Errorf("Get() error = %v, want the primary's 404", err)
+		}
+	})
+
+	t.Run("absolute URL", func(t *testing.T) {
+		c := newFailoverClient(down.URL, secondary.URL)
+		if got, err := get(c, primary.URL+"/abs"); err != nil || got != "primary /abs" {
+			t.Errorf("Get() = %q, %v, want the absolute URL used as is", got, err)
+		}
+	})
+}
+
+func TestFailoverClientContext(t *testing.T) {
+	t.Parallel()
+	var sent []string
+	c := NewFailoverClient([]string{"http://a.example", "http://b.example", "http://c.example"}, NewMockClient(func(ctx context.Context, req *Request) error {
+		sent = append(sent, req.URL)
+		return ctx.Err()
+	}))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := c.Get(ctx, "/x"); !errors.Is(err, context.Canceled) {
+		t.Errorf("Get() error = %v, want context.Canceled", err)
+	}
+	if len(sent) != 1 {
+		t.Errorf("sent %v, want no failover once the context is done", sent)
+	}
+}
+
+func TestFailoverClient_request_safety(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	type received struct{ body, key string }
+	newServer := func(status int, got *[]received) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := ioutil.ReadAll(r.Body)
+			*got = append(*got, received{string(body), r.Header.Get("Idempotency-Key")})
+			w.WriteHeader(status)
+		}))
+	}
+	var primaryGot, secondaryGot []received
+	primary := newServer(http.StatusServiceUnavailable, &primaryGot)
+	defer primary.Close()
+	secondary := newServer(http.StatusOK, &secondaryGot)
+	defer secondary.Close()
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+
+	for _, tt := range []struct {
+		name     string
+		primary  string
+		options  []RequestOption
+		failOver bool
+	}{
+		{name: "POST", primary: primary.URL, options: []RequestOption{WithJSONBody("order")}},
+		{name: "POST not sent", primary: down.URL, options: []RequestOption{WithJSONBody("order")}, failOver: true},
+		{name: "POST with key", primary: primary.URL, options: []RequestOption{WithJSONBody("order"), WithAutoIdempotencyKey()}, failOver: true},
+		{name: "streamed body", primary: primary.URL, options: []RequestOption{WithBodyReader(strings.NewReader(`"order"`), -1), WithIdempotentRetry()}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			primaryGot, secondaryGot = nil, nil
+			c := NewFailoverClient([]string{tt.primary, secondary.URL}, NewClient())
+			err := c.Post(ctx, "/orders", tt.options...)
+			if failedOver := len(secondaryGot) > 0; failedOver != tt.failOver {
+				t.Fatalf("Post() error = %v, failed over = %v, want %v", err, failedOver, tt.failOver)
+			}
+			if !tt.failOver {
+				var bse *BadStatusError
+				if !errors.As(err, &bse) || bse.Code != http.StatusServiceUnavailable {
+					t.Errorf("Post() error = %v, want the primary's 503", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("Post() error = %v", err)
+			}
+			if got := secondaryGot[0]; got.body != `"order"` || len(primaryGot) > 0 && got != primaryGot[0] {
+				t.Errorf("secondary got %+v, want the request the primary got, %+v", got, primaryGot)
+			}
+		})
+	}
+}
+
+func TestFailoverClient_options(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	var primaryStatus, secondaryStatus int32 = http.StatusServiceUnavailable, 0
+	primary := newRegionServer(t, "primary", &primaryStatus)
+	defer primary.Close()
+	secondary := newRegionServer(t, "secondary", &secondaryStatus)
+	defer secondary.Close()
+
+	// A custom option sets Params directly, and counts the requests it is applied to.
+	var applied int32
+	setParam := func(r *Request) {
+		atomic.AddInt32(&applied, 1)
+		r.Params.Set("q", "1")
+	}
+	var got string
+	c := NewFailoverClient([]string{primary.URL, secondary.URL}, NewClient())
+	if err := c.Get(ctx, "/search", setParam, WithStringResponse(&got)); err != nil || got != "secondary /search?q=1" {
+		t.Errorf("Get() = %q, %v, want %q", got, err, "secondary /search?q=1")
+	}
+	if n := atomic.LoadInt32(&applied); n != 2 {
+		t.Errorf("option applied %d times, want once per endpoint tried", n)
+	}
+}
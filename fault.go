@@ -0,0 +1,186 @@
+package http
+
+import (
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+//
+// FaultRule describes a fault injected by a FaultMap into matching requests.
+//
+// A rule waits Delay, then fails the request with Err or answers it with
+// Response. A rule with neither delays the real request.
+//
+type FaultRule struct {
+	// Method matches the request method; empty matches any method.
+	Method string
+	// URL matches the full request URL; nil matches any URL.
+	URL *regexp.Regexp
+	// Times is the number of requests the rule applies to; zero means every request.
+	Times int
+
+	Delay    time.Duration
+	Err      error
+	Response *FaultResponse
+}
+
+// FaultResponse is a synthetic response returned by a FaultRule.
+type FaultResponse struct {
+	StatusCode int
+	Header     http.Header
+	Body       string
+}
+
+//
+// FaultMap holds the fault rules of clients built with WithFaultMap.
+//
+// Faults are injected beneath all client logic, in place of the network, so
+// the full request and response path runs against them. Rules can be added
+// and removed while requests are running.
+//
+// A FaultMap must come from NewFaultMap: WithFaultMap panics on any other.
+//
+type FaultMap struct {
+	// forTest is set by NewFaultMap, which ties the map to a test.
+	forTest bool
+
+	mu    sync.Mutex
+	rules []*faultRule
+}
+
+type faultRule struct {
+	FaultRule
+	used int
+}
+
+//
+// NewFaultMap constructs an empty FaultMap for the test t.
+//
+// Requiring a test keeps fault injection out of production clients. Every
+// rule is removed when the test completes.
+//
+func NewFaultMap(t TB) *FaultMap {
+	t.Helper()
+	m := &FaultMap{forTest: true}
+	t.Cleanup(m.Clear)
+	return m
+}
+
+// Add adds a rule after the existing ones and returns a function that removes it.
+func (m *FaultMap) Add(rule FaultRule) (remove func()) {
+	r := &faultRule{FaultRule: rule}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.rules = append(m.rules, r)
+	return func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		for i, o := range m.rules {
+			if o == r {
+				m.rules = append(m.rules[:i:i], m.rules[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// Clear removes every rule.
+func (m *FaultMap) Clear() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.rules = nil
+}
+
+// match returns the first rule applying to req and counts it as used.
+func (m *FaultMap) match(req *http.Request) (FaultRule, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, r := range m.rules {
+		if r.Method != "" && !strings.EqualFold(r.Method, req.Method) {
+			continue
+		}
+		if r.URL != nil && !r.URL.MatchString(req.URL.String()) {
+			continue
+		}
+		if r.Times > 0 && r.used >= r.Times {
+			continue
+		}
+		r.used++
+		return r.FaultRule, true
+	}
+	return FaultRule{}, false
+}
+
+// WithFaultMap makes the client apply the rules of m to every request, in place of the network.
+func WithFaultMap(m *FaultMap) ClientOption {
+	return func(c *clientConfig) {
+		if m == nil || !m.forTest {
+			panic("WithFaultMap requires a FaultMap from NewFaultMap")
+		}
+		c.faults = m
+	}
+}
+
+// faultTransport applies a FaultMap before the real transport.
+type faultTransport struct {
+	next   http.RoundTripper
+	faults *FaultMap
+}
+
+func (t *faultTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	rule, ok := t.faults.match(req)
+	if !ok {
+		return t.next.RoundTrip(req)
+	}
+
+	if rule.Delay > 0 {
+		timer := time.NewTimer(rule.Delay)
+		select {
+		case <-timer.C:
+		case <-req.Context().Done():
+			timer.Stop()
+			closeBody(req)
+			return nil, req.Context().Err()
+		}
+	}
+	switch {
+	case rule.Err != nil:
+		// As a RoundTripper must, the body is closed even on errors.
+		closeBody(req)
+		return nil, rule.Err
+	case rule.Response != nil:
+		closeBody(req)
+		header := rule.Response.Header.Clone()
+		if header == nil {
+			header = http.Header{}
+		}
+		return &http.Response{
+			Status:        strconv.Itoa(rule.Response.StatusCode) + " " + http.StatusText(rule.Response.StatusCode),
+			StatusCode:    rule.Response.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        header,
+			Body:          ioutil.NopCloser(strings.NewReader(rule.Response.Body)),
+			ContentLength: int64(len(rule.Response.Body)),
+			Request:       req,
+		}, nil
+	}
+	return t.next.RoundTrip(req)
+}
+
+// closeBody closes the body of req, if any, in place of the transport sending it.
+func closeBody(req *http.Request) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+}
+
+func (t *faultTransport) CloseIdleConnections() {
+	closeIdleConnections(t.next)
+}
@@ -0,0 +1,139 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFaultMap_pipe_retries_transport_errors(t *testing.T) {
+	t.Parallel()
+	var puts int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			w.Write([]byte("payload"))
+		case "PUT":
+			atomic.AddInt32(&puts, 1)
+			if body, _ := ioutil.ReadAll(r.Body); string(body) != "payload" {
+				t.Errorf("PUT body = %q, want payload", body)
+			}
+		}
+	}))
+	defer srv.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	faults := NewFaultMap(t)
+	errReset := errors.New("connection reset by fault")
+	faults.Add(FaultRule{Method: "PUT", URL: regexp.MustCompile(`/dest$`), Times: 2, Err: errReset})
+
+	cli := NewClient(WithFaultMap(faults))
+	err := Pipe(ctx, cli, PipeSource{URL: srv.URL + "/src"}, PipeDest{URL: srv.URL + "/dest"}, WithPipeSpool(1<<10), WithPipeRetries(2))
+	if err != nil {
+		t.Fatalf("Pipe() error = %v", err)
+	}
+	if puts != 1 {
+		t.Errorf("server saw %d PUTs, want 1 after two injected failures", puts)
+	}
+}
+
+func TestFaultMap_response_and_remove(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`"real"`))
+	}))
+	defer srv.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	faults := NewFaultMap(t)
+	remove := faults.Add(FaultRule{
+		Delay: 10 * time.Millisecond,
+		Response: &FaultResponse{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"X-Fault": {"yes"}},
+			Body:       `"synthetic"`,
+		},
+	})
+	cli := NewClient(WithFaultMap(faults))
+
+	var got string
+	var header http.Header
+	start := time.Now()
+	if err := cli.Get(ctx, srv.URL, WithJSONResponse(&got), WithResponseHeaders(&header)); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if got != "synthetic" || header.Get("X-Fault") != "yes" {
+		t.Errorf("Get() = %q with headers %v, want the synthetic response", got, header)
+	}
+	if elapsed := time.Since(start); elapsed < 10*time.Millisecond {
+		t.Errorf("Get() took %v, want at least the 10ms delay", elapsed)
+	}
+
+	remove()
+	if err := cli.Get(ctx, srv.URL, WithJSONResponse(&got)); err != nil || got != "real" {
+		t.Errorf("Get() after remove = %q, %v, want real", got, err)
+	}
+}
+
+func TestFaultMap_status(t *testing.T) {
+	t.Parallel()
+	faults := NewFaultMap(t)
+	faults.Add(FaultRule{Response: &FaultResponse{StatusCode: http.StatusServiceUnavailable, Body: "down"}})
+
+	err := NewClient(WithFaultMap(faults)).Get(context.Background(), "http://faults.invalid/")
+	var bse *BadStatusError
+	if !errors.As(err, &bse) || bse.Code != http.StatusServiceUnavailable || string(bse.Body) != "down" {
+		t.Errorf("Get() error = %v, want 503 BadStatusError", err)
+	}
+}
+
+// closeRecorder is a request body recording whether it was closed.
+type closeRecorder struct {
+	io.Reader
+	closed int32
+}
+
+func (cr *closeRecorder) Close() error {
+	atomic.StoreInt32(&cr.closed, 1)
+	return nil
+}
+
+func TestFaultMap_closes_body(t *testing.T) {
+	t.Parallel()
+	faults := NewFaultMap(t)
+	errReset := errors.New("connection reset by fault")
+	faults.Add(FaultRule{Err: errReset})
+	transport := &faultTransport{next: failingTransport{t}, faults: faults}
+
+	body := &closeRecorder{Reader: strings.NewReader("payload")}
+	req, err := http.NewRequest(http.MethodPut, "http://faults.invalid/", body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := transport.RoundTrip(req); !errors.Is(err, errReset) {
+		t.Errorf("RoundTrip() error = %v, want %v", err, errReset)
+	}
+	if atomic.LoadInt32(&body.closed) == 0 {
+		t.Error("RoundTrip() left the request body open")
+	}
+}
+
+func TestWithFaultMap_requires_test(t *testing.T) {
+	t.Parallel()
+	defer func() {
+		if recover() == nil {
+			t.Error("NewClient(WithFaultMap(&FaultMap{})) did not panic")
+		}
+	}()
+	NewClient(WithFaultMap(&FaultMap{}))
+}
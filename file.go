@@ -0,0 +1,82 @@
+package http
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// FileOption controls how WithFileResponse writes the file.
+type FileOption func(*fileConfig)
+
+type fileConfig struct {
+	mode         os.FileMode
+	lastModified bool
+}
+
+// WithFileMode will create the file with this mode instead of 0644.
+func WithFileMode(mode os.FileMode) FileOption {
+	return func(c *fileConfig) {
+		c.mode = mode
+	}
+}
+
+// WithLastModifiedTime will set the modification time of the file from the Last-Modified response header.
+func WithLastModifiedTime() FileOption {
+	return func(c *fileConfig) {
+		c.lastModified = true
+	}
+}
+
+//
+// WithFileResponse will write the HTTP response body to the file at path.
+//
+// The body is written to a temporary file in the same directory, which is
+// renamed to path only once the whole body has been received, so path is
+// never left holding a partial download.
+//
+func WithFileResponse(path string, options ...FileOption) RequestOption {
+	cfg := fileConfig{mode: 0644}
+	for _, o := range options {
+		o(&cfg)
+	}
+	return func(r *Request) {
+		r.ResponseCallback = func(status int, header http.Header, body io.Reader) error {
+			return cfg.write(path, header, body)
+		}
+	}
+}
+
+func (cfg fileConfig) write(path string, header http.Header, body io.Reader) (err error) {
+	f, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			f.Close()
+			os.Remove(f.Name())
+		}
+	}()
+
+	if _, err = io.Copy(f, body); err != nil {
+		return err
+	}
+	if err = f.Chmod(cfg.mode); err != nil {
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	if cfg.lastModified {
+		if t, parseErr := http.ParseTime(header.Get("Last-Modified")); parseErr == nil {
+			if err = os.Chtimes(f.Name(), time.Now(), t); err != nil {
+				return err
+			}
+		}
+	}
+	return os.Rename(f.Name(), path)
+}
@@ -0,0 +1,72 @@
+package http
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestGet_file_response(t *testing.T) {
+	t.Parallel()
+	lastModified := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Last-Modified", lastModified.Format(http.TimeFormat))
+		w.Write([]byte("artifact"))
+	}))
+	defer srv.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	path := filepath.Join(t.TempDir(), "artifact.bin")
+	if err := NewClient().Get(ctx, srv.URL, WithFileResponse(path, WithFileMode(0600), WithLastModifiedTime())); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if buf, err := ioutil.ReadFile(path); err != nil || string(buf) != "artifact" {
+		t.Errorf("file = %q, %v, want artifact", buf, err)
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Stat() error = %v", err)
+	}
+	if fi.Mode().Perm() != 0600 {
+		t.Errorf("file mode = %v, want 0600", fi.Mode().Perm())
+	}
+	if !fi.ModTime().Equal(lastModified) {
+		t.Errorf("file mtime = %v, want %v", fi.ModTime(), lastModified)
+	}
+}
+
+func TestGet_file_response_truncated(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "1000")
+		w.Write([]byte("partial"))
+		w.(http.Flusher).Flush()
+		conn, _, _ := w.(http.Hijacker).Hijack()
+		conn.Close()
+	}))
+	defer srv.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "artifact.bin")
+	if err := ioutil.WriteFile(path, []byte("previous"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := NewClient().Get(ctx, srv.URL, WithFileResponse(path)); err == nil {
+		t.Fatalf("Get() error = nil, want error for truncated body")
+	}
+
+	if buf, _ := ioutil.ReadFile(path); string(buf) != "previous" {
+		t.Errorf("file = %q, want the previous contents untouched", buf)
+	}
+	if entries, _ := ioutil.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("directory has %d entries, want only the original file", len(entries))
+	}
+}
@@ -0,0 +1,52 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"reflect"
+)
+
+// ErrRequestMutated is returned when a Request is changed after it was dispatched.
+var ErrRequestMutated = errors.New("request changed after dispatch")
+
+//
+// requestView is a copy of what a Request sends, taken when it is
+// dispatched.
+//
+// Once its options are applied, a Request is frozen. Everything that sees it
+// afterwards, such as the mock handler and response hooks, must leave it as
+// it was sent, which is checked against the view when the request returns.
+// Retries apply the options to a fresh Request instead of reusing one.
+//
+type requestView struct {
+	Method  string
+	URL     string
+	Params  url.Values
+	Header  http.Header
+	Cookies []http.Cookie
+}
+
+// freeze returns the view of the request as it is dispatched.
+func (req *Request) freeze() requestView {
+	v := requestView{
+		Method: req.Method,
+		URL:    req.URL,
+		Header: req.Header.Clone(),
+	}
+	if req.Params != nil {
+		v.Params = url.Values(http.Header(req.Params).Clone())
+	}
+	for _, c := range req.Cookies {
+		v.Cookies = append(v.Cookies, *c)
+	}
+	return v
+}
+
+// checkFrozen returns ErrRequestMutated if the request no longer matches v.
+func (req *Request) checkFrozen(v requestView) error {
+	if !reflect.DeepEqual(req.freeze(), v) {
+		return ErrRequestMutated
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestMockClient_mutated_request(t *testing.T) {
+	t.Parallel()
+	mutations := map[string]func(r *Request){
+		"header": func(r *Request) { r.Header.Set("Authorization", "changed") },
+		"param":  func(r *Request) { r.Params.Set("page", "2") },
+		"url":    func(r *Request) { r.URL = "http://other.example.com" },
+		"cookie": func(r *Request) { r.Cookies[0].Value = "changed" },
+	}
+	for name, mutate := range mutations {
+		mutate := mutate
+		t.Run(name, func(t *testing.T) {
+			cli := NewMockClient(func(ctx context.Context, r *Request) error {
+				mutate(r)
+				return nil
+			})
+			err := cli.Get(context.Background(), "http://example.com",
+				WithHeader("Authorization", "token"),
+				WithParam("page", "1"),
+				WithCookie(&http.Cookie{Name: "session", Value: "abc"}))
+			if err != ErrRequestMutated {
+				t.Errorf("Get() error = %v, want %v", err, ErrRequestMutated)
+			}
+		})
+	}
+
+	cli := NewMockClient(func(ctx context.Context, r *Request) error {
+		return nil
+	})
+	if err := cli.Get(context.Background(), "http://example.com", WithHeader("Authorization", "token")); err != nil {
+		t.Errorf("Get() error = %v for a handler that only reads the request", err)
+	}
+}
+
+func TestGet_sent_header_is_a_copy(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	req, err := NewClient().(*client).newRequest("GET", srv.URL, []RequestOption{WithHeader("X-Trace", "1")})
+	if err != nil {
+		t.Fatalf("newRequest() error = %v", err)
+	}
+	r, err := req.prepareRequest(ctx)
+	if err != nil {
+		t.Fatalf("prepareRequest() error = %v", err)
+	}
+	view := req.freeze()
+
+	req.Header.Set("X-Trace", "2")
+	if got := r.Header.Get("X-Trace"); got != "1" {
+		t.Errorf("sent X-Trace = %q after the Request changed, want 1", got)
+	}
+	if err := req.checkFrozen(view); err != ErrRequestMutated {
+		t.Errorf("checkFrozen() error = %v, want %v", err, ErrRequestMutated)
+	}
+}
@@ -0,0 +1,250 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrGraphCycle is returned by RequestGraph.Validate when nodes depend on each other in a cycle.
+	ErrGraphCycle = errors.New("request graph has a cycle")
+	// ErrGraphDependencyFailed is the error of a node skipped because a dependency failed.
+	ErrGraphDependencyFailed = errors.New("request graph dependency failed")
+)
+
+// GraphPolicy controls what a RequestGraph does when a node fails.
+type GraphPolicy int
+
+const (
+	// GraphFailFast cancels every other node on the first failure.
+	GraphFailFast GraphPolicy = iota
+	// GraphContinueIndependent keeps running the nodes that do not depend on a failed node.
+	GraphContinueIndependent
+)
+
+// GraphResult is the outcome of one node of a RequestGraph.
+type GraphResult struct {
+	Value interface{}
+	Err   error
+}
+
+//
+// GraphNodeFunc runs one node of a RequestGraph.
+//
+// deps holds the results of the nodes it depends on, which have all
+// succeeded.
+//
+type GraphNodeFunc func(ctx context.Context, c Client, deps map[string]GraphResult) (interface{}, error)
+
+//
+// GraphRequest returns a GraphNodeFunc that sends a request and returns out,
+// which the options are expected to fill, such as with WithJSONResponse(out).
+//
+func GraphRequest(method, url string, out interface{}, options ...RequestOption) GraphNodeFunc {
+	return func(ctx context.Context, c Client, deps map[string]GraphResult) (interface{}, error) {
+		if err := c.Do(ctx, method, url, options...); err != nil {
+			return nil, err
+		}
+		return out, nil
+	}
+}
+
+// GraphNodeOption controls the behavior of a node of a RequestGraph.
+type GraphNodeOption func(*graphNode)
+
+// WithDependencies will run the node after the named nodes have succeeded.
+func WithDependencies(names ...string) GraphNodeOption {
+	return func(n *graphNode) {
+		n.deps = append(n.deps, names...)
+	}
+}
+
+// WithNodeTimeout will bound the run of the node to d.
+func WithNodeTimeout(d time.Duration) GraphNodeOption {
+	return func(n *graphNode) {
+		n.timeout = d
+	}
+}
+
+//
+// RequestGraph runs requests that depend on the results of other requests,
+// running independent nodes concurrently.
+//
+// Declare nodes with Node, then run them with Execute. A RequestGraph can be
+// executed more than once, but not concurrently.
+//
+type RequestGraph struct {
+	client Client
+	policy GraphPolicy
+	nodes  map[string]*graphNode
+	names  []string
+	err    error
+}
+
+type graphNode struct {
+	name    string
+	fn      GraphNodeFunc
+	deps    []string
+	timeout time.Duration
+
+	done   chan struct{}
+	result GraphResult
+}
+
+// NewRequestGraph constructs an empty RequestGraph sending its requests with c.
+func NewRequestGraph(c Client, policy GraphPolicy) *RequestGraph {
+	return &RequestGraph{
+		client: c,
+		policy: policy,
+		nodes:  map[string]*graphNode{},
+	}
+}
+
+// Node declares a node of the graph. Dependencies may name nodes declared later.
+func (g *RequestGraph) Node(name string, fn GraphNodeFunc, options ...GraphNodeOption) *RequestGraph {
+	if _, ok := g.nodes[name]; ok {
+		if g.err == nil {
+			g.err = fmt.Errorf("%w: duplicate graph node %q", ErrInvalidOption, name)
+		}
+		return g
+	}
+	n := &graphNode{name: name, fn: fn}
+	for _, o := range options {
+		o(n)
+	}
+	g.nodes[name] = n
+	g.names = append(g.names, name)
+	return g
+}
+
+// Validate reports duplicate nodes, unknown dependencies and cycles without running anything.
+func (g *RequestGraph) Validate() error {
+	if g.err != nil {
+		return g.err
+	}
+	for _, name := range g.names {
+		for _, dep := range g.nodes[name].deps {
+			if _, ok := g.nodes[dep]; !ok {
+				return fmt.Errorf("%w: graph node %q depends on unknown node %q", ErrInvalidOption, name, dep)
+			}
+		}
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := map[string]int{}
+	var path []string
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch state[name] {
+		case visiting:
+			for i, p := range path {
+				if p == name {
+					return fmt.Errorf("%w: %s", ErrGraphCycle, strings.Join(append(path[i:], name), " -> "))
+				}
+			}
+		case visited:
+			return nil
+		}
+		state[name] = visiting
+		path = append(path, name)
+		for _, dep := range g.nodes[name].deps {
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		state[name] = visited
+		return nil
+	}
+	names := append([]string(nil), g.names...)
+	sort.Strings(names)
+	for _, name := range names {
+		if err := visit(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//
+// Execute runs every node of the graph once its dependencies have succeeded,
+// and returns the result of each node.
+//
+// The error is that of the first node to fail. Nodes whose dependencies
+// failed are skipped with ErrGraphDependencyFailed, and under GraphFailFast
+// the remaining nodes are canceled.
+//
+func (g *RequestGraph) Execute(ctx context.Context) (map[string]GraphResult, error) {
+	if err := g.Validate(); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		firstErr     error
+		firstErrOnce sync.Once
+		wg           sync.WaitGroup
+	)
+	for _, n := range g.nodes {
+		n.done = make(chan struct{})
+		n.result = GraphResult{}
+	}
+	for _, n := range g.nodes {
+		wg.Add(1)
+		go func(n *graphNode) {
+			defer wg.Done()
+			defer close(n.done)
+			n.result = g.run(ctx, n)
+			if n.result.Err != nil {
+				firstErrOnce.Do(func() {
+					firstErr = fmt.Errorf("graph node %q: %w", n.name, n.result.Err)
+				})
+				if g.policy == GraphFailFast {
+					cancel()
+				}
+			}
+		}(n)
+	}
+	wg.Wait()
+
+	results := make(map[string]GraphResult, len(g.nodes))
+	for name, n := range g.nodes {
+		results[name] = n.result
+	}
+	return results, firstErr
+}
+
+// run waits for the dependencies of n and runs it.
+func (g *RequestGraph) run(ctx context.Context, n *graphNode) GraphResult {
+	deps := make(map[string]GraphResult, len(n.deps))
+	for _, name := range n.deps {
+		dep := g.nodes[name]
+		<-dep.done
+		if dep.result.Err != nil {
+			return GraphResult{Err: fmt.Errorf("%w: %s", ErrGraphDependencyFailed, name)}
+		}
+		deps[name] = dep.result
+	}
+	if err := ctx.Err(); err != nil {
+		return GraphResult{Err: err}
+	}
+
+	if n.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, n.timeout)
+		defer cancel()
+	}
+	v, err := n.fn(ctx, g.client, deps)
+	return GraphResult{Value: v, Err: err}
+}
@@ -0,0 +1,172 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// newGraphTestClient serves each URL from responses, blocking on "/slow" until canceled.
+func newGraphTestClient(responses map[string]string) Client {
+	return NewMockClient(func(ctx context.Context, r *Request) error {
+		if r.URL == "/slow" {
+			<-ctx.Done()
+			return ctx.Err()
+		}
+		body, ok := responses[r.URL]
+		if !ok {
+			return &BadStatusError{Code: 500}
+		}
+		*r.StringOutput = body
+		return nil
+	})
+}
+
+// newDiamondGraph builds user -> (org, perms) -> billing.
+func newDiamondGraph(c Client, policy GraphPolicy) *RequestGraph {
+	var user, org, perms string
+	return NewRequestGraph(c, policy).
+		Node("user", GraphRequest("GET", "/user", &user, WithStringResponse(&user))).
+		Node("org", func(ctx context.Context, c Client, deps map[string]GraphResult) (interface{}, error) {
+			err := c.Get(ctx, "/org?user="+*deps["user"].Value.(*string), WithStringResponse(&org))
+			return org, err
+		}, WithDependencies("user")).
+		Node("perms", GraphRequest("GET", "/perms", &perms, WithStringResponse(&perms)), WithDependencies("user")).
+		Node("billing", func(ctx context.Context, c Client, deps map[string]GraphResult) (interface{}, error) {
+			return deps["org"].Value.(string) + "/" + *deps["perms"].Value.(*string), nil
+		}, WithDependencies("org", "perms"))
+}
+
+func TestRequestGraph_diamond(t *testing.T) {
+	t.Parallel()
+	c := newGraphTestClient(map[string]string{
+		"/user":        "u1",
+		"/org?user=u1": "o1",
+		"/perms":       "admin",
+	})
+
+	results, err := newDiamondGraph(c, GraphFailFast).Execute(context.Background())
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if got := results["billing"].Value; got != "o1/admin" {
+		t.Errorf("billing = %v, want o1/admin", got)
+	}
+}
+
+func TestRequestGraph_failing_node(t *testing.T) {
+	t.Parallel()
+	policyTestCases := []struct {
+		name      string
+		policy    GraphPolicy
+		permsURL  string
+		wantPerms error
+	}{
+		{name: "fail fast", policy: GraphFailFast, permsURL: "/slow", wantPerms: context.Canceled},
+		{name: "continue independent", policy: GraphContinueIndependent, permsURL: "/perms"},
+	}
+	for _, tt := range policyTestCases {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			c := newGraphTestClient(map[string]string{"/user": "u1", "/perms": "admin"})
+			var user, perms string
+			g := NewRequestGraph(c, tt.policy).
+				Node("user", GraphRequest("GET", "/user", &user, WithStringResponse(&user))).
+				Node("org", GraphRequest("GET", "/missing", nil, WithStringResponse(new(string))), WithDependencies("user")).
+				Node("perms", GraphRequest("GET", tt.permsURL, &perms, WithStringResponse(&perms)), WithDependencies("user")).
+				Node("billing", GraphRequest("GET", "/billing", nil, WithStringResponse(new(string))), WithDependencies("org", "perms"))
+
+			ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+			defer cancel()
+			results, err := g.Execute(ctx)
+			var bse *BadStatusError
+			if !errors.As(err, &bse) {
+				t.Fatalf("Execute() error = %v, want the org *BadStatusError", err)
+			}
+			if !errors.Is(results["billing"].Err, ErrGraphDependencyFailed) {
+				t.Errorf("billing error = %v, want %v", results["billing"].Err, ErrGraphDependencyFailed)
+			}
+			if !errors.Is(results["perms"].Err, tt.wantPerms) || (tt.wantPerms == nil && results["perms"].Err != nil) {
+				t.Errorf("perms error = %v, want %v", results["perms"].Err, tt.wantPerms)
+			}
+			if results["user"].Err != nil {
+				t.Errorf("user error = %v, want nil", results["user"].Err)
+			}
+		})
+	}
+}
+
+func TestRequestGraph_canceled(t *testing.T) {
+	t.Parallel()
+	c := newGraphTestClient(map[string]string{"/user": "u1"})
+	ctx, cancel := context.WithCancel(context.Background())
+	var user string
+	g := NewRequestGraph(c, GraphContinueIndependent).
+		Node("user", GraphRequest("GET", "/user", &user, WithStringResponse(&user))).
+		Node("slow", func(ctx context.Context, c Client, deps map[string]GraphResult) (interface{}, error) {
+			cancel()
+			return nil, c.Get(ctx, "/slow", WithStringResponse(new(string)))
+		}, WithDependencies("user")).
+		Node("after", GraphRequest("GET", "/user", nil, WithStringResponse(new(string))), WithDependencies("slow"))
+
+	results, err := g.Execute(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Execute() error = %v, want %v", err, context.Canceled)
+	}
+	if results["user"].Err != nil {
+		t.Errorf("user error = %v, want nil", results["user"].Err)
+	}
+	if !errors.Is(results["after"].Err, ErrGraphDependencyFailed) {
+		t.Errorf("after error = %v, want %v", results["after"].Err, ErrGraphDependencyFailed)
+	}
+}
+
+func TestRequestGraph_node_timeout(t *testing.T) {
+	t.Parallel()
+	c := newGraphTestClient(nil)
+	g := NewRequestGraph(c, GraphFailFast).
+		Node("slow", GraphRequest("GET", "/slow", nil, WithStringResponse(new(string))), WithNodeTimeout(10*time.Millisecond))
+
+	if _, err := g.Execute(context.Background()); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Execute() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestRequestGraph_validate(t *testing.T) {
+	t.Parallel()
+	noop := func(ctx context.Context, c Client, deps map[string]GraphResult) (interface{}, error) {
+		return nil, nil
+	}
+	called := false
+	g := NewRequestGraph(newGraphTestClient(nil), GraphFailFast).
+		Node("root", func(ctx context.Context, c Client, deps map[string]GraphResult) (interface{}, error) {
+			called = true
+			return nil, nil
+		}).
+		Node("a", noop, WithDependencies("root", "c")).
+		Node("b", noop, WithDependencies("a")).
+		Node("c", noop, WithDependencies("b"))
+
+	err := g.Validate()
+	if !errors.Is(err, ErrGraphCycle) {
+		t.Fatalf("Validate() error = %v, want %v", err, ErrGraphCycle)
+	}
+	if want := "request graph has a cycle: a -> c -> b -> a"; err.Error() != want {
+		t.Errorf("Validate() error = %q, want %q", err, want)
+	}
+	if _, err := g.Execute(context.Background()); !errors.Is(err, ErrGraphCycle) || called {
+		t.Errorf("Execute() error = %v, called = %v, want %v before running any node", err, called, ErrGraphCycle)
+	}
+
+	g = NewRequestGraph(newGraphTestClient(nil), GraphFailFast).Node("a", noop, WithDependencies("missing"))
+	if err := g.Validate(); !errors.Is(err, ErrInvalidOption) {
+		t.Errorf("Validate() error = %v, want %v for unknown dependency", err, ErrInvalidOption)
+	}
+
+	g = NewRequestGraph(newGraphTestClient(nil), GraphFailFast).Node("a", noop).Node("a", noop)
+	if err := g.Validate(); !errors.Is(err, ErrInvalidOption) {
+		t.Errorf("Validate() error = %v, want %v for duplicate node", err, ErrInvalidOption)
+	}
+}
@@ -0,0 +1,41 @@
+package http
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+
+	"golang.org/x/net/http2"
+)
+
+//
+// WithH2C will send http:// requests with HTTP/2 over cleartext TCP (h2c)
+// with prior knowledge, as service meshes speak between services, instead of
+// HTTP/1.1. https:// requests fail.
+//
+// The client uses an http2.Transport, so NewClient panics given WithH2C
+// along with NewTLSClient, WithTLSConfig, WithTransport or the options
+// configuring an *http.Transport, such as WithMaxIdleConnsPerHost.
+//
+func WithH2C() ClientOption {
+	return func(c *clientConfig) {
+		c.h2c = true
+	}
+}
+
+// useH2C makes c send requests over h2c.
+func (c *client) useH2C() {
+	if c.config.tlsConfig != nil {
+		panic("WithH2C sends cleartext requests and cannot be used with NewTLSClient or WithTLSConfig")
+	}
+	if c.client.Transport != nil {
+		panic("WithH2C installs its own transport and cannot be used with another, such as from WithTransport")
+	}
+	var dialer net.Dialer
+	c.client.Transport = &http2.Transport{
+		AllowHTTP: true,
+		DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+			return dialer.DialContext(ctx, network, addr)
+		},
+	}
+}
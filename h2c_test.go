@@ -0,0 +1,56 @@
+package http
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+func TestWithH2C(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(h2c.NewHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Proto))
+	}), &http2.Server{}))
+	defer srv.Close()
+
+	var proto string
+	var resp *http.Response
+	if err := NewClient(WithH2C()).Get(context.Background(), srv.URL, WithRawResponse(&resp)); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	resp.Body.Close()
+	if resp.ProtoMajor != 2 {
+		t.Errorf("response ProtoMajor = %d, want 2", resp.ProtoMajor)
+	}
+	if err := NewClient(WithH2C()).Get(context.Background(), srv.URL, WithStringResponse(&proto)); err != nil || proto != "HTTP/2.0" {
+		t.Errorf("server saw %q, %v, want HTTP/2.0", proto, err)
+	}
+}
+
+func TestWithH2C_conflicts(t *testing.T) {
+	t.Parallel()
+	for _, tt := range []struct {
+		name       string
+		newClient  func()
+		wantErrStr string
+	}{
+		{name: "TLS", newClient: func() { NewTLSClient(&tls.Config{}, WithH2C()) }, wantErrStr: "cannot be used with NewTLSClient"},
+		{name: "transport", newClient: func() { NewClient(WithTransport(http.DefaultTransport), WithH2C()) }, wantErrStr: "cannot be used with another, such as from WithTransport"},
+		{name: "pool", newClient: func() { NewClient(WithH2C(), WithMaxIdleConnsPerHost(8)) }, wantErrStr: "WithMaxIdleConnsPerHost requires an *http.Transport"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if p, _ := recover().(string); !strings.Contains(p, tt.wantErrStr) {
+					t.Errorf("NewClient() panic = %q, want containing %q", p, tt.wantErrStr)
+				}
+			}()
+			tt.newClient()
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+type hedgePolicy struct {
+	delay     time.Duration
+	maxHedges int
+}
+
+//
+// WithHedging will send up to maxHedges more copies of a GET or HEAD
+// request, one each time delay passes without a response, and use the
+// first response to arrive. The other attempts are canceled, and their
+// responses discarded, so only the winner reaches the outputs.
+//
+// An attempt failing with a transport error starts the next copy at once.
+// The request fails only if every attempt does. Hedging spreads the load of
+// slow requests over more servers, so delay is best set near the latency
+// of the slowest requests worth hedging, such as the 95th percentile.
+//
+func WithHedging(delay time.Duration, maxHedges int) ClientOption {
+	return func(c *clientConfig) {
+		c.hedging = &hedgePolicy{delay: delay, maxHedges: maxHedges}
+	}
+}
+
+// WithRequestHedging will hedge this request as WithHedging does, instead of following the client setting; 0 maxHedges disables hedging.
+func WithRequestHedging(delay time.Duration, maxHedges int) RequestOption {
+	return func(r *Request) {
+		r.hedging = &hedgePolicy{delay: delay, maxHedges: maxHedges}
+	}
+}
+
+// hedgePolicy returns the hedging of req, or nil if it is not hedged.
+func (c *client) hedgePolicy(req *Request) *hedgePolicy {
+	p := c.config.hedging
+	if req.hedging != nil {
+		p = req.hedging
+	}
+	if p == nil || p.maxHedges < 1 || req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return nil
+	}
+	return p
+}
+
+func (p *hedgePolicy) describe() string {
+	return fmt.Sprintf("up to %d more attempts, %v apart", p.maxHedges, p.delay)
+}
+
+// attempt signs r and sends it once with hc, hedging it if req asks for it and its body can be sent again.
+func (c *client) attempt(hc *http.Client, req *Request, r *http.Request) (*http.Response, error) {
+	r, err := req.signed(r)
+	if err != nil {
+		return nil, err
+	}
+	if p := c.hedgePolicy(req); p != nil && canReplay(r) {
+		return p.do(hc, r, &c.tasks)
+	}
+	return hc.Do(r)
+}
+
+type hedgeResult struct {
+	resp *http.Response
+	err  error
+	// attempt is the index of the attempt in the cancel functions.
+	attempt int
+}
+
+// do sends r with hc, and copies of it with their own bodies after each delay, returning the first response.
+// The responses of the losing attempts are discarded in a task of tasks.
+func (p *hedgePolicy) do(hc *http.Client, r *http.Request, tasks *taskRegistry) (*http.Response, error) {
+	results := make(chan hedgeResult, p.maxHedges+1)
+	var cancels []context.CancelFunc
+	pending := 0
+	launch := func() {
+		ctx, cancel := context.WithCancel(r.Context())
+		attempt := len(cancels)
+		cancels = append(cancels, cancel)
+		pending++
+		go func() {
+			hedge := r.Clone(ctx)
+			var resp *http.Response
+			var err error
+			if attempt > 0 {
+				hedge, err = replayRequest(hedge)
+			}
+			if err == nil {
+				resp, err = hc.Do(hedge)
+			}
+			results <- hedgeResult{resp: resp, err: err, attempt: attempt}
+		}()
+	}
+
+	launch()
+	timer := time.NewTimer(p.delay)
+	defer timer.Stop()
+	var lastErr error
+	for pending > 0 {
+		select {
+		case <-timer.C:
+			if len(cancels) <= p.maxHedges {
+				launch()
+				timer.Reset(p.delay)
+			}
+		case res := <-results:
+			pending--
+			if res.err != nil {
+				cancels[res.attempt]()
+				lastErr = res.err
+				if len(cancels) <= p.maxHedges && r.Context().Err() == nil {
+					launch()
+					timer.Reset(p.delay)
+				}
+				continue
+			}
+			for i, cancel := range cancels {
+				if i != res.attempt {
+					cancel()
+				}
+			}
+			n := pending
+			if tasks.start("hedge-discard", func(context.Context) { discardHedges(results, n) }) != nil {
+				discardHedges(results, n)
+			}
+			// The winner keeps its context until its body is closed.
+			res.resp.Body = &cancelOnClose{ReadCloser: res.resp.Body, cancel: cancels[res.attempt]}
+			return res.resp, nil
+		}
+	}
+	return nil, lastErr
+}
+
+// discardHedges discards the responses of the n canceled attempts still pending.
+func discardHedges(results chan hedgeResult, n int) {
+	for ; n > 0; n-- {
+		if res := <-results; res.resp != nil {
+			discardResponse(res.resp)
+		}
+	}
+}
+
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (cc *cancelOnClose) Close() error {
+	err := cc.ReadCloser.Close()
+	cc.cancel()
+	return err
+}
@@ -0,0 +1,146 @@
+package http
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newSlowFirstServer returns a server whose first response stalls until its request is canceled, and the count of requests it received.
+func newSlowFirstServer(t *testing.T) (*httptest.Server, *int32) {
+	t.Helper()
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if atomic.AddInt32(&requests, 1) == 1 {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(10 * time.Second):
+			}
+			w.Write([]byte(`{"from": "slow"}`))
+			return
+		}
+		w.Write([]byte(`{"from": "fast"}`))
+	}))
+	return srv, &requests
+}
+
+func TestWithHedging(t *testing.T) {
+	// Not parallel, to count the goroutines left behind.
+	before := runtime.NumGoroutine()
+	srv, requests := newSlowFirstServer(t)
+	c := NewClient(WithHedging(20*time.Millisecond, 2), WithDisableKeepAlives())
+
+	var got struct{ From string }
+	start := time.Now()
+	if err := c.Get(context.Background(), srv.URL, WithJSONResponse(&got)); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Get() took %v, want the hedge to win", elapsed)
+	}
+	if got.From != "fast" {
+		t.Errorf("Get() decoded from %q, want the fast response", got.From)
+	}
+	if n := atomic.LoadInt32(requests); n != 2 {
+		t.Errorf("server got %d requests, want 2", n)
+	}
+
+	srv.Close()
+	deadline := time.Now().Add(5 * time.Second)
+	for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if after := runtime.NumGoroutine(); after > before {
+		t.Errorf("%d goroutines after hedging, want at most the %d before", after, before)
+	}
+}
+
+func TestWithRequestHedging(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	for _, tt := range []struct {
+		name         string
+		clientOpts   []ClientOption
+		method       string
+		options      []RequestOption
+		wantRequests int32
+	}{
+		{name: "request option", method: http.MethodGet, options: []RequestOption{WithRequestHedging(20*time.Millisecond, 1)}, wantRequests: 2},
+		{name: "disabled for request", clientOpts: []ClientOption{WithHedging(20*time.Millisecond, 1)}, method: http.MethodGet, options: []RequestOption{WithRequestHedging(0, 0)}, wantRequests: 1},
+		{name: "not for POST", clientOpts: []ClientOption{WithHedging(20*time.Millisecond, 1)}, method: http.MethodPost, wantRequests: 1},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, requests := newSlowFirstServer(t)
+			defer srv.Close()
+			ctx, cancel := context.WithTimeout(ctx, 500*time.Millisecond)
+			defer cancel()
+
+			NewClient(tt.clientOpts...).Do(ctx, tt.method, srv.URL, tt.options...)
+			if n := atomic.LoadInt32(requests); n != tt.wantRequests {
+				t.Errorf("server got %d requests, want %d", n, tt.wantRequests)
+			}
+		})
+	}
+}
+
+func TestWithHedgingTransportError(t *testing.T) {
+	t.Parallel()
+	var attempts int32
+	c := newClient(http.Client{Transport: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		if atomic.AddInt32(&attempts, 1) < 3 {
+			return nil, context.DeadlineExceeded
+		}
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: r}, nil
+	})}, []ClientOption{WithHedging(time.Hour, 2)})
+
+	// Each failure starts the next attempt at once, without waiting for the delay.
+	if err := c.Get(context.Background(), "http://example.com/"); err != nil {
+		t.Errorf("Get() error = %v, want the third attempt to succeed", err)
+	}
+	if n := atomic.LoadInt32(&attempts); n != 3 {
+		t.Errorf("transport got %d attempts, want 3", n)
+	}
+}
+
+func TestWithHedgingBody(t *testing.T) {
+	t.Parallel()
+	for _, tt := range []struct {
+		name         string
+		body         RequestOption
+		wantRequests int32
+	}{
+		{name: "replayable body", body: WithBodyReader(strings.NewReader("query"), 5), wantRequests: 2},
+		{name: "streamed body not hedged", body: WithBodyReader(io.MultiReader(strings.NewReader("query")), -1), wantRequests: 1},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests int32
+			c := newClient(http.Client{Transport: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+				n := atomic.AddInt32(&requests, 1)
+				body, err := ioutil.ReadAll(r.Body)
+				if err != nil || string(body) != "query" {
+					t.Errorf("attempt %d sent body %q, %v, want %q", n, body, err, "query")
+				}
+				if n == 1 && tt.wantRequests > 1 {
+					<-r.Context().Done()
+					return nil, r.Context().Err()
+				}
+				return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: r}, nil
+			})}, []ClientOption{WithHedging(time.Millisecond, 1)})
+
+			if err := c.Get(context.Background(), "http://example.com/", tt.body); err != nil {
+				t.Errorf("Get() error = %v", err)
+			}
+			if n := atomic.LoadInt32(&requests); n != tt.wantRequests {
+				t.Errorf("transport got %d attempts, want %d", n, tt.wantRequests)
+			}
+		})
+	}
+}
@@ -0,0 +1,290 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultHSTSEntries bounds the store created by WithHSTS.
+const defaultHSTSEntries = 1024
+
+//
+// WithHSTS makes the client remember Strict-Transport-Security (RFC 6797)
+// from https responses and upgrade later http:// requests to those hosts to
+// https before dialing.
+//
+// The client uses an in-memory store; use WithHSTSStore to share or persist it.
+//
+func WithHSTS() ClientOption {
+	return func(c *clientConfig) {
+		if c.hsts == nil {
+			c.hsts = NewHSTSStore(defaultHSTSEntries)
+		}
+	}
+}
+
+// WithHSTSStore enables HSTS like WithHSTS, using s to remember hosts.
+func WithHSTSStore(s *HSTSStore) ClientOption {
+	return func(c *clientConfig) {
+		c.hsts = s
+	}
+}
+
+//
+// WithHSTSUpgradeDisabled makes http:// requests to known HSTS hosts fail
+// with an *HSTSError instead of being upgraded to https.
+//
+// It has no effect unless HSTS is enabled with WithHSTS or WithHSTSStore.
+//
+func WithHSTSUpgradeDisabled() ClientOption {
+	return func(c *clientConfig) {
+		c.hstsNoUpgrade = true
+	}
+}
+
+// HSTSError is returned for a plaintext request to a known HSTS host when upgrades are disabled.
+type HSTSError struct {
+	Host string
+	URL  string
+}
+
+func (he *HSTSError) Error() string {
+	return fmt.Sprintf("refusing plaintext request to HSTS host %s: %s", he.Host, he.URL)
+}
+
+//
+// HSTSStore remembers the Strict-Transport-Security policy of hosts.
+//
+// It is safe for concurrent use and holds at most a fixed number of hosts,
+// evicting the policy closest to expiry when full.
+//
+type HSTSStore struct {
+	mu         sync.Mutex
+	maxEntries int
+	entries    map[string]hstsEntry
+	now        func() time.Time
+}
+
+type hstsEntry struct {
+	Expires           time.Time `json:"expires"`
+	IncludeSubDomains bool      `json:"include_subdomains,omitempty"`
+}
+
+// NewHSTSStore constructs an empty HSTSStore holding at most maxEntries hosts.
+func NewHSTSStore(maxEntries int) *HSTSStore {
+	return &HSTSStore{
+		maxEntries: maxEntries,
+		entries:    map[string]hstsEntry{},
+		now:        time.Now,
+	}
+}
+
+// Save writes the unexpired policies of the store to w as JSON.
+func (s *HSTSStore) Save(w io.Writer) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pruneLocked()
+	return json.NewEncoder(w).Encode(s.entries)
+}
+
+// Load adds the policies written by Save to the store, skipping expired ones.
+func (s *HSTSStore) Load(r io.Reader) error {
+	var entries map[string]hstsEntry
+	if err := json.NewDecoder(r).Decode(&entries); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for host, e := range entries {
+		s.putLocked(normalizeHSTSHost(host), e)
+	}
+	return nil
+}
+
+// record updates the policy of host from a Strict-Transport-Security header value.
+func (s *HSTSStore) record(host, header string) {
+	maxAge, includeSubDomains, ok := parseSTS(header)
+	if !ok {
+		return
+	}
+	host = normalizeHSTSHost(host)
+	if net.ParseIP(host) != nil {
+		// IP literals are never HSTS hosts (RFC 6797 section 8.1.1).
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if maxAge == 0 {
+		delete(s.entries, host)
+		return
+	}
+	s.putLocked(host, hstsEntry{
+		Expires:           s.now().Add(time.Duration(maxAge) * time.Second),
+		IncludeSubDomains: includeSubDomains,
+	})
+}
+
+// putLocked stores e for host, evicting the policy closest to expiry if the store is full.
+func (s *HSTSStore) putLocked(host string, e hstsEntry) {
+	if s.maxEntries <= 0 || !s.now().Before(e.Expires) {
+		return
+	}
+	if _, ok := s.entries[host]; !ok && len(s.entries) >= s.maxEntries {
+		s.pruneLocked()
+		if len(s.entries) >= s.maxEntries {
+			var soonest string
+			for h, o := range s.entries {
+				if soonest == "" || o.Expires.Before(s.entries[soonest].Expires) {
+					soonest = h
+				}
+			}
+			delete(s.entries, soonest)
+		}
+	}
+	s.entries[host] = e
+}
+
+func (s *HSTSStore) pruneLocked() {
+	now := s.now()
+	for h, e := range s.entries {
+		if !now.Before(e.Expires) {
+			delete(s.entries, h)
+		}
+	}
+}
+
+// match reports whether host is a known HSTS host, by congruent or superdomain match (RFC 6797 section 8.2).
+func (s *HSTSStore) match(host string) bool {
+	host = normalizeHSTSHost(host)
+	if net.ParseIP(host) != nil {
+		return false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	for candidate := host; candidate != ""; candidate = parentDomain(candidate) {
+		e, ok := s.entries[candidate]
+		switch {
+		case !ok:
+		case !now.Before(e.Expires):
+			delete(s.entries, candidate)
+		case candidate == host || e.IncludeSubDomains:
+			return true
+		}
+	}
+	return false
+}
+
+// parentDomain strips the first label from host, returning "" for a single label.
+func parentDomain(host string) string {
+	if i := strings.IndexByte(host, '.'); i >= 0 {
+		return host[i+1:]
+	}
+	return ""
+}
+
+func normalizeHSTSHost(host string) string {
+	return strings.TrimSuffix(strings.ToLower(host), ".")
+}
+
+//
+// parseSTS parses a Strict-Transport-Security header value (RFC 6797 section 6.1).
+//
+// Directive names are case-insensitive, values may be quoted, unknown
+// directives are ignored, and a header repeating a directive or missing
+// max-age is invalid.
+//
+func parseSTS(header string) (maxAge int64, includeSubDomains bool, ok bool) {
+	seen := map[string]bool{}
+	for _, directive := range strings.Split(header, ";") {
+		directive = strings.TrimSpace(directive)
+		if directive == "" {
+			continue
+		}
+		name, value := directive, ""
+		if i := strings.IndexByte(directive, '='); i >= 0 {
+			name, value = strings.TrimSpace(directive[:i]), strings.TrimSpace(directive[i+1:])
+		}
+		name = strings.ToLower(name)
+		if seen[name] {
+			return 0, false, false
+		}
+		seen[name] = true
+
+		switch name {
+		case "max-age":
+			if len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"' {
+				value = value[1 : len(value)-1]
+			}
+			n, err := strconv.ParseUint(value, 10, 63)
+			if err != nil {
+				return 0, false, false
+			}
+			maxAge = int64(n)
+		case "includesubdomains":
+			includeSubDomains = true
+		}
+	}
+	if !seen["max-age"] {
+		return 0, false, false
+	}
+	return maxAge, includeSubDomains, true
+}
+
+// hstsTransport applies an HSTSStore to every request, including redirects.
+type hstsTransport struct {
+	next      http.RoundTripper
+	store     *HSTSStore
+	noUpgrade bool
+}
+
+func (t *hstsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Scheme == "http" && t.store.match(req.URL.Hostname()) {
+		if t.noUpgrade {
+			return nil, &HSTSError{Host: req.URL.Hostname(), URL: req.URL.String()}
+		}
+		req = upgradeToHTTPS(req)
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	if sts := resp.Header.Get("Strict-Transport-Security"); sts != "" && req.URL.Scheme == "https" && resp.TLS != nil {
+		t.store.record(req.URL.Hostname(), sts)
+	}
+	return resp, nil
+}
+
+func (t *hstsTransport) CloseIdleConnections() {
+	closeIdleConnections(t.next)
+}
+
+// upgradeToHTTPS returns a copy of req using https, mapping an explicit port 80 to 443 (RFC 6797 section 8.3).
+func upgradeToHTTPS(req *http.Request) *http.Request {
+	upgraded := req.Clone(req.Context())
+	upgraded.URL.Scheme = "https"
+	if upgraded.URL.Port() == "80" {
+		upgraded.URL.Host = net.JoinHostPort(upgraded.URL.Hostname(), "443")
+	}
+	if req.Host == req.URL.Host {
+		upgraded.Host = upgraded.URL.Host
+	}
+	return upgraded
+}
+
+func closeIdleConnections(rt http.RoundTripper) {
+	if ci, ok := rt.(interface{ CloseIdleConnections() }); ok {
+		ci.CloseIdleConnections()
+	}
+}
@@ -0,0 +1,194 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (fc *fakeClock) Now() time.Time {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	return fc.now
+}
+
+func (fc *fakeClock) Advance(d time.Duration) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	fc.now = fc.now.Add(d)
+}
+
+// newHSTSTestClient returns a client that dials srv for every host name.
+func newHSTSTestClient(srv *httptest.Server, opts ...ClientOption) *client {
+	var dialer net.Dialer
+	return newClient(http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				return dialer.DialContext(ctx, network, srv.Listener.Addr().String())
+			},
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		},
+	}, opts)
+}
+
+func TestGet_hsts_upgrade(t *testing.T) {
+	t.Parallel()
+	var mu sync.Mutex
+	var schemes []string
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.TLS != nil {
+			schemes = append(schemes, "https")
+		} else {
+			schemes = append(schemes, "http")
+		}
+		w.Header().Set("Strict-Transport-Security", "max-age=60")
+	}))
+	defer srv.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	store := NewHSTSStore(10)
+	cli := newHSTSTestClient(srv, WithHSTSStore(store))
+	if err := cli.Get(ctx, "https://example.com/"); err != nil {
+		t.Fatalf("Get(https) error = %v", err)
+	}
+	if !store.match("example.com") {
+		t.Fatalf("example.com not learned from response")
+	}
+
+	// Without the upgrade this would be a plaintext request to the TLS server and fail.
+	if err := cli.Get(ctx, "http://example.com/"); err != nil {
+		t.Fatalf("Get(http) error = %v", err)
+	}
+	if len(schemes) != 2 || schemes[1] != "https" {
+		t.Errorf("server saw %v, want two https requests", schemes)
+	}
+
+	err := newHSTSTestClient(srv, WithHSTSStore(store), WithHSTSUpgradeDisabled()).Get(ctx, "http://example.com/")
+	var hstsErr *HSTSError
+	if !errors.As(err, &hstsErr) || hstsErr.Host != "example.com" {
+		t.Errorf("Get() error = %v, want *HSTSError for example.com", err)
+	}
+}
+
+func TestHSTSStore_subdomains(t *testing.T) {
+	t.Parallel()
+	store := NewHSTSStore(10)
+	store.record("example.com", "max-age=60; includeSubDomains")
+	store.record("other.test", "max-age=60")
+
+	subdomainTestCases := []struct {
+		host string
+		want bool
+	}{
+		{host: "example.com", want: true},
+		{host: "EXAMPLE.com.", want: true},
+		{host: "api.example.com", want: true},
+		{host: "a.b.example.com", want: true},
+		{host: "badexample.com", want: false},
+		{host: "other.test", want: true},
+		{host: "api.other.test", want: false},
+		{host: "127.0.0.1", want: false},
+	}
+	for _, tt := range subdomainTestCases {
+		if got := store.match(tt.host); got != tt.want {
+			t.Errorf("match(%s) = %v, want %v", tt.host, got, tt.want)
+		}
+	}
+}
+
+func TestHSTSStore_expiry(t *testing.T) {
+	t.Parallel()
+	clock := newFakeClock()
+	store := NewHSTSStore(10)
+	store.now = clock.Now
+
+	store.record("example.com", "max-age=60")
+	clock.Advance(59 * time.Second)
+	if !store.match("example.com") {
+		t.Errorf("match() = false before max-age elapsed")
+	}
+	clock.Advance(1 * time.Second)
+	if store.match("example.com") {
+		t.Errorf("match() = true after max-age elapsed")
+	}
+
+	store.record("example.com", "max-age=60")
+	store.record("example.com", "max-age=0")
+	if store.match("example.com") {
+		t.Errorf("match() = true after max-age=0")
+	}
+}
+
+func TestHSTSStore_bounded(t *testing.T) {
+	t.Parallel()
+	clock := newFakeClock()
+	store := NewHSTSStore(2)
+	store.now = clock.Now
+
+	store.record("a.test", "max-age=10")
+	store.record("b.test", "max-age=30")
+	store.record("c.test", "max-age=20")
+	if store.match("a.test") || !store.match("b.test") || !store.match("c.test") {
+		t.Errorf("store kept %v, want b.test and c.test", store.entries)
+	}
+}
+
+func TestHSTSStore_persist(t *testing.T) {
+	t.Parallel()
+	store := NewHSTSStore(10)
+	store.record("example.com", "max-age=60; includeSubDomains")
+
+	var buf bytes.Buffer
+	if err := store.Save(&buf); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	loaded := NewHSTSStore(10)
+	if err := loaded.Load(&buf); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if !loaded.match("api.example.com") {
+		t.Errorf("loaded store did not keep includeSubDomains policy")
+	}
+}
+
+func TestParseSTS(t *testing.T) {
+	t.Parallel()
+	stsTestCases := []struct {
+		header            string
+		maxAge            int64
+		includeSubDomains bool
+		ok                bool
+	}{
+		{header: "max-age=31536000", maxAge: 31536000, ok: true},
+		{header: `Max-Age="60"; includeSubDomains`, maxAge: 60, includeSubDomains: true, ok: true},
+		{header: "includeSubDomains; max-age=60; preload", maxAge: 60, includeSubDomains: true, ok: true},
+		{header: "includeSubDomains"},
+		{header: "max-age=60; max-age=70"},
+		{header: "max-age=-1"},
+	}
+	for _, tt := range stsTestCases {
+		maxAge, includeSubDomains, ok := parseSTS(tt.header)
+		if maxAge != tt.maxAge || includeSubDomains != tt.includeSubDomains || ok != tt.ok {
+			t.Errorf("parseSTS(%q) = %d, %v, %v, want %d, %v, %v", tt.header, maxAge, includeSubDomains, ok, tt.maxAge, tt.includeSubDomains, tt.ok)
+		}
+	}
+}
@@ -4,11 +4,16 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"encoding/xml"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"net/url"
+	"strings"
+	"time"
 
 	"crypto/tls"
 )
@@ -20,13 +25,65 @@ import (
 // with NewMockClient.
 //
 type Request struct {
-	Method     string
-	URL        string
-	Params     url.Values
-	Body       interface{}
-	JSONOutput interface{}
-	Output     io.Writer
-	Header     http.Header
+	Method          string
+	URL             string
+	Params          url.Values
+	Body            interface{}
+	BodyReader      io.Reader
+	JSONOutput      interface{}
+	XMLOutput       interface{}
+	StringOutput    *string
+	BytesOutput     *[]byte
+	Output          io.Writer
+	Header          http.Header
+	StatusCode      *int
+	ResponseHeader  *http.Header
+	Cookies         []*http.Cookie
+	ResponseCookies *[]*http.Cookie
+	ResponseTrailer *http.Header
+	// RawResponse receives the response of WithRawResponse; mock handlers may fabricate one.
+	RawResponse **http.Response
+	// ResponseCallback is called with the body of a successful response.
+	ResponseCallback func(status int, header http.Header, body io.Reader) error
+	// DecodedOutput is the target of WithDecodedResponse, such as a proto.Message.
+	DecodedOutput interface{}
+
+	envelope      *EnvelopeConfig
+	jsonStrict    bool
+	jsonNumbers   bool
+	sentBody      *BodyCapture
+	sentBodyLimit int64
+	bodyLength    int64
+	onResponse    func(*http.Response)
+
+	maxResponseBytes     int64
+	xssiPrefixes         []string
+	discardBody          bool
+	downloadProgress     func(bytesReceived, total int64)
+	checksumAlgo         string
+	checksumHex          string
+	allowedStatuses      []int
+	expectedStatuses     []int
+	lastKnown            time.Time
+	jsonError            interface{}
+	strippedPrefix       string
+	decodedFormat        string
+	expectedContentTypes []string
+	rawEncoding          bool
+	acceptEncodings      []string
+	teeWriters           []io.Writer
+	errorBody            io.Writer
+	successCheck         func(status int, header http.Header) bool
+	signers              []RequestSigner
+	digestAuth           *digestCredentials
+	apiKey               *apiKey
+	tokenAuthorized      bool
+	defaultHeaders       map[string]bool
+	noRedirects          bool
+	retries              *retryOverride
+	idempotentRetry      bool
+	hedging              *hedgePolicy
+	decode               func([]byte, interface{}) error
 }
 
 // RequestOption controls the behavior of the HTTP request.
@@ -40,6 +97,179 @@ func WithJSONResponse(o interface{}) RequestOption {
 	}
 }
 
+//
+// WithStrictJSONResponse will JSON Unmarshal the HTTP response body into this
+// object like WithJSONResponse, but fails if the body has fields the object
+// does not.
+//
+func WithStrictJSONResponse(o interface{}) RequestOption {
+	return func(r *Request) {
+		WithJSONResponse(o)(r)
+		r.jsonStrict = true
+	}
+}
+
+//
+// WithJSONNumbers will decode JSON numbers in the response as json.Number
+// rather than float64, so that large integers keep their precision.
+//
+// It applies to the WithJSONResponse and WithStrictJSONResponse targets.
+//
+func WithJSONNumbers() RequestOption {
+	return func(r *Request) {
+		r.jsonNumbers = true
+	}
+}
+
+// WithXMLResponse will XML Unmarshal the HTTP response body into this object.
+func WithXMLResponse(o interface{}) RequestOption {
+	return func(r *Request) {
+		r.XMLOutput = o
+		r.Header.Add("Accept", "application/xml")
+	}
+}
+
+//
+// WithDecodedResponse will decode the HTTP response body into o with decode,
+// sending contentType as the Accept header.
+//
+// It lets packages add formats without this package depending on their
+// codecs; format names the format in a *DecodeError.
+//
+func WithDecodedResponse(format, contentType string, o interface{}, decode func([]byte, interface{}) error) RequestOption {
+	return func(r *Request) {
+		r.DecodedOutput = o
+		r.decodedFormat = format
+		r.decode = decode
+		r.Header.Add("Accept", contentType)
+	}
+}
+
+// WithStringResponse will read the HTTP response body into this string.
+func WithStringResponse(s *string) RequestOption {
+	return func(r *Request) {
+		r.StringOutput = s
+	}
+}
+
+// WithBytesResponse will read the HTTP response body into this slice.
+func WithBytesResponse(b *[]byte) RequestOption {
+	return func(r *Request) {
+		r.BytesOutput = b
+	}
+}
+
+//
+// WithMaxResponseBytes will fail the request with a *ResponseTooLargeError if
+// the response body is larger than n bytes.
+//
+// The body of an unsuccessful response is truncated to n bytes in the
+// BadStatusError instead.
+//
+func WithMaxResponseBytes(n int64) RequestOption {
+	return func(r *Request) {
+		r.maxResponseBytes = n
+	}
+}
+
+//
+// WithDiscardBody will read and discard the HTTP response body, so the
+// connection can be reused when only the status matters.
+//
+// Bodies longer than maxDiscardBytes are not read to the end, and their
+// connection is closed instead.
+//
+func WithDiscardBody() RequestOption {
+	return func(r *Request) {
+		r.discardBody = true
+	}
+}
+
+//
+// WithResponseCallback will call fn with a successful HTTP response, so that
+// large bodies can be streamed while knowing the response headers.
+//
+// The body is closed once fn returns, and an error from fn is returned by the
+// request.
+//
+func WithResponseCallback(fn func(status int, header http.Header, body io.Reader) error) RequestOption {
+	return func(r *Request) {
+		r.ResponseCallback = fn
+	}
+}
+
+//
+// WithAllowedStatuses will treat responses with these status codes like
+// successful ones instead of returning a BadStatusError.
+//
+// Raw outputs such as WithResponse and WithStringResponse receive the body
+// of an allowed error status. WithJSONResponse and WithXMLResponse decode it
+// only if it is valid; an empty or malformed body, or an unsuccessful
+// envelope, leaves the target unchanged without an error. Use WithStatusCode
+// to tell the outcomes apart.
+//
+func WithAllowedStatuses(codes ...int) RequestOption {
+	return func(r *Request) {
+		r.allowedStatuses = append(r.allowedStatuses, codes...)
+	}
+}
+
+//
+// WithSuccessCheck will replace DefaultSuccessCheck, which accepts any 2xx
+// status, as the test of a successful response.
+//
+// A response fn rejects is returned as a *BadStatusError, like any other
+// unsuccessful response, unless WithAllowedStatuses accepts it.
+// WithExpectStatus takes precedence over fn. With NewMockClient, fn is given
+// a nil header.
+//
+func WithSuccessCheck(fn func(status int, header http.Header) bool) RequestOption {
+	return func(r *Request) {
+		r.successCheck = fn
+	}
+}
+
+//
+// WithExpectStatus will fail the request with a *BadStatusError, holding the
+// body, unless the response status is code. It can be given more than once
+// to expect any of several statuses.
+//
+// The expected statuses replace the default of any 2xx status, and the body
+// is only passed to the outputs when the status matches.
+//
+func WithExpectStatus(code int) RequestOption {
+	return func(r *Request) {
+		r.expectedStatuses = append(r.expectedStatuses, code)
+	}
+}
+
+//
+// WithJSONError will JSON Unmarshal the body of a response returned as a
+// *BadStatusError into this object.
+//
+// The BadStatusError is returned either way, and its ErrorDecoded field
+// reports whether the body could be decoded, which an HTML error page or an
+// empty body cannot.
+//
+func WithJSONError(o interface{}) RequestOption {
+	return func(r *Request) {
+		r.jsonError = o
+	}
+}
+
+//
+// WithErrorBody will stream the body of a response returned as a
+// *BadStatusError into w, instead of holding all of it in memory.
+//
+// The BadStatusError Body then holds only its first 512 bytes, which are
+// also all that WithJSONError can decode.
+//
+func WithErrorBody(w io.Writer) RequestOption {
+	return func(r *Request) {
+		r.errorBody = w
+	}
+}
+
 // WithResponse will write the HTTP response to this writer.
 func WithResponse(w io.Writer) RequestOption {
 	return func(r *Request) {
@@ -47,9 +277,69 @@ func WithResponse(w io.Writer) RequestOption {
 	}
 }
 
+//
+// WithRawResponse will store the *http.Response in out instead of handling
+// it, for cases such as protocol upgrades and manual streaming.
+//
+// The caller owns the response and must close its Body. Its status is not
+// checked, so no BadStatusError is returned, and it cannot be combined with
+// outputs such as WithJSONResponse or WithResponse.
+//
+func WithRawResponse(out **http.Response) RequestOption {
+	return func(r *Request) {
+		r.RawResponse = out
+	}
+}
+
+// WithStatusCode will store the HTTP response status code, whether or not it was successful.
+func WithStatusCode(code *int) RequestOption {
+	return func(r *Request) {
+		r.StatusCode = code
+	}
+}
+
+// WithResponseHeaders will store a copy of the HTTP response headers, whether or not it was successful.
+func WithResponseHeaders(h *http.Header) RequestOption {
+	return func(r *Request) {
+		r.ResponseHeader = h
+	}
+}
+
+// WithResponseCookies will store the cookies set by the HTTP response, whether or not it was successful.
+func WithResponseCookies(out *[]*http.Cookie) RequestOption {
+	return func(r *Request) {
+		r.ResponseCookies = out
+	}
+}
+
+//
+// WithResponseTrailers will store a copy of the HTTP response trailers,
+// whether or not it was successful.
+//
+// Trailers follow the body, so they are stored only once the output has
+// returned and the rest of the body, up to 1MiB, has been read. A
+// WithResponseCallback or WithResponse output cannot see them yet; read h
+// after the request returns.
+//
+func WithResponseTrailers(h *http.Header) RequestOption {
+	return func(r *Request) {
+		r.ResponseTrailer = h
+	}
+}
+
+// WithCookie will send the cookie with the HTTP request.
+func WithCookie(c *http.Cookie) RequestOption {
+	return func(r *Request) {
+		r.Cookies = append(r.Cookies, c)
+	}
+}
+
 // WithParam will set the query parameter on the HTTP request url.
 func WithParam(k, v string) RequestOption {
 	return func(r *Request) {
+		if r.Params == nil {
+			r.Params = url.Values{}
+		}
 		r.Params.Add(k, v)
 	}
 }
@@ -65,9 +355,51 @@ func WithJSONBody(b interface{}) RequestOption {
 	}
 }
 
-// WithHeader will set the HTTP Header on the request.
+// BodyCapture holds a copy of the request body captured by WithSentBodyCapture.
+type BodyCapture struct {
+	// Bytes is the encoded body as sent, up to the capture limit.
+	Bytes []byte
+	// Size is the full length of the encoded body.
+	Size int64
+	// Truncated reports whether Bytes holds less than Size bytes.
+	Truncated bool
+}
+
+// WithSentBodyCapture will copy up to maxSize bytes of the encoded request body into c.
+// It takes a *BodyCapture rather than a plain *[]byte so callers can also tell the full
+// size of the body and whether the copy was cut short; c.Bytes holds what a *[]byte
+// would. A negative maxSize fails the request with ErrInvalidOption.
+func WithSentBodyCapture(c *BodyCapture, maxSize int64) RequestOption {
+	return func(r *Request) {
+		r.sentBody = c
+		r.sentBodyLimit = maxSize
+	}
+}
+
+//
+// WithBodyReader will stream r as the HTTP request body.
+//
+// length is sent as the Content-Length, or -1 if it is unknown. The body is
+// read once, so it cannot be captured with WithSentBodyCapture.
+//
+func WithBodyReader(r io.Reader, length int64) RequestOption {
+	return func(req *Request) {
+		req.BodyReader = r
+		req.bodyLength = length
+	}
+}
+
+//
+// WithHeader will set the HTTP Header on the request. It can be given more
+// than once to send several values, the first of which replaces the client
+// WithDefaultHeader values.
+//
 func WithHeader(k, v string) RequestOption {
 	return func(r *Request) {
+		if key := http.CanonicalHeaderKey(k); r.defaultHeaders[key] {
+			r.Header.Del(key)
+			delete(r.defaultHeaders, key)
+		}
 		r.Header.Add(k, v)
 	}
 }
@@ -85,51 +417,356 @@ func WithHeader(k, v string) RequestOption {
 type Client interface {
 	Get(ctx context.Context, url string, options ...RequestOption) error
 	Post(ctx context.Context, url string, options ...RequestOption) error
+	// Do makes a request with any HTTP method.
+	Do(ctx context.Context, method, url string, options ...RequestOption) error
+
+	// Close stops the background work of the client, waiting for it to
+	// finish until ctx is done. That is the watch of a CertificateSource,
+	// the shared requests of WithSingleflight and the losing attempts of
+	// WithHedging; lookups of a DNSCache belong to the cache, which may
+	// outlive the client, and rate limiters and breakers start no goroutines.
+	Close(ctx context.Context) error
+	// BackgroundTasks lists the live background goroutines of the client.
+	BackgroundTasks() []BackgroundTask
+
+	// DryRun applies and validates options like a request would, and
+	// describes what would be sent without sending it.
+	DryRun(ctx context.Context, method, url string, options ...RequestOption) (*PreparedRequest, error)
+	// Explain describes the layers a request would pass through, without sending it.
+	Explain(ctx context.Context, method, url string, options ...RequestOption) (*Explanation, error)
 }
 
 type client struct {
 	client http.Client
+	config clientConfig
+	tasks  taskRegistry
+	reauth reauthGroup
+	// clock times retries and cache freshness; tests replace it with a fake.
+	clock clock
+}
+
+// ClientOption controls the behavior of every request made by a Client.
+type ClientOption func(*clientConfig)
+
+//
+// WithTimeout limits the time each request may take, from connecting through
+// reading the response body. The error of a request running out of time is
+// a net.Error whose Timeout method reports true.
+//
+// A request context with an earlier deadline still ends the request first.
+//
+func WithTimeout(d time.Duration) ClientOption {
+	return func(c *clientConfig) {
+		c.timeout = d
+	}
+}
+
+//
+// WithHTTPClient will send requests with a copy of hc, for control over its
+// CheckRedirect, Jar and Timeout. The other options apply on top of it, e.g.
+// WithTimeout replaces its Timeout.
+//
+// Set the Transport of hc rather than using WithTransport as well, which
+// makes NewClient panic.
+//
+func WithHTTPClient(hc *http.Client) ClientOption {
+	return func(c *clientConfig) {
+		c.httpClient = hc
+	}
+}
+
+//
+// WithTransport will send requests with rt instead of http.DefaultTransport.
+//
+// Options configuring the transport, such as WithTLSConfig or WithProxy,
+// require rt to be an *http.Transport, and configure a copy of it.
+//
+func WithTransport(rt http.RoundTripper) ClientOption {
+	return func(c *clientConfig) {
+		c.transport = rt
+	}
+}
+
+// WithTLSConfig will make TLS connections with config.
+func WithTLSConfig(config *tls.Config) ClientOption {
+	return func(c *clientConfig) {
+		c.tlsConfig = config
+	}
+}
+
+//
+// WithDefaultHeader will add this header to every request, before the
+// request options are applied. It can be given more than once, and
+// WithHeader on a request replaces the values of its header.
+//
+func WithDefaultHeader(k, v string) ClientOption {
+	return func(c *clientConfig) {
+		if c.header == nil {
+			c.header = http.Header{}
+		}
+		c.header.Add(k, v)
+	}
+}
+
+// setDefaultHeaders copies the WithDefaultHeader headers into the request.
+func (req *Request) setDefaultHeaders(h http.Header) {
+	if len(h) == 0 {
+		return
+	}
+	req.defaultHeaders = make(map[string]bool, len(h))
+	for k, vs := range h {
+		req.Header[k] = append([]string(nil), vs...)
+		req.defaultHeaders[k] = true
+	}
+}
+
+type clientConfig struct {
+	httpClient           *http.Client
+	timeout              time.Duration
+	transport            http.RoundTripper
+	tlsConfig            *tls.Config
+	header               http.Header
+	envelope             *EnvelopeConfig
+	hsts                 *HSTSStore
+	hstsNoUpgrade        bool
+	certSource           CertificateSource
+	faults               *FaultMap
+	authorization        string
+	tokenSource          func(ctx context.Context) (string, error)
+	apiKey               *apiKey
+	reauth               func(ctx context.Context) error
+	tlsServerName        string
+	proxy                *url.URL
+	proxyAuth            *url.Userinfo
+	proxyFromEnvironment bool
+	baseURL              *url.URL
+	endpoints            *endpointSet
+	endpointHealth       func(endpoint string) bool
+	userAgent            string
+	cookieJar            http.CookieJar
+	transportOptions     []transportOption
+	h2c                  bool
+	dialContext          func(ctx context.Context, network, addr string) (net.Conn, error)
+	hostOverrides        map[string]string
+	dnsCache             *DNSCache
+	localAddr            *net.TCPAddr
+	redirects            *redirectPolicy
+	insecureTLS          bool
+	rootCAs              []rootCAs
+	noSystemRootCAs      bool
+	retry                *RetryPolicy
+	hedging              *hedgePolicy
+	singleflight         *flightGroup
+	cache                Cache
+	logf                 func(format string, args ...interface{})
 }
 
-// NewTLSClient constructs a Client from the given tls.Config.
-func NewTLSClient(config *tls.Config) Client {
-	return &client{
-		http.Client{
-			Transport: &http.Transport{
-				TLSClientConfig: config,
-			},
-		},
+func newClient(hc http.Client, opts []ClientOption) *client {
+	c := &client{client: hc}
+	for _, o := range opts {
+		o(&c.config)
 	}
+	if c.config.httpClient != nil {
+		if c.config.transport != nil {
+			panic("WithHTTPClient and WithTransport conflict: set the Transport of the http.Client instead")
+		}
+		c.client = *c.config.httpClient
+	}
+	if c.config.endpoints != nil && c.config.baseURL != nil {
+		panic("WithEndpoints and WithBaseURL conflict: give the base URL path to each endpoint instead")
+	}
+	if c.config.certSource != nil && (c.config.proxy != nil || c.config.proxyFromEnvironment) {
+		panic("WithCertificateSource and WithProxy conflict: the transport does not use the certificate source through a proxy")
+	}
+	if c.config.timeout != 0 {
+		c.client.Timeout = c.config.timeout
+	}
+	if c.config.cookieJar != nil {
+		c.client.Jar = c.config.cookieJar
+	}
+	if c.config.redirects != nil {
+		c.client.CheckRedirect = c.config.redirects.checkRedirect
+	}
+	if c.config.transport != nil {
+		c.client.Transport = c.config.transport
+	}
+	if c.config.h2c {
+		c.useH2C()
+	}
+	if c.config.tlsConfig != nil {
+		c.useTLSConfig(c.config.tlsConfig)
+	}
+	if c.config.rootCAs != nil || c.config.noSystemRootCAs {
+		c.useRootCAs()
+	}
+	if len(c.config.transportOptions) > 0 {
+		c.useTransportOptions(c.config.transportOptions)
+	}
+	if c.config.dialContext != nil || c.config.hostOverrides != nil || c.config.dnsCache != nil || c.config.localAddr != nil {
+		c.useDialer()
+	}
+	if c.config.tlsServerName != "" {
+		c.useServerName(c.config.tlsServerName)
+	}
+	if c.config.proxy != nil || c.config.proxyAuth != nil || c.config.proxyFromEnvironment {
+		c.useProxy()
+	}
+	if c.config.certSource != nil {
+		c.useCertificateSource(c.config.certSource)
+	}
+	if c.config.insecureTLS {
+		c.logf("gohttp: WARNING: TLS certificate verification is disabled by WithInsecureTLS; never use it in production")
+	}
+	if c.config.faults != nil {
+		c.client.Transport = &faultTransport{
+			next:   transportOrDefault(c.client.Transport),
+			faults: c.config.faults,
+		}
+	}
+	if c.config.hsts != nil {
+		c.client.Transport = &hstsTransport{
+			next:      transportOrDefault(c.client.Transport),
+			store:     c.config.hsts,
+			noUpgrade: c.config.hstsNoUpgrade,
+		}
+	}
+	return c
+}
+
+func transportOrDefault(rt http.RoundTripper) http.RoundTripper {
+	if rt == nil {
+		return http.DefaultTransport
+	}
+	return rt
+}
+
+// useTLSConfig configures the transport of c to make TLS connections with config.
+func (c *client) useTLSConfig(config *tls.Config) {
+	if c.client.Transport == nil {
+		c.client.Transport = &http.Transport{TLSClientConfig: config}
+		return
+	}
+	t := c.cloneTransport("WithTLSConfig")
+	t.TLSClientConfig = config
+	c.client.Transport = t
+}
+
+// configureTLS returns an option making newClient apply configure to a copy of the TLS config of its transport.
+func configureTLS(name string, configure func(*tls.Config)) ClientOption {
+	return configureTransport(name, func(t *http.Transport) {
+		config := &tls.Config{}
+		if t.TLSClientConfig != nil {
+			config = t.TLSClientConfig.Clone()
+		}
+		configure(config)
+		t.TLSClientConfig = config
+	})
+}
+
+// cloneTransport returns a copy of the transport of c for option to configure.
+func (c *client) cloneTransport(option string) *http.Transport {
+	t, ok := transportOrDefault(c.client.Transport).(*http.Transport)
+	if !ok {
+		panic(fmt.Sprintf("%s requires an *http.Transport, not %T", option, c.client.Transport))
+	}
+	return t.Clone()
+}
+
+//
+// NewTLSClient constructs a Client from the given tls.Config, like NewClient
+// with WithTLSConfig.
+//
+// Given WithTransport as well, the config is set on a copy of that
+// transport, which must then be an *http.Transport. For an instrumented
+// wrapper, configure TLS on the transport it wraps and pass the wrapper to
+// NewClient instead.
+//
+func NewTLSClient(config *tls.Config, opts ...ClientOption) Client {
+	return newClient(http.Client{}, append([]ClientOption{WithTLSConfig(config)}, opts...))
 }
 
 // NewClient constructs a Client.
-func NewClient() Client {
-	return &client{}
+func NewClient(opts ...ClientOption) Client {
+	return newClient(http.Client{}, opts)
 }
 
 //
 // NewMockClient constructs a Client that calls handleRequest instead of actually
 // doing a network request.
 //
-func NewMockClient(handleRequest func(context.Context, *Request) error) Client {
-	return &mockClient{handleRequest}
+// Of the client options, only WithDefaultHeader, WithUserAgent and
+// WithDefaultBasicAuth apply to a mock client.
+//
+func NewMockClient(handleRequest func(context.Context, *Request) error, opts ...ClientOption) Client {
+	mc := &mockClient{handleRequest: handleRequest}
+	for _, o := range opts {
+		o(&mc.config)
+	}
+	return mc
 }
 
 type mockClient struct {
 	handleRequest func(context.Context, *Request) error
+	config        clientConfig
+	// clock times WithConditional; tests replace it with a fake.
+	clock clock
 }
 
-func (mc *mockClient) do(ctx context.Context, method, baseURL string, options ...RequestOption) error {
+func (mc *mockClient) newRequest(method, baseURL string, options []RequestOption) (*Request, error) {
 	var r = Request{
 		URL:    baseURL,
 		Method: method,
 		Params: url.Values{},
+		Header: http.Header{},
 	}
-	for _, o := range options {
-		o(&r)
+	r.setDefaultHeaders(mc.config.header)
+	r.setUserAgent(mc.config.userAgent)
+	if mc.config.authorization != "" {
+		r.Header.Set("Authorization", mc.config.authorization)
+	}
+	if err := r.apply(options); err != nil {
+		return nil, err
+	}
+	r.setConditional(mc.clockOrDefault().Now())
+	return &r, nil
+}
+
+func (mc *mockClient) do(ctx context.Context, method, baseURL string, options ...RequestOption) error {
+	r, err := mc.newRequest(method, baseURL, options)
+	if err != nil {
+		return err
+	}
+	if r.StatusCode != nil {
+		*r.StatusCode = http.StatusOK
 	}
 
-	return mc.handleRequest(ctx, &r)
+	view := r.freeze()
+	err = mc.handleRequest(ctx, r)
+	if frozenErr := r.checkFrozen(view); frozenErr != nil {
+		return frozenErr
+	}
+	// A BadStatusError from the handler stands for the status of the response.
+	code := http.StatusOK
+	var bse *BadStatusError
+	if errors.As(err, &bse) {
+		code = bse.Code
+		if r.StatusCode != nil {
+			*r.StatusCode = code
+		}
+	} else if err != nil {
+		return err
+	}
+	if !r.acceptedStatus(code, nil) {
+		if bse == nil {
+			return &BadStatusError{Code: code, Expected: r.expectedStatuses}
+		}
+		if r.errorBody != nil {
+			r.errorBody.Write(bse.Body)
+		}
+		r.decodeErrorBody(bse)
+		return err
+	}
+	return nil
 }
 
 func (mc *mockClient) Get(ctx context.Context, url string, options ...RequestOption) error {
@@ -140,6 +777,18 @@ func (mc *mockClient) Post(ctx context.Context, url string, options ...RequestOp
 	return mc.do(ctx, "POST", url, options...)
 }
 
+func (mc *mockClient) Do(ctx context.Context, method, url string, options ...RequestOption) error {
+	return mc.do(ctx, method, url, options...)
+}
+
+func (mc *mockClient) Close(ctx context.Context) error {
+	return nil
+}
+
+func (mc *mockClient) BackgroundTasks() []BackgroundTask {
+	return nil
+}
+
 func (c *client) Get(ctx context.Context, url string, options ...RequestOption) error {
 	return c.do(ctx, "GET", url, options...)
 }
@@ -148,6 +797,100 @@ func (c *client) Post(ctx context.Context, url string, options ...RequestOption)
 	return c.do(ctx, "POST", url, options...)
 }
 
+func (c *client) Do(ctx context.Context, method, url string, options ...RequestOption) error {
+	return c.do(ctx, method, url, options...)
+}
+
+func (c *client) Close(ctx context.Context) error {
+	err := c.tasks.close(ctx)
+	c.client.CloseIdleConnections()
+	return err
+}
+
+func (c *client) BackgroundTasks() []BackgroundTask {
+	return c.tasks.list()
+}
+
+func (bc *BodyCapture) capture(body []byte, limit int64) {
+	bc.Size = int64(len(body))
+	bc.Truncated = bc.Size > limit
+	if bc.Truncated {
+		body = body[:limit]
+	}
+	bc.Bytes = append([]byte(nil), body...)
+}
+
+// ErrConflictingOptions is returned when a request is given options that cannot be combined.
+var ErrConflictingOptions = errors.New("conflicting request options")
+
+// outputs names the options that consume the response body.
+func (req *Request) outputs() []string {
+	return req.appendOutputs(nil)
+}
+
+// appendOutputs appends the names of the options that consume the response body to names.
+func (req *Request) appendOutputs(names []string) []string {
+	if req.Output != nil {
+		names = append(names, "WithResponse")
+	}
+	// WithResponse has always taken precedence over WithJSONResponse, so
+	// that pair is not reported as a conflict.
+	if req.JSONOutput != nil && req.Output == nil {
+		names = append(names, "WithJSONResponse")
+	}
+	if req.XMLOutput != nil {
+		names = append(names, "WithXMLResponse")
+	}
+	if req.DecodedOutput != nil {
+		names = append(names, "WithDecodedResponse")
+	}
+	if req.StringOutput != nil {
+		names = append(names, "WithStringResponse")
+	}
+	if req.BytesOutput != nil {
+		names = append(names, "WithBytesResponse")
+	}
+	if req.discardBody {
+		names = append(names, "WithDiscardBody")
+	}
+	if req.ResponseCallback != nil {
+		names = append(names, "WithResponseCallback")
+	}
+	if req.RawResponse != nil {
+		names = append(names, "WithRawResponse")
+	}
+	return names
+}
+
+// apply applies options to the request and validates the result.
+func (req *Request) apply(options []RequestOption) error {
+	for _, o := range options {
+		o(req)
+	}
+	return req.validate()
+}
+
+// validate checks the combination of options applied to the request.
+func (req *Request) validate() error {
+	var buf [4]string
+	if names := req.appendOutputs(buf[:0]); len(names) > 1 {
+		return fmt.Errorf("%w: %s", ErrConflictingOptions, strings.Join(names, " and "))
+	}
+	if req.BodyReader != nil && req.Body != nil {
+		return fmt.Errorf("%w: WithJSONBody and WithBodyReader", ErrConflictingOptions)
+	}
+	if req.BodyReader != nil && req.sentBody != nil {
+		return fmt.Errorf("%w: WithSentBodyCapture cannot capture a WithBodyReader body", ErrConflictingOptions)
+	}
+	if req.sentBody != nil && req.sentBodyLimit < 0 {
+		return fmt.Errorf("%w: WithSentBodyCapture max size %d is negative", ErrInvalidOption, req.sentBodyLimit)
+	}
+	if err := req.validateCompression(); err != nil {
+		return err
+	}
+	return req.validateChecksum()
+}
+
 func (req *Request) prepareRequest(ctx context.Context) (*http.Request, error) {
 	var body io.Reader
 	if req.Body != nil {
@@ -156,48 +899,459 @@ func (req *Request) prepareRequest(ctx context.Context) (*http.Request, error) {
 			return nil, err
 		}
 		body = bytes.NewBuffer(j)
+		if req.sentBody != nil {
+			req.sentBody.capture(j, req.sentBodyLimit)
+		}
+	} else if req.BodyReader != nil {
+		body = req.BodyReader
 	}
 	var urlWithParams = req.URL
-	if len(req.Params) > 0 {
-		urlWithParams += "?" + req.Params.Encode()
+	if params := req.apiKey.params(req.Params); len(params) > 0 {
+		urlWithParams += "?" + params.Encode()
 	}
-	r, err := http.NewRequest(req.Method, urlWithParams, body)
+	r, err := http.NewRequestWithContext(ctx, req.Method, urlWithParams, body)
 	if err != nil {
 		return nil, err
 	}
-	r = r.WithContext(ctx)
+	if req.BodyReader != nil {
+		r.ContentLength = req.bodyLength
+	}
 
-	r.Header = req.Header
+	// The sent header is a copy, which AddCookie may modify.
+	r.Header = req.Header.Clone()
+	for _, c := range req.Cookies {
+		r.AddCookie(c)
+	}
+	req.apiKey.setHeader(r.Header)
 	return r, nil
 }
 
 type BadStatusError struct {
 	Code int
 	Body []byte
+	// Expected holds the statuses given to WithExpectStatus, if any.
+	Expected []int
+	// ErrorDecoded reports whether Body was decoded into the WithJSONError target.
+	ErrorDecoded bool
+	// RetryAfter is how long the Retry-After header asked to wait, or zero without a valid one.
+	RetryAfter time.Duration
 }
 
 func (bse *BadStatusError) Error() string {
+	if len(bse.Expected) > 0 {
+		return fmt.Sprintf("Got HTTP %d (%s), expected %v: %q", bse.Code, http.StatusText(bse.Code), bse.Expected, string(bse.Body))
+	}
 	return fmt.Sprintf("Got HTTP %d (%s): %q", bse.Code, http.StatusText(bse.Code), string(bse.Body))
 }
 
-func (req *Request) handleResponse(httpResp *http.Response) error {
-	if httpResp.StatusCode < 200 || httpResp.StatusCode >= 300 {
-		buf, _ := ioutil.ReadAll(httpResp.Body)
+// retryable reports whether a request that failed with err may succeed if sent again: a transport error or a 5xx status, while ctx is live.
+func retryable(ctx context.Context, err error) bool {
+	var bse *BadStatusError
+	return err != nil && ctx.Err() == nil && (!errors.As(err, &bse) || bse.Code >= 500)
+}
+
+// DecodeError is returned when a successful response body cannot be decoded.
+type DecodeError struct {
+	Format      string
+	URL         string
+	ContentType string
+	// StrippedPrefix is the BOM or XSSI guard removed before decoding, if any.
+	StrippedPrefix string
+	Err            error
+}
 
-		return &BadStatusError{Code: httpResp.StatusCode, Body: buf}
+func (de *DecodeError) Error() string {
+	if de.StrippedPrefix != "" {
+		return fmt.Sprintf("decoding %s response from %s (Content-Type %q, stripped prefix %q): %v", de.Format, de.URL, de.ContentType, de.StrippedPrefix, de.Err)
 	}
+	return fmt.Sprintf("decoding %s response from %s (Content-Type %q): %v", de.Format, de.URL, de.ContentType, de.Err)
+}
+
+func (de *DecodeError) Unwrap() error {
+	return de.Err
+}
+
+//
+// OutputWriteError is returned when the writer given to WithResponse fails.
+//
+// The rest of the response body is drained up to a limit to report whether
+// the transfer itself was complete. If it was not, the connection is closed
+// rather than reused.
+//
+type OutputWriteError struct {
+	// BytesWritten is the number of body bytes accepted by the writer.
+	BytesWritten int64
+	// Remaining reports whether the body had unread data when the writer failed.
+	Remaining bool
+	// Drained is the number of unread body bytes discarded after the failure.
+	// It stops counting at maxDrainBytes.
+	Drained int64
+	Err     error
+}
+
+func (owe *OutputWriteError) Error() string {
+	return fmt.Sprintf("writing response output after %d bytes: %v", owe.BytesWritten, owe.Err)
+}
+
+func (owe *OutputWriteError) Unwrap() error {
+	return owe.Err
+}
+
+// maxDiscardBytes bounds how much of the body WithDiscardBody and WithResponseTrailers read.
+const maxDiscardBytes = 1 << 20
+
+// maxDrainBytes bounds how much of an unwanted body is read before giving up on the connection.
+const maxDrainBytes = 64 << 10
+
+type countingWriter struct {
+	w   io.Writer
+	n   int64
+	err error
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	if err != nil {
+		cw.err = err
+	}
+	return n, err
+}
+
+// copyOutput copies body to w, wrapping failures of w in an *OutputWriteError.
+func copyOutput(w io.Writer, body io.Reader) error {
+	cw := countingWriter{w: w}
+	_, err := io.Copy(&cw, body)
+	if err == nil {
+		return nil
+	}
+	if cw.err == nil && err != io.ErrShortWrite {
+		// Reading the body failed, not the writer.
+		return err
+	}
+
+	drained, _ := io.Copy(ioutil.Discard, io.LimitReader(body, maxDrainBytes))
+	return &OutputWriteError{
+		BytesWritten: cw.n,
+		Remaining:    drained > 0,
+		Drained:      drained,
+		Err:          err,
+	}
+}
+
+// decodeJSON unmarshals buf into v like json.Unmarshal, applying the JSON options of the request.
+func (req *Request) decodeJSON(buf []byte, v interface{}) error {
+	if !req.jsonStrict && !req.jsonNumbers {
+		return json.Unmarshal(buf, v)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(buf))
+	if req.jsonStrict {
+		dec.DisallowUnknownFields()
+	}
+	if req.jsonNumbers {
+		dec.UseNumber()
+	}
+	if err := dec.Decode(v); err != nil {
+		return err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return errors.New("invalid data after top-level value")
+	}
+	return nil
+}
+
+// ResponseTooLargeError is returned when a response body is larger than allowed.
+type ResponseTooLargeError struct {
+	Limit int64
+}
+
+func (rtle *ResponseTooLargeError) Error() string {
+	return fmt.Sprintf("response body exceeds %d bytes", rtle.Limit)
+}
+
+// maxBytesReader fails with a *ResponseTooLargeError once more than limit bytes are read.
+type maxBytesReader struct {
+	r         io.Reader
+	remaining int64
+	limit     int64
+}
+
+func (m *maxBytesReader) Read(p []byte) (int, error) {
+	if m.remaining <= 0 {
+		// Probe for data past the limit, so a body of exactly limit bytes succeeds.
+		var probe [1]byte
+		n, err := m.r.Read(probe[:])
+		if n > 0 {
+			return 0, &ResponseTooLargeError{Limit: m.limit}
+		}
+		return 0, err
+	}
+	if int64(len(p)) > m.remaining {
+		p = p[:m.remaining]
+	}
+	n, err := m.r.Read(p)
+	m.remaining -= int64(n)
+	return n, err
+}
+
+// maxStringResponseBytes bounds the body read by WithStringResponse.
+const maxStringResponseBytes = 10 << 20
+
+// readAllLimited reads r to EOF, failing if it holds more than limit bytes.
+func readAllLimited(r io.Reader, limit int64) ([]byte, error) {
+	buf, err := ioutil.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(buf)) > limit {
+		return nil, &ResponseTooLargeError{Limit: limit}
+	}
+	return buf, nil
+}
+
+// bufferBody reads the body of resp if it holds at most limit bytes, and closes it.
+// A longer body is left in resp for the caller, with the bytes already read put back.
+func bufferBody(resp *http.Response, limit int64) ([]byte, bool, error) {
+	buf, err := ioutil.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		resp.Body.Close()
+		return nil, false, err
+	}
+	if int64(len(buf)) > limit {
+		resp.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(buf), resp.Body), resp.Body}
+		return nil, false, nil
+	}
+	resp.Body.Close()
+	return buf, true, nil
+}
 
+func (req *Request) handleResponse(httpResp *http.Response, now time.Time) error {
+	if req.StatusCode != nil {
+		*req.StatusCode = httpResp.StatusCode
+	}
+	if req.ResponseHeader != nil {
+		*req.ResponseHeader = httpResp.Header.Clone()
+	}
+	if req.ResponseCookies != nil {
+		*req.ResponseCookies = httpResp.Cookies()
+	}
+
+	var body io.Reader = httpResp.Body
+
+	successful := req.successful(httpResp.StatusCode, httpResp.Header)
+	if !req.acceptedStatus(httpResp.StatusCode, httpResp.Header) {
+		var errBody io.Reader = httpResp.Body
+		if decoded, err := req.decodeContentEncoding(httpResp, errBody); err == nil {
+			errBody = decoded
+		}
+		if req.maxResponseBytes > 0 {
+			errBody = io.LimitReader(errBody, req.maxResponseBytes)
+		}
+		var buf []byte
+		if req.errorBody != nil {
+			buf = req.streamErrorBody(errBody)
+		} else {
+			buf, _ = ioutil.ReadAll(errBody)
+			req.teeErrorBody(buf)
+		}
+		req.readTrailers(httpResp, httpResp.Body)
+
+		bse := &BadStatusError{Code: httpResp.StatusCode, Body: buf, Expected: req.expectedStatuses}
+		bse.RetryAfter, _ = parseRetryAfter(httpResp.Header.Get("Retry-After"), now)
+		req.decodeErrorBody(bse)
+		return bse
+	}
+	if err := req.checkContentType(httpResp.Header.Get("Content-Type"), body); err != nil {
+		return err
+	}
+	if req.onResponse != nil {
+		req.onResponse(httpResp)
+	}
+	if req.downloadProgress != nil {
+		pr := &progressReader{r: body, total: httpResp.ContentLength, fn: req.downloadProgress}
+		defer pr.stop()
+		body = pr
+	}
+	body, err := req.decodeContentEncoding(httpResp, body)
+	if err != nil {
+		return err
+	}
+	if req.maxResponseBytes > 0 {
+		// The limit applies to the decompressed body, as a small compressed one can expand without bound.
+		body = &maxBytesReader{r: body, remaining: req.maxResponseBytes, limit: req.maxResponseBytes}
+	}
+	tee := req.newTeeReader(body)
+	if tee != nil {
+		body = tee
+	}
+	var checksum *checksumReader
+	if req.checksumAlgo != "" {
+		checksum = newChecksumReader(body, req.checksumAlgo, req.checksumHex)
+		body = checksum
+	}
+
+	if err := req.readBody(httpResp, body); err != nil {
+		var de *DecodeError
+		var ee *EnvelopeError
+		if successful || !(errors.As(err, &de) || errors.As(err, &ee)) {
+			return err
+		}
+		// The body of an allowed error status is only decoded if it can be.
+	}
+	if tee != nil {
+		if err := tee.finish(); err != nil {
+			return err
+		}
+	}
+	req.readTrailers(httpResp, body)
+
+	if checksum != nil {
+		return checksum.verify()
+	}
+	return nil
+}
+
+// readTrailers stores the trailers of the response for WithResponseTrailers, reading the rest of body to reach them.
+func (req *Request) readTrailers(httpResp *http.Response, body io.Reader) {
+	if req.ResponseTrailer == nil {
+		return
+	}
+	io.Copy(ioutil.Discard, io.LimitReader(body, maxDiscardBytes))
+	*req.ResponseTrailer = httpResp.Trailer.Clone()
+}
+
+// errorBodyPrefixBytes is how much of a body streamed by WithErrorBody is kept in the BadStatusError.
+const errorBodyPrefixBytes = 512
+
+// streamErrorBody copies body to the WithErrorBody writer and the tee writers, returning its first bytes.
+// The request fails with a *BadStatusError either way, so write errors only stop the copy.
+func (req *Request) streamErrorBody(body io.Reader) []byte {
+	prefix := &prefixWriter{limit: errorBodyPrefixBytes}
+	io.Copy(io.MultiWriter(append([]io.Writer{prefix, req.errorBody}, req.teeWriters...)...), body)
+	return prefix.buf
+}
+
+// prefixWriter keeps the first limit bytes written to it.
+type prefixWriter struct {
+	buf   []byte
+	limit int
+}
+
+func (pw *prefixWriter) Write(p []byte) (int, error) {
+	if n := pw.limit - len(pw.buf); n > 0 {
+		if n > len(p) {
+			n = len(p)
+		}
+		pw.buf = append(pw.buf, p[:n]...)
+	}
+	return len(p), nil
+}
+
+// decodeErrorBody decodes the body of bse into the WithJSONError target, recording whether it could.
+func (req *Request) decodeErrorBody(bse *BadStatusError) {
+	if req.jsonError == nil {
+		return
+	}
+	buf, _ := req.stripJSONPrefix(bse.Body)
+	bse.ErrorDecoded = json.Unmarshal(buf, req.jsonError) == nil
+}
+
+// DefaultSuccessCheck is the success predicate of requests without WithSuccessCheck: any 2xx status.
+func DefaultSuccessCheck(status int, header http.Header) bool {
+	return status >= 200 && status < 300
+}
+
+// successful reports whether a response is successful according to the success predicate of the request.
+func (req *Request) successful(code int, header http.Header) bool {
+	if req.successCheck != nil {
+		return req.successCheck(code, header)
+	}
+	return DefaultSuccessCheck(code, header)
+}
+
+// acceptedStatus reports whether a response with code is handled rather than returned as a BadStatusError.
+func (req *Request) acceptedStatus(code int, header http.Header) bool {
+	if len(req.expectedStatuses) > 0 {
+		return containsStatus(req.expectedStatuses, code)
+	}
+	return req.successful(code, header) || containsStatus(req.allowedStatuses, code)
+}
+
+func containsStatus(codes []int, code int) bool {
+	for _, c := range codes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+// readBody passes the response body to the output of the request.
+func (req *Request) readBody(httpResp *http.Response, body io.Reader) error {
 	if req.Output != nil {
-		if _, err := io.Copy(req.Output, httpResp.Body); err != nil {
+		if err := copyOutput(req.Output, body); err != nil {
 			return err
 		}
 	} else if req.JSONOutput != nil {
-		buf, err := ioutil.ReadAll(httpResp.Body)
+		buf, err := ioutil.ReadAll(body)
 		if err != nil {
 			return err
 		}
+		buf, req.strippedPrefix = req.stripJSONPrefix(buf)
+
+		if req.envelope != nil {
+			if buf, err = req.envelope.unwrap(req.URL, httpResp.Header.Get("Content-Type"), buf); err != nil {
+				return err
+			}
+		}
 
-		if err = json.Unmarshal(buf, req.JSONOutput); err != nil {
+		if err = req.decodeJSON(buf, req.JSONOutput); err != nil {
+			return &DecodeError{Format: "JSON", URL: req.URL, ContentType: httpResp.Header.Get("Content-Type"), StrippedPrefix: req.strippedPrefix, Err: err}
+		}
+	} else if req.XMLOutput != nil {
+		buf, err := ioutil.ReadAll(body)
+		if err != nil {
+			return err
+		}
+
+		if err = xml.Unmarshal(buf, req.XMLOutput); err != nil {
+			return &DecodeError{Format: "XML", URL: req.URL, ContentType: httpResp.Header.Get("Content-Type"), Err: err}
+		}
+	} else if req.DecodedOutput != nil {
+		buf, err := ioutil.ReadAll(body)
+		if err != nil {
+			return err
+		}
+
+		if err = req.decode(buf, req.DecodedOutput); err != nil {
+			return &DecodeError{Format: req.decodedFormat, URL: req.URL, ContentType: httpResp.Header.Get("Content-Type"), Err: err}
+		}
+	} else if req.StringOutput != nil {
+		var limit int64 = maxStringResponseBytes
+		if req.maxResponseBytes > 0 {
+			limit = req.maxResponseBytes
+		}
+		buf, err := readAllLimited(body, limit)
+		if err != nil {
+			return fmt.Errorf("reading response from %s: %w", req.URL, err)
+		}
+		*req.StringOutput = string(buf)
+	} else if req.BytesOutput != nil {
+		buf, err := ioutil.ReadAll(body)
+		if err != nil {
+			return err
+		}
+		*req.BytesOutput = buf
+	} else if req.ResponseCallback != nil {
+		if err := req.ResponseCallback(httpResp.StatusCode, httpResp.Header, body); err != nil {
+			return err
+		}
+	} else if req.discardBody {
+		if _, err := io.Copy(ioutil.Discard, io.LimitReader(body, maxDiscardBytes)); err != nil {
 			return err
 		}
 	}
@@ -205,27 +1359,91 @@ func (req *Request) handleResponse(httpResp *http.Response) error {
 	return nil
 }
 
-func (c *client) do(ctx context.Context, method, baseURL string, options ...RequestOption) error {
+func (c *client) newRequest(method, baseURL string, options []RequestOption) (*Request, error) {
+	return c.buildRequest(method, c.resolveURL(baseURL, true), options)
+}
+
+// previewRequest returns the request newRequest would, without taking the turn of a WithEndpoints endpoint.
+func (c *client) previewRequest(method, baseURL string, options []RequestOption) (*Request, error) {
+	return c.buildRequest(method, c.resolveURL(baseURL, false), options)
+}
+
+// buildRequest returns the request to rawURL, as resolved, with the client defaults and options applied.
+func (c *client) buildRequest(method, rawURL string, options []RequestOption) (*Request, error) {
 	var req = Request{
-		Method: method,
-		URL:    baseURL,
-		Params: url.Values{},
-		Header: http.Header{},
+		Method:   method,
+		URL:      rawURL,
+		Params:   url.Values{},
+		Header:   make(http.Header, 2),
+		envelope: c.config.envelope,
+		apiKey:   c.config.apiKey,
 	}
-	for _, o := range options {
-		o(&req)
+	req.setDefaultHeaders(c.config.header)
+	req.setUserAgent(c.config.userAgent)
+	if c.config.authorization != "" {
+		req.Header.Set("Authorization", c.config.authorization)
+	}
+	if err := req.apply(options); err != nil {
+		return nil, err
+	}
+	req.setConditional(c.clockOrDefault().Now())
+	return &req, nil
+}
+
+func (c *client) do(ctx context.Context, method, baseURL string, options ...RequestOption) error {
+	req, err := c.newRequest(method, baseURL, options)
+	if err != nil {
+		return err
+	}
+	if err := c.authorize(ctx, req, req.Header); err != nil {
+		return err
 	}
 
 	r, err := req.prepareRequest(ctx)
 	if err != nil {
 		return err
 	}
+	// Only response hooks see the Request after it is sent.
+	var view requestView
+	if req.onResponse != nil {
+		view = req.freeze()
+	}
 
-	httpResp, err := c.client.Do(r)
+	var gen uint64
+	if c.config.reauth != nil {
+		gen = c.reauth.generation()
+	}
+	httpResp, err := c.sendCached(ctx, req, r)
 	if err != nil {
+		var ue *url.Error
+		if param := req.apiKey.secretParam(); param != "" && errors.As(err, &ue) {
+			ue.URL = redactURL(r.URL, param)
+		}
 		return err
 	}
+	if req.digestAuth != nil {
+		replay := func(retry *http.Request) (*http.Response, error) { return c.send(ctx, req, retry) }
+		if httpResp, err = req.digestAuth.retry(r, httpResp, replay); err != nil {
+			return err
+		}
+	}
+	if c.config.reauth != nil {
+		if httpResp, err = c.reauthenticate(ctx, req, r, httpResp, gen); err != nil {
+			return err
+		}
+	}
+	if req.RawResponse != nil {
+		// The caller closes the body.
+		*req.RawResponse = httpResp
+		return nil
+	}
 	defer httpResp.Body.Close()
 
-	return req.handleResponse(httpResp)
+	if err := req.handleResponse(httpResp, c.clockOrDefault().Now()); err != nil {
+		return err
+	}
+	if req.onResponse != nil {
+		return req.checkFrozen(view)
+	}
+	return nil
 }
@@ -0,0 +1,37 @@
+package http
+
+import (
+	"crypto/tls"
+	"net/http"
+)
+
+//
+// WithHTTP2 will negotiate HTTP/2 with TLS servers that support it if
+// enabled, even with the custom TLS config of NewTLSClient, which otherwise
+// disables it. If not enabled, requests stick to HTTP/1.1, e.g. to avoid
+// server bugs with HTTP/2 flow control stalling large uploads.
+//
+// Like the connection pool options, it configures a copy of the transport,
+// which must be an *http.Transport when given with WithTransport.
+//
+func WithHTTP2(enabled bool) ClientOption {
+	return configureTransport("WithHTTP2", func(t *http.Transport) {
+		t.ForceAttemptHTTP2 = enabled
+		if enabled {
+			return
+		}
+		// A non-nil empty TLSNextProto disables HTTP/2, but an h2 the
+		// transport already advertised must be withdrawn too.
+		t.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+		if t.TLSClientConfig != nil {
+			config := t.TLSClientConfig.Clone()
+			config.NextProtos = nil
+			for _, proto := range t.TLSClientConfig.NextProtos {
+				if proto != "h2" {
+					config.NextProtos = append(config.NextProtos, proto)
+				}
+			}
+			t.TLSClientConfig = config
+		}
+	})
+}
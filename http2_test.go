@@ -0,0 +1,39 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWithHTTP2(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Proto))
+	}))
+	srv.EnableHTTP2 = true
+	srv.StartTLS()
+	defer srv.Close()
+	config := srv.Client().Transport.(*http.Transport).TLSClientConfig.Clone()
+	config.NextProtos = nil
+
+	for _, tt := range []struct {
+		name string
+		cli  Client
+		want string
+	}{
+		{name: "TLS client default", cli: NewTLSClient(config), want: "HTTP/1.1"},
+		{name: "TLS client enabled", cli: NewTLSClient(config, WithHTTP2(true)), want: "HTTP/2.0"},
+		{name: "TLS client disabled", cli: NewTLSClient(config, WithHTTP2(false)), want: "HTTP/1.1"},
+		{name: "transport enabled", cli: NewClient(WithTransport(srv.Client().Transport), WithHTTP2(true)), want: "HTTP/2.0"},
+		{name: "transport disabled", cli: NewClient(WithTransport(srv.Client().Transport), WithHTTP2(false)), want: "HTTP/1.1"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var proto string
+			if err := tt.cli.Get(context.Background(), srv.URL, WithStringResponse(&proto)); err != nil || proto != tt.want {
+				t.Errorf("server saw %q, %v, want %s", proto, err, tt.want)
+			}
+		})
+	}
+}
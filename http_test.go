@@ -1,9 +1,20 @@
 package http
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 
 	"reflect"
@@ -122,6 +133,23 @@ func TestGet_param_multi(t *testing.T) {
 	}
 }
 
+func TestGet_param_custom_option(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.RawQuery != "param=value" {
+			t.Errorf("Unexpected url %s", r.URL)
+		}
+	}))
+	defer srv.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	setParam := func(r *Request) { r.Params.Set("param", "value") }
+	if err := NewClient().Get(ctx, srv.URL, setParam); err != nil {
+		t.Errorf("Get() error = %v", err)
+	}
+}
+
 func TestGet_json_response(t *testing.T) {
 	t.Parallel()
 	var currentHandler http.HandlerFunc
@@ -179,3 +207,1190 @@ func TestGet_json_response(t *testing.T) {
 		})
 	}
 }
+
+func TestGet_xml_response(t *testing.T) {
+	t.Parallel()
+	var currentHandler http.HandlerFunc
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		currentHandler(w, r)
+	}))
+	defer srv.Close()
+
+	type payloadType struct {
+		Name string `xml:"name"`
+	}
+	xmlTestCases := []struct {
+		name     string
+		respBody string
+		wantErr  bool
+		wantResp payloadType
+	}{
+		{
+			name:     "simple xml response",
+			respBody: `<payload><name>alex</name></payload>`,
+			wantResp: payloadType{Name: "alex"},
+		},
+		{
+			name:     "invalid xml response",
+			respBody: `{"name": "alex"}`,
+			wantErr:  true,
+		},
+	}
+	for _, tt := range xmlTestCases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+			defer cancel()
+
+			currentHandler = func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("Accept") != "application/xml" {
+					t.Error("Unexpected Accept header", r.Header)
+				}
+				w.Write([]byte(tt.respBody))
+			}
+
+			var resp payloadType
+			err := NewClient().Get(ctx, srv.URL, WithXMLResponse(&resp))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Get() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			var decodeErr *DecodeError
+			if tt.wantErr && !errors.As(err, &decodeErr) {
+				t.Errorf("Get() error = %T, want *DecodeError", err)
+			}
+			if !reflect.DeepEqual(tt.wantResp, resp) {
+				t.Errorf("Get() %v, want %v", resp, tt.wantResp)
+			}
+		})
+	}
+}
+
+func TestGet_xml_json_conflict(t *testing.T) {
+	t.Parallel()
+	var x, j map[string]string
+	err := NewClient().Get(context.Background(), "http://127.0.0.1:0", WithXMLResponse(&x), WithJSONResponse(&j))
+	if !errors.Is(err, ErrConflictingOptions) {
+		t.Errorf("Get() error = %v, want %v", err, ErrConflictingOptions)
+	}
+}
+
+func TestMockClient_xml_response(t *testing.T) {
+	t.Parallel()
+	type payloadType struct {
+		Name string
+	}
+	cli := NewMockClient(func(ctx context.Context, r *Request) error {
+		r.XMLOutput.(*payloadType).Name = "alex"
+		return nil
+	})
+
+	var resp payloadType
+	if err := cli.Get(context.Background(), "http://example.com", WithXMLResponse(&resp)); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if resp.Name != "alex" {
+		t.Errorf("Get() %v, want alex", resp)
+	}
+}
+
+func TestGet_string_response(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`ok`))
+	}))
+	defer srv.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	var resp string
+	if err := NewClient().Get(ctx, srv.URL, WithStringResponse(&resp)); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if resp != "ok" {
+		t.Errorf("Get() %q, want %q", resp, "ok")
+	}
+}
+
+func TestReadAllLimited(t *testing.T) {
+	t.Parallel()
+	if buf, err := readAllLimited(strings.NewReader("12345"), 5); err != nil || string(buf) != "12345" {
+		t.Errorf("readAllLimited() = %q, %v, want %q", buf, err, "12345")
+	}
+	var tooLarge *ResponseTooLargeError
+	if _, err := readAllLimited(strings.NewReader("123456"), 5); !errors.As(err, &tooLarge) || tooLarge.Limit != 5 {
+		t.Errorf("readAllLimited() error = %v, want *ResponseTooLargeError", err)
+	}
+}
+
+type failingWriter struct {
+	n     int
+	limit int
+}
+
+var errWriterFull = errors.New("writer full")
+
+func (fw *failingWriter) Write(p []byte) (int, error) {
+	if fw.n+len(p) > fw.limit {
+		n := fw.limit - fw.n
+		fw.n = fw.limit
+		return n, errWriterFull
+	}
+	fw.n += len(p)
+	return len(p), nil
+}
+
+func TestGet_output_write_error(t *testing.T) {
+	t.Parallel()
+	var currentBody string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(currentBody))
+	}))
+	defer srv.Close()
+
+	writeErrorTestCases := []struct {
+		name          string
+		bodySize      int
+		wantRemaining bool
+		wantDrained   int64
+	}{
+		{
+			name:     "body fully read",
+			bodySize: 1000,
+		},
+		{
+			name:          "body larger than drain limit",
+			bodySize:      4 * maxDrainBytes,
+			wantRemaining: true,
+			wantDrained:   maxDrainBytes,
+		},
+	}
+	for _, tt := range writeErrorTestCases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+			defer cancel()
+			currentBody = strings.Repeat("x", tt.bodySize)
+
+			err := NewClient().Get(ctx, srv.URL, WithResponse(&failingWriter{limit: 100}))
+			var owe *OutputWriteError
+			if !errors.As(err, &owe) {
+				t.Fatalf("Get() error = %v, want *OutputWriteError", err)
+			}
+			if !errors.Is(err, errWriterFull) {
+				t.Errorf("Get() error = %v, want %v", err, errWriterFull)
+			}
+			if owe.BytesWritten != 100 || owe.Remaining != tt.wantRemaining || owe.Drained != tt.wantDrained {
+				t.Errorf("Get() error = %+v, want 100 bytes written, remaining %v, drained %d", owe, tt.wantRemaining, tt.wantDrained)
+			}
+		})
+	}
+}
+
+func TestGet_bytes_response(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte{0, 1, 2, 0xff})
+	}))
+	defer srv.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	var resp []byte
+	if err := NewClient().Get(ctx, srv.URL, WithBytesResponse(&resp)); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if want := []byte{0, 1, 2, 0xff}; !reflect.DeepEqual(resp, want) {
+		t.Errorf("Get() %v, want %v", resp, want)
+	}
+
+	var j map[string]string
+	if err := NewClient().Get(ctx, srv.URL, WithBytesResponse(&resp), WithJSONResponse(&j)); !errors.Is(err, ErrConflictingOptions) {
+		t.Errorf("Get() error = %v, want %v", err, ErrConflictingOptions)
+	}
+}
+
+func TestMockClient_bytes_response(t *testing.T) {
+	t.Parallel()
+	cli := NewMockClient(func(ctx context.Context, r *Request) error {
+		*r.BytesOutput = []byte("stubbed")
+		return nil
+	})
+
+	var resp []byte
+	if err := cli.Get(context.Background(), "http://example.com", WithBytesResponse(&resp)); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if string(resp) != "stubbed" {
+		t.Errorf("Get() %q, want %q", resp, "stubbed")
+	}
+}
+
+func TestPost_sent_body_capture(t *testing.T) {
+	t.Parallel()
+	var received []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received, _ = ioutil.ReadAll(r.Body)
+	}))
+	defer srv.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	body := map[string]string{"hello": "world"}
+	var full, truncated BodyCapture
+	if err := NewClient().Post(ctx, srv.URL,
+		WithJSONBody(body),
+		WithSentBodyCapture(&full, 1024)); err != nil {
+		t.Fatalf("Post() error = %v", err)
+	}
+	if !bytes.Equal(full.Bytes, received) || full.Truncated || full.Size != int64(len(received)) {
+		t.Errorf("captured %+v, server received %q", full, received)
+	}
+
+	if err := NewClient().Post(ctx, srv.URL,
+		WithJSONBody(body),
+		WithSentBodyCapture(&truncated, 4)); err != nil {
+		t.Fatalf("Post() error = %v", err)
+	}
+	if !bytes.Equal(truncated.Bytes, received[:4]) || !truncated.Truncated || truncated.Size != int64(len(received)) {
+		t.Errorf("captured %+v, want truncated prefix of %q", truncated, received)
+	}
+}
+
+func TestPost_sent_body_capture_negative(t *testing.T) {
+	t.Parallel()
+	var c BodyCapture
+	err := newClient(http.Client{Transport: failingTransport{t}}, nil).Post(context.Background(), "http://example.com",
+		WithJSONBody(map[string]string{"hello": "world"}),
+		WithSentBodyCapture(&c, -1))
+	if !errors.Is(err, ErrInvalidOption) {
+		t.Errorf("Post() error = %v, want ErrInvalidOption", err)
+	}
+}
+
+func TestGet_status_code(t *testing.T) {
+	t.Parallel()
+	var currentStatus int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(currentStatus)
+		w.Write([]byte(`{"name": "alex"}`))
+	}))
+	defer srv.Close()
+
+	for _, status := range []int{http.StatusOK, http.StatusCreated, http.StatusAccepted, http.StatusNotFound} {
+		ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+		currentStatus = status
+
+		var code int
+		var resp map[string]string
+		err := NewClient().Get(ctx, srv.URL, WithStatusCode(&code), WithJSONResponse(&resp))
+		cancel()
+		if (err != nil) != (status >= 300) {
+			t.Errorf("Get() error = %v for status %d", err, status)
+		}
+		if code != status {
+			t.Errorf("Get() status = %d, want %d", code, status)
+		}
+	}
+}
+
+func TestMockClient_status_code(t *testing.T) {
+	t.Parallel()
+	cli := NewMockClient(func(ctx context.Context, r *Request) error {
+		switch r.URL {
+		case "http://example.com/accepted":
+			*r.StatusCode = http.StatusAccepted
+		case "http://example.com/missing":
+			return &BadStatusError{Code: http.StatusNotFound}
+		}
+		return nil
+	})
+
+	mockStatusTestCases := []struct {
+		url  string
+		want int
+	}{
+		{url: "http://example.com/ok", want: http.StatusOK},
+		{url: "http://example.com/accepted", want: http.StatusAccepted},
+		{url: "http://example.com/missing", want: http.StatusNotFound},
+	}
+	for _, tt := range mockStatusTestCases {
+		var code int
+		cli.Get(context.Background(), tt.url, WithStatusCode(&code))
+		if code != tt.want {
+			t.Errorf("Get(%s) status = %d, want %d", tt.url, code, tt.want)
+		}
+	}
+}
+
+func TestGet_response_headers(t *testing.T) {
+	t.Parallel()
+	var currentStatus int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Remaining", "0")
+		w.Header().Set("ETag", `"v1"`)
+		w.WriteHeader(currentStatus)
+	}))
+	defer srv.Close()
+
+	for _, status := range []int{http.StatusOK, http.StatusTooManyRequests} {
+		ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+		currentStatus = status
+
+		var header http.Header
+		err := NewClient().Get(ctx, srv.URL, WithResponseHeaders(&header))
+		cancel()
+		var bse *BadStatusError
+		if (status == http.StatusTooManyRequests) != errors.As(err, &bse) {
+			t.Errorf("Get() error = %v for status %d", err, status)
+		}
+		if header.Get("X-RateLimit-Remaining") != "0" || header.Get("ETag") != `"v1"` {
+			t.Errorf("Get() headers = %v for status %d", header, status)
+		}
+	}
+}
+
+func TestPost_cookies(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			http.SetCookie(w, &http.Cookie{Name: "csrf", Value: "token"})
+			if r.URL.Query().Get("password") != "secret" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc"})
+		case "/me":
+			if c, err := r.Cookie("session"); err != nil || c.Value != "abc" {
+				w.WriteHeader(http.StatusUnauthorized)
+			}
+		}
+	}))
+	defer srv.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	var cookies []*http.Cookie
+	err := NewClient().Post(ctx, srv.URL+"/login", WithParam("password", "wrong"), WithResponseCookies(&cookies))
+	if err == nil || len(cookies) != 1 || cookies[0].Name != "csrf" {
+		t.Errorf("Post() error = %v, cookies = %v, want failure with csrf cookie", err, cookies)
+	}
+
+	if err := NewClient().Post(ctx, srv.URL+"/login", WithParam("password", "secret"), WithResponseCookies(&cookies)); err != nil {
+		t.Fatalf("Post() error = %v", err)
+	}
+	var opts []RequestOption
+	for _, c := range cookies {
+		opts = append(opts, WithCookie(c))
+	}
+	if err := NewClient().Get(ctx, srv.URL+"/me", opts...); err != nil {
+		t.Errorf("Get() error = %v", err)
+	}
+}
+
+func TestGet_strict_json_response(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"name": "alex", "nickname": "al"}`))
+	}))
+	defer srv.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	type payloadType struct {
+		Name string
+	}
+	var resp payloadType
+	if err := NewClient().Get(ctx, srv.URL, WithJSONResponse(&resp)); err != nil {
+		t.Errorf("Get() error = %v", err)
+	}
+
+	var strict payloadType
+	err := NewClient().Get(ctx, srv.URL, WithStrictJSONResponse(&strict))
+	var decodeErr *DecodeError
+	if !errors.As(err, &decodeErr) || decodeErr.URL != srv.URL || !strings.Contains(err.Error(), `"nickname"`) {
+		t.Errorf("Get() error = %v, want *DecodeError naming the URL and nickname", err)
+	}
+}
+
+func TestGet_json_numbers(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"id": 9007199254740993}`))
+	}))
+	defer srv.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	var resp map[string]interface{}
+	if err := NewClient().Get(ctx, srv.URL, WithJSONResponse(&resp), WithJSONNumbers()); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if id, ok := resp["id"].(json.Number); !ok || id.String() != "9007199254740993" {
+		t.Errorf("Get() id = %#v, want json.Number 9007199254740993", resp["id"])
+	}
+
+	var strict map[string]interface{}
+	if err := NewClient().Get(ctx, srv.URL, WithStrictJSONResponse(&strict), WithJSONNumbers()); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if id, ok := strict["id"].(json.Number); !ok || id.String() != "9007199254740993" {
+		t.Errorf("Get() strict id = %#v, want json.Number 9007199254740993", strict["id"])
+	}
+}
+
+func TestGet_max_response_bytes(t *testing.T) {
+	t.Parallel()
+	var currentStatus int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n, _ := strconv.Atoi(r.URL.Query().Get("n"))
+		if currentStatus != 0 {
+			w.WriteHeader(currentStatus)
+		}
+		w.Write([]byte(`"` + strings.Repeat("x", n-2) + `"`))
+	}))
+	defer srv.Close()
+
+	maxBytesTestCases := []struct {
+		name    string
+		size    int
+		wantErr bool
+	}{
+		{name: "below limit", size: 99},
+		{name: "at limit", size: 100},
+		{name: "above limit", size: 101, wantErr: true},
+	}
+	for _, tt := range maxBytesTestCases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+			defer cancel()
+			currentStatus = 0
+			n := WithParam("n", strconv.Itoa(tt.size))
+
+			var tooLarge *ResponseTooLargeError
+			var resp string
+			err := NewClient().Get(ctx, srv.URL, n, WithMaxResponseBytes(100), WithJSONResponse(&resp))
+			if errors.As(err, &tooLarge) != tt.wantErr || (err != nil && !tt.wantErr) {
+				t.Errorf("Get(JSON) error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			var buf bytes.Buffer
+			err = NewClient().Get(ctx, srv.URL, n, WithMaxResponseBytes(100), WithResponse(&buf))
+			if errors.As(err, &tooLarge) != tt.wantErr || (err != nil && !tt.wantErr) {
+				t.Errorf("Get(Output) error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if buf.Len() > 100 {
+				t.Errorf("Get(Output) wrote %d bytes past the limit", buf.Len())
+			}
+
+			var str string
+			err = NewClient().Get(ctx, srv.URL, n, WithMaxResponseBytes(100), WithStringResponse(&str))
+			if errors.As(err, &tooLarge) != tt.wantErr || (err != nil && !tt.wantErr) {
+				t.Errorf("Get(String) error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			currentStatus = http.StatusInternalServerError
+			err = NewClient().Get(ctx, srv.URL, n, WithMaxResponseBytes(100))
+			var bse *BadStatusError
+			if !errors.As(err, &bse) || len(bse.Body) > 100 {
+				t.Errorf("Get(error) error = %v, want BadStatusError with at most 100 bytes", err)
+			}
+		})
+	}
+}
+
+func TestGet_discard_body_reuses_connection(t *testing.T) {
+	t.Parallel()
+	var mu sync.Mutex
+	remotes := map[string]bool{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		remotes[r.RemoteAddr] = true
+		mu.Unlock()
+		w.Write(bytes.Repeat([]byte("x"), 32<<10))
+	}))
+	defer srv.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	cli := NewClient()
+	for i := 0; i < 3; i++ {
+		if err := cli.Get(ctx, srv.URL, WithDiscardBody()); err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+	}
+	if len(remotes) != 1 {
+		t.Errorf("server saw %d connections, want 1", len(remotes))
+	}
+}
+
+func TestGet_discard_body_conflict(t *testing.T) {
+	t.Parallel()
+	var j map[string]string
+	for _, o := range []RequestOption{WithJSONResponse(&j), WithResponse(ioutil.Discard)} {
+		err := NewClient().Get(context.Background(), "http://127.0.0.1:0", WithDiscardBody(), o)
+		if !errors.Is(err, ErrConflictingOptions) {
+			t.Errorf("Get() error = %v, want %v", err, ErrConflictingOptions)
+		}
+	}
+}
+
+func TestGet_response_callback(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/csv")
+		w.Write([]byte("a,b\n1,2\n"))
+	}))
+	defer srv.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	var contentType, body string
+	err := NewClient().Get(ctx, srv.URL, WithResponseCallback(func(status int, header http.Header, r io.Reader) error {
+		contentType = header.Get("Content-Type")
+		buf, err := ioutil.ReadAll(r)
+		body = string(buf)
+		return err
+	}))
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if contentType != "text/csv" || body != "a,b\n1,2\n" {
+		t.Errorf("callback saw %q, %q, want text/csv and the body", contentType, body)
+	}
+
+	errCallback := errors.New("callback failed")
+	err = NewClient().Get(ctx, srv.URL, WithResponseCallback(func(int, http.Header, io.Reader) error {
+		return errCallback
+	}))
+	if err != errCallback {
+		t.Errorf("Get() error = %v, want %v", err, errCallback)
+	}
+}
+
+func TestMockClient_response_callback(t *testing.T) {
+	t.Parallel()
+	cli := NewMockClient(func(ctx context.Context, r *Request) error {
+		return r.ResponseCallback(200, http.Header{"Content-Type": {"text/plain"}}, strings.NewReader("streamed"))
+	})
+
+	var body string
+	err := cli.Get(context.Background(), "http://example.com", WithResponseCallback(func(status int, header http.Header, r io.Reader) error {
+		buf, err := ioutil.ReadAll(r)
+		body = string(buf)
+		return err
+	}))
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if body != "streamed" {
+		t.Errorf("Get() body = %q, want streamed", body)
+	}
+}
+
+func TestGet_download_progress(t *testing.T) {
+	t.Parallel()
+	payload := bytes.Repeat([]byte("x"), 3<<20)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", strconv.Itoa(len(payload)))
+		w.Write(payload)
+	}))
+	defer srv.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var calls int
+	var received, total int64
+	var leaked io.Reader
+	err := NewClient().Get(ctx, srv.URL,
+		WithDownloadProgress(func(n, t int64) {
+			calls++
+			received, total = n, t
+		}),
+		WithResponseCallback(func(status int, header http.Header, body io.Reader) error {
+			leaked = body
+			_, err := io.Copy(ioutil.Discard, body)
+			return err
+		}))
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if calls < 2 || received != int64(len(payload)) || total != int64(len(payload)) {
+		t.Errorf("progress called %d times, last with %d/%d, want several calls ending at %d/%d", calls, received, total, len(payload), len(payload))
+	}
+
+	before := calls
+	leaked.Read(make([]byte, 1))
+	if calls != before {
+		t.Errorf("progress called after the request returned")
+	}
+}
+
+func TestGet_allowed_statuses(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/missing":
+			w.WriteHeader(http.StatusNotFound)
+		case "/conflict":
+			w.WriteHeader(http.StatusConflict)
+			w.Write([]byte(`{"error":"already exists"}`))
+		}
+	}))
+	defer srv.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	var code int
+	resp := map[string]string{"untouched": "yes"}
+	err := NewClient().Get(ctx, srv.URL+"/missing", WithAllowedStatuses(http.StatusNotFound), WithStatusCode(&code), WithJSONResponse(&resp))
+	if err != nil {
+		t.Fatalf("Get(404) error = %v", err)
+	}
+	if code != http.StatusNotFound || resp["untouched"] != "yes" {
+		t.Errorf("Get(404) = %d, %v, want 404 and the target untouched", code, resp)
+	}
+
+	var apiErr struct {
+		Error string `json:"error"`
+	}
+	err = NewClient().Get(ctx, srv.URL+"/conflict", WithAllowedStatuses(http.StatusNotFound, http.StatusConflict), WithStatusCode(&code), WithJSONResponse(&apiErr))
+	if err != nil {
+		t.Fatalf("Get(409) error = %v", err)
+	}
+	if code != http.StatusConflict || apiErr.Error != "already exists" {
+		t.Errorf("Get(409) = %d, %+v, want 409 with the decoded error body", code, apiErr)
+	}
+
+	var bse *BadStatusError
+	err = NewClient().Get(ctx, srv.URL+"/conflict", WithAllowedStatuses(http.StatusNotFound), WithJSONResponse(&apiErr))
+	if !errors.As(err, &bse) || bse.Code != http.StatusConflict {
+		t.Errorf("Get(409) error = %v, want BadStatusError for a status that is not allowed", err)
+	}
+}
+
+func TestMockClient_allowed_statuses(t *testing.T) {
+	t.Parallel()
+	cli := NewMockClient(func(ctx context.Context, r *Request) error {
+		return &BadStatusError{Code: http.StatusNotFound}
+	})
+
+	var code int
+	if err := cli.Get(context.Background(), "http://example.com", WithAllowedStatuses(http.StatusNotFound), WithStatusCode(&code)); err != nil {
+		t.Errorf("Get() error = %v", err)
+	}
+	if code != http.StatusNotFound {
+		t.Errorf("Get() status = %d, want 404", code)
+	}
+}
+
+func TestGet_expect_status(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/created" {
+			w.WriteHeader(http.StatusCreated)
+		}
+		w.Write([]byte(`{"id":"1"}`))
+	}))
+	defer srv.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	var resp map[string]string
+	if err := NewClient().Post(ctx, srv.URL+"/created", WithExpectStatus(http.StatusCreated), WithJSONResponse(&resp)); err != nil {
+		t.Fatalf("Post(201) error = %v", err)
+	}
+	if resp["id"] != "1" {
+		t.Errorf("Post(201) = %v, want decoded body", resp)
+	}
+
+	resp = nil
+	err := NewClient().Post(ctx, srv.URL+"/noop", WithExpectStatus(http.StatusCreated), WithExpectStatus(http.StatusAccepted), WithJSONResponse(&resp))
+	var bse *BadStatusError
+	if !errors.As(err, &bse) || bse.Code != http.StatusOK || string(bse.Body) != `{"id":"1"}` || !reflect.DeepEqual(bse.Expected, []int{201, 202}) {
+		t.Fatalf("Post(200) error = %v, want BadStatusError for 200 expecting 201 or 202", err)
+	}
+	if resp != nil {
+		t.Errorf("Post(200) decoded %v for an unexpected status", resp)
+	}
+}
+
+func TestMockClient_expect_status(t *testing.T) {
+	t.Parallel()
+	cli := NewMockClient(func(ctx context.Context, r *Request) error {
+		return nil
+	})
+
+	var bse *BadStatusError
+	if err := cli.Post(context.Background(), "http://example.com", WithExpectStatus(http.StatusCreated)); !errors.As(err, &bse) || bse.Code != http.StatusOK {
+		t.Errorf("Post() error = %v, want BadStatusError for the mock's 200", err)
+	}
+}
+
+func TestGet_json_error(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		switch r.URL.Path {
+		case "/json":
+			w.Write([]byte(`{"code":"invalid","message":"name is required"}`))
+		case "/html":
+			w.Write([]byte(`<html><body>Bad Request</body></html>`))
+		}
+	}))
+	defer srv.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	type apiError struct {
+		Code    string
+		Message string
+	}
+	jsonErrorTestCases := []struct {
+		path        string
+		wantDecoded bool
+		want        apiError
+	}{
+		{path: "/json", wantDecoded: true, want: apiError{Code: "invalid", Message: "name is required"}},
+		{path: "/html"},
+		{path: "/empty"},
+	}
+	for _, tt := range jsonErrorTestCases {
+		var got apiError
+		err := NewClient().Get(ctx, srv.URL+tt.path, WithJSONError(&got))
+		var bse *BadStatusError
+		if !errors.As(err, &bse) || bse.Code != http.StatusBadRequest {
+			t.Errorf("Get(%s) error = %v, want the 400 BadStatusError", tt.path, err)
+			continue
+		}
+		if bse.ErrorDecoded != tt.wantDecoded || got != tt.want {
+			t.Errorf("Get(%s) decoded = %v, %+v, want %v, %+v", tt.path, bse.ErrorDecoded, got, tt.wantDecoded, tt.want)
+		}
+	}
+}
+
+func TestMockClient_json_error(t *testing.T) {
+	t.Parallel()
+	cli := NewMockClient(func(ctx context.Context, r *Request) error {
+		return &BadStatusError{Code: http.StatusNotFound, Body: []byte(`{"message":"no such item"}`)}
+	})
+
+	var got struct{ Message string }
+	err := cli.Get(context.Background(), "http://example.com", WithJSONError(&got))
+	var bse *BadStatusError
+	if !errors.As(err, &bse) || !bse.ErrorDecoded || got.Message != "no such item" {
+		t.Errorf("Get() = %v, %+v, want the decoded error body", err, got)
+	}
+}
+
+func TestGet_expected_content_type(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", r.URL.Query().Get("type"))
+		io.WriteString(w, r.URL.Query().Get("body"))
+	}))
+	defer srv.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+	cli := NewClient()
+
+	var got map[string]string
+	err := cli.Get(ctx, srv.URL, WithParam("type", "application/json; charset=utf-8"), WithParam("body", `{"a":"b"}`),
+		WithExpectedContentType("application/json"), WithJSONResponse(&got))
+	if err != nil || got["a"] != "b" {
+		t.Errorf("Get() = %v, %v, want the decoded JSON body", got, err)
+	}
+
+	err = cli.Get(ctx, srv.URL, WithParam("type", "text/html"), WithParam("body", "<html>proxy error</html>"),
+		WithExpectedContentType("application/json"), WithJSONResponse(&got))
+	var cte *ContentTypeError
+	if !errors.As(err, &cte) || cte.ContentType != "text/html" || string(cte.BodyPrefix) != "<html>proxy error</html>" {
+		t.Errorf("Get() error = %v, want *ContentTypeError for text/html", err)
+	}
+}
+
+func TestGet_response_trailers(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Trailer", "X-Checksum, X-Status")
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+		io.WriteString(w, `"streamed"`)
+		w.(http.Flusher).Flush()
+		w.Header().Set("X-Checksum", "abc123")
+		w.Header().Set("X-Status", "complete")
+	}))
+	defer srv.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+	cli := NewClient()
+
+	var trailer http.Header
+	var called bool
+	err := cli.Get(ctx, srv.URL, WithResponseTrailers(&trailer), WithResponseCallback(func(status int, header http.Header, body io.Reader) error {
+		// Only the first bytes are read; the rest is drained to reach the trailers.
+		called = true
+		_, err := body.Read(make([]byte, 1))
+		return err
+	}))
+	if err != nil || !called {
+		t.Fatalf("Get() error = %v, callback called = %v", err, called)
+	}
+	if trailer.Get("X-Checksum") != "abc123" || trailer.Get("X-Status") != "complete" {
+		t.Errorf("Get() trailers = %v, want X-Checksum and X-Status", trailer)
+	}
+
+	trailer = nil
+	err = cli.Get(ctx, srv.URL+"/fail", WithResponseTrailers(&trailer))
+	var bse *BadStatusError
+	if !errors.As(err, &bse) || trailer.Get("X-Status") != "complete" {
+		t.Errorf("Get() = %v with trailers %v, want a BadStatusError with trailers", err, trailer)
+	}
+}
+
+func TestGet_tee_response(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		io.WriteString(w, `{"a":"b"}`)
+	}))
+	defer srv.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+	cli := NewClient()
+
+	var got map[string]string
+	var audit, archive bytes.Buffer
+	if err := cli.Get(ctx, srv.URL, WithJSONResponse(&got), WithTeeResponse(&audit), WithTeeResponse(&archive)); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if got["a"] != "b" || audit.String() != `{"a":"b"}` || archive.String() != `{"a":"b"}` {
+		t.Errorf("Get() = %v, teed %q and %q, want the body decoded and teed twice", got, audit.String(), archive.String())
+	}
+
+	audit.Reset()
+	if err := cli.Get(ctx, srv.URL+"/fail", WithTeeResponse(&audit)); err == nil || audit.String() != `{"a":"b"}` {
+		t.Errorf("Get() = %v, teed %q, want a BadStatusError with the error body teed", err, audit.String())
+	}
+
+	err := cli.Get(ctx, srv.URL, WithJSONResponse(&got), WithTeeResponse(&failingWriter{limit: 2}))
+	if !errors.Is(err, errWriterFull) {
+		t.Errorf("Get() error = %v, want %v", err, errWriterFull)
+	}
+}
+
+func TestGet_error_body(t *testing.T) {
+	t.Parallel()
+	trace := "<html>" + strings.Repeat("at frame\n", 10000) + "</html>"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		io.WriteString(w, trace)
+	}))
+	defer srv.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	var errBody bytes.Buffer
+	err := NewClient().Get(ctx, srv.URL, WithErrorBody(&errBody))
+	var bse *BadStatusError
+	if !errors.As(err, &bse) || bse.Code != http.StatusInternalServerError {
+		t.Fatalf("Get() error = %v, want a 500 BadStatusError", err)
+	}
+	if errBody.String() != trace {
+		t.Errorf("WithErrorBody writer got %d bytes, want %d", errBody.Len(), len(trace))
+	}
+	if string(bse.Body) != trace[:errorBodyPrefixBytes] {
+		t.Errorf("BadStatusError.Body has %d bytes, want the first %d", len(bse.Body), errorBodyPrefixBytes)
+	}
+}
+
+func TestGet_success_check(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/partial" {
+			w.WriteHeader(http.StatusMultiStatus)
+		} else if r.URL.Path == "/warning" {
+			w.Header().Set("X-Gateway-Status", "degraded")
+		}
+		io.WriteString(w, `{"a":"b"}`)
+	}))
+	defer srv.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+	cli := NewClient()
+
+	check := WithSuccessCheck(func(status int, header http.Header) bool {
+		return status != http.StatusMultiStatus && header.Get("X-Gateway-Status") == "" && DefaultSuccessCheck(status, header)
+	})
+	var bse *BadStatusError
+	for _, path := range []string{"/partial", "/warning"} {
+		if err := cli.Get(ctx, srv.URL+path, check); !errors.As(err, &bse) || string(bse.Body) != `{"a":"b"}` {
+			t.Errorf("Get(%s) error = %v, want a BadStatusError with the body", path, err)
+		}
+	}
+
+	var got map[string]string
+	if err := cli.Get(ctx, srv.URL, check, WithJSONResponse(&got)); err != nil || got["a"] != "b" {
+		t.Errorf("Get() = %v, %v, want the decoded body", got, err)
+	}
+}
+
+func TestGet_raw_response(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+		io.WriteString(w, "raw body")
+	}))
+	defer srv.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	var resp *http.Response
+	if err := NewClient().Get(ctx, srv.URL, WithRawResponse(&resp)); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil || resp.StatusCode != http.StatusTeapot || string(body) != "raw body" {
+		t.Errorf("Get() response = %d %q, %v, want the unread 418 response", resp.StatusCode, body, err)
+	}
+
+	var got map[string]string
+	if err := NewClient().Get(ctx, srv.URL, WithRawResponse(&resp), WithJSONResponse(&got)); !errors.Is(err, ErrConflictingOptions) {
+		t.Errorf("Get() error = %v, want %v", err, ErrConflictingOptions)
+	}
+}
+
+func TestMockClient_raw_response(t *testing.T) {
+	t.Parallel()
+	cli := NewMockClient(func(ctx context.Context, r *Request) error {
+		*r.RawResponse = &http.Response{
+			StatusCode: http.StatusSwitchingProtocols,
+			Header:     http.Header{"Upgrade": {"websocket"}},
+			Body:       ioutil.NopCloser(strings.NewReader("")),
+		}
+		return nil
+	})
+
+	var resp *http.Response
+	if err := cli.Get(context.Background(), "/ws", WithRawResponse(&resp)); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols || resp.Header.Get("Upgrade") != "websocket" {
+		t.Errorf("Get() response = %+v, want the fabricated upgrade", resp)
+	}
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+func TestNewClient_options(t *testing.T) {
+	t.Parallel()
+	var mu sync.Mutex
+	var got http.Header
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		got = r.Header.Clone()
+		mu.Unlock()
+		if r.URL.Path == "/slow" {
+			time.Sleep(200 * time.Millisecond)
+		}
+	}))
+	defer srv.Close()
+	ctx := context.Background()
+
+	var roundTrips int32
+	transport := srv.Client().Transport.(*http.Transport)
+	counting := roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		atomic.AddInt32(&roundTrips, 1)
+		return transport.RoundTrip(r)
+	})
+
+	cli := NewClient(
+		WithTransport(counting),
+		WithTimeout(50*time.Millisecond),
+		WithDefaultHeader("X-Team", "payments"),
+		WithDefaultHeader("X-Team", "billing"),
+		WithDefaultHeader("User-Agent", "gohttp-test"),
+	)
+	if err := cli.Get(ctx, srv.URL, WithHeader("X-Team", "ledger")); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	mu.Lock()
+	if strings.Join(got["X-Team"], ",") != "ledger" || got.Get("User-Agent") != "gohttp-test" {
+		t.Errorf("headers = %v, want default headers replaced by request ones", got)
+	}
+	mu.Unlock()
+	if atomic.LoadInt32(&roundTrips) != 1 {
+		t.Errorf("round trips = %d, want 1 through WithTransport", roundTrips)
+	}
+
+	// Default headers are copied into each request, not replaced in the client.
+	if err := cli.Get(ctx, srv.URL); err != nil {
+		t.Fatalf("second Get() error = %v", err)
+	}
+	mu.Lock()
+	if strings.Join(got["X-Team"], ",") != "payments,billing" {
+		t.Errorf("second request X-Team = %q, want the defaults only", got["X-Team"])
+	}
+	mu.Unlock()
+
+	if err := cli.Get(ctx, srv.URL+"/slow"); err == nil || !strings.Contains(err.Error(), "Client.Timeout") {
+		t.Errorf("Get() of slow endpoint error = %v, want timeout", err)
+	}
+}
+
+func TestNewClient_tls_config_composition(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	ctx := context.Background()
+	config := srv.Client().Transport.(*http.Transport).TLSClientConfig
+
+	// The TLS config applies to a copy of WithTransport, whichever option comes first.
+	base := &http.Transport{MaxIdleConnsPerHost: 7}
+	for _, opts := range [][]ClientOption{
+		{WithTransport(base), WithTLSConfig(config)},
+		{WithTLSConfig(config), WithTransport(base)},
+	} {
+		cli := NewClient(opts...)
+		if err := cli.Get(ctx, srv.URL); err != nil {
+			t.Errorf("Get() error = %v", err)
+		}
+		if tr := cli.(*client).client.Transport.(*http.Transport); tr == base || tr.MaxIdleConnsPerHost != 7 {
+			t.Errorf("transport = %p with MaxIdleConnsPerHost %d, want a copy of %p", tr, tr.MaxIdleConnsPerHost, base)
+		}
+	}
+
+	if err := NewTLSClient(config).Get(ctx, srv.URL); err != nil {
+		t.Errorf("NewTLSClient Get() error = %v", err)
+	}
+
+	defer func() {
+		if p := recover(); p == nil || !strings.Contains(fmt.Sprint(p), "WithTLSConfig requires an *http.Transport") {
+			t.Errorf("NewClient() panic = %v, want WithTLSConfig requirement", p)
+		}
+	}()
+	NewClient(WithTransport(roundTripperFunc(http.DefaultTransport.RoundTrip)), WithTLSConfig(config))
+}
+
+func TestNewClient_default_header_concurrent(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.Join(r.Header["X-Tenant-Id"], ",")))
+	}))
+	defer srv.Close()
+	cli := NewClient(WithDefaultHeader("X-Tenant-ID", "default"))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			tenant, want := strconv.Itoa(i), strconv.Itoa(i)+","+strconv.Itoa(i+1)
+			if i%2 == 0 {
+				tenant, want = "", "default"
+			}
+			var got string
+			opts := []RequestOption{WithStringResponse(&got)}
+			if tenant != "" {
+				opts = append(opts, WithHeader("X-Tenant-ID", tenant), WithHeader("X-Tenant-ID", strconv.Itoa(i+1)))
+			}
+			if err := cli.Get(context.Background(), srv.URL, opts...); err != nil || got != want {
+				t.Errorf("Get() = %q, %v, want %q", got, err, want)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestMockClient_default_header(t *testing.T) {
+	t.Parallel()
+	var got http.Header
+	cli := NewMockClient(func(ctx context.Context, r *Request) error {
+		got = r.Header
+		return nil
+	}, WithDefaultHeader("User-Agent", "gohttp-test"), WithDefaultHeader("X-Tenant-ID", "default"))
+
+	if err := cli.Get(context.Background(), "http://example.com", WithHeader("X-Tenant-ID", "acme")); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if got.Get("User-Agent") != "gohttp-test" || strings.Join(got["X-Tenant-Id"], ",") != "acme" {
+		t.Errorf("headers = %v, want default User-Agent and request X-Tenant-ID", got)
+	}
+}
+
+func TestNewClient_http_client(t *testing.T) {
+	t.Parallel()
+	var tenant string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tenant = r.Header.Get("X-Tenant-ID")
+		http.Redirect(w, r, "/elsewhere", http.StatusFound)
+	}))
+	defer srv.Close()
+
+	errRedirect := errors.New("redirect refused")
+	hc := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return errRedirect }}
+	cli := NewClient(WithHTTPClient(hc), WithDefaultHeader("X-Tenant-ID", "acme"), WithTimeout(time.Second))
+	if err := cli.Get(context.Background(), srv.URL); !errors.Is(err, errRedirect) {
+		t.Errorf("Get() error = %v, want %v from CheckRedirect", err, errRedirect)
+	}
+	if tenant != "acme" {
+		t.Errorf("X-Tenant-ID = %q, want the default header", tenant)
+	}
+	if hc.Timeout != 0 {
+		t.Errorf("WithTimeout modified the WithHTTPClient client")
+	}
+
+	defer func() {
+		if p := recover(); p == nil || !strings.Contains(fmt.Sprint(p), "WithHTTPClient and WithTransport conflict") {
+			t.Errorf("NewClient() panic = %v, want conflict", p)
+		}
+	}()
+	NewClient(WithHTTPClient(hc), WithTransport(http.DefaultTransport))
+}
+
+func TestNewClient_transport(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Header.Get("X-Stamp")))
+	}))
+	defer srv.Close()
+
+	stamping := roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		r = r.Clone(r.Context())
+		r.Header.Set("X-Stamp", "instrumented")
+		return http.DefaultTransport.RoundTrip(r)
+	})
+	var got string
+	if err := NewClient(WithTransport(stamping)).Get(context.Background(), srv.URL, WithStringResponse(&got)); err != nil || got != "instrumented" {
+		t.Errorf("Get() = %q, %v, want the header stamped by the transport", got, err)
+	}
+}
+
+func TestNewClient_timeout(t *testing.T) {
+	t.Parallel()
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+	defer close(release)
+	cli := NewClient(WithTimeout(50 * time.Millisecond))
+
+	var ne net.Error
+	if err := cli.Get(context.Background(), srv.URL); !errors.As(err, &ne) || !ne.Timeout() {
+		t.Errorf("Get() error = %v, want a net.Error timeout", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := cli.Get(ctx, srv.URL); !errors.Is(err, context.DeadlineExceeded) || strings.Contains(err.Error(), "Client.Timeout") {
+		t.Errorf("Get() with earlier context deadline error = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
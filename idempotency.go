@@ -0,0 +1,43 @@
+package http
+
+import (
+	"crypto/rand"
+	"fmt"
+)
+
+//
+// WithIdempotencyKey will send key in the Idempotency-Key header, letting
+// a server that supports it recognize a request it already handled. The
+// header also lets the WithRetry policy retry the request whatever its
+// method.
+//
+func WithIdempotencyKey(key string) RequestOption {
+	return func(r *Request) {
+		r.Header.Set("Idempotency-Key", key)
+	}
+}
+
+//
+// WithAutoIdempotencyKey will send a random UUID in the Idempotency-Key
+// header, as WithIdempotencyKey does.
+//
+// The key is generated once for each call, so every retry of the call
+// sends the same one while separate calls, even with the same options,
+// send different ones.
+//
+func WithAutoIdempotencyKey() RequestOption {
+	return func(r *Request) {
+		r.Header.Set("Idempotency-Key", newUUID())
+	}
+}
+
+// newUUID returns a random (version 4) UUID.
+func newUUID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic("generating UUID: " + err.Error())
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
@@ -0,0 +1,64 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sync"
+	"testing"
+)
+
+func TestWithAutoIdempotencyKey(t *testing.T) {
+	t.Parallel()
+	var mu sync.Mutex
+	var keys []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		keys = append(keys, r.Header.Get("Idempotency-Key"))
+		if len(keys)%3 != 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+	c := newClient(http.Client{}, []ClientOption{WithRetry(RetryPolicy{MaxAttempts: 3})})
+	fakeSleeper(c)
+
+	option := WithAutoIdempotencyKey()
+	for i := 0; i < 2; i++ {
+		if err := c.Post(context.Background(), srv.URL, option, WithJSONBody("charge")); err != nil {
+			t.Fatalf("Post() error = %v", err)
+		}
+	}
+
+	uuid := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	if len(keys) != 6 {
+		t.Fatalf("server got %d attempts, want 6: %q", len(keys), keys)
+	}
+	for i, key := range keys {
+		if !uuid.MatchString(key) {
+			t.Errorf("attempt %d sent Idempotency-Key %q, want a UUIDv4", i+1, key)
+		}
+		if first := keys[i/3*3]; key != first {
+			t.Errorf("attempt %d sent Idempotency-Key %q, want %q as the first attempt of its call", i+1, key, first)
+		}
+	}
+	if keys[0] == keys[3] {
+		t.Errorf("both calls sent Idempotency-Key %q, want different keys", keys[0])
+	}
+}
+
+func TestWithIdempotencyKey(t *testing.T) {
+	t.Parallel()
+	var keys []string
+	c := NewMockClient(func(ctx context.Context, req *Request) error {
+		keys = append(keys, req.Header.Get("Idempotency-Key"))
+		return nil
+	})
+	c.Post(context.Background(), "http://example.com", WithIdempotencyKey("order-42"))
+	c.Post(context.Background(), "http://example.com", WithIdempotencyKey("order-42"))
+	if len(keys) != 2 || keys[0] != "order-42" || keys[1] != "order-42" {
+		t.Errorf("Idempotency-Key headers = %q, want order-42 twice", keys)
+	}
+}
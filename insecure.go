@@ -0,0 +1,38 @@
+package http
+
+import (
+	"crypto/tls"
+	"net/http"
+)
+
+//
+// WithInsecureTLS will accept any server certificate, skipping
+// verification, for development against self-signed staging endpoints. It
+// applies on top of NewTLSClient and WithTLSConfig, and a warning is logged
+// through WithLogger when the client is constructed.
+//
+// Never use it in production: anyone on the network path can impersonate the
+// server. IsInsecure reports clients using it, for review tooling.
+//
+func WithInsecureTLS() ClientOption {
+	configure := configureTLS("WithInsecureTLS", func(config *tls.Config) {
+		config.InsecureSkipVerify = true
+	})
+	return func(c *clientConfig) {
+		configure(c)
+		c.insecureTLS = true
+	}
+}
+
+// IsInsecure reports whether c skips TLS certificate verification, because of WithInsecureTLS or its TLS config.
+func IsInsecure(c Client) bool {
+	cl, ok := c.(*client)
+	if !ok {
+		return false
+	}
+	if cl.config.insecureTLS {
+		return true
+	}
+	t, ok := networkTransport(cl.client.Transport).(*http.Transport)
+	return ok && t.TLSClientConfig != nil && t.TLSClientConfig.InsecureSkipVerify
+}
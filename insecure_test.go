@@ -0,0 +1,53 @@
+package http
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWithInsecureTLS(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	ctx := context.Background()
+
+	secure := NewClient()
+	if err := secure.Get(ctx, srv.URL); err == nil {
+		t.Errorf("Get() of untrusted server error = nil, want certificate error")
+	}
+	if IsInsecure(secure) {
+		t.Errorf("IsInsecure(NewClient()) = true")
+	}
+
+	var logs []string
+	logf := func(format string, args ...interface{}) { logs = append(logs, fmt.Sprintf(format, args...)) }
+	for name, cli := range map[string]Client{
+		"default":    NewClient(WithInsecureTLS(), WithLogger(logf)),
+		"TLS client": NewTLSClient(&tls.Config{MinVersion: tls.VersionTLS12}, WithInsecureTLS(), WithLogger(logf)),
+	} {
+		if err := cli.Get(ctx, srv.URL); err != nil {
+			t.Errorf("%s: Get() error = %v", name, err)
+		}
+		if !IsInsecure(cli) {
+			t.Errorf("%s: IsInsecure() = false", name)
+		}
+	}
+	if len(logs) != 2 || !strings.Contains(logs[0], "WARNING: TLS certificate verification is disabled") {
+		t.Errorf("logged %q, want a warning per client", logs)
+	}
+
+	if !IsInsecure(NewTLSClient(&tls.Config{InsecureSkipVerify: true})) {
+		t.Errorf("IsInsecure() of a hand-rolled InsecureSkipVerify config = false")
+	}
+	if !IsInsecure(NewTLSClient(&tls.Config{InsecureSkipVerify: true}, WithHSTS(), WithFaultMap(NewFaultMap(t)))) {
+		t.Errorf("IsInsecure() of a hand-rolled InsecureSkipVerify config behind wrapping transports = false")
+	}
+	if IsInsecure(NewMockClient(func(context.Context, *Request) error { return nil })) {
+		t.Errorf("IsInsecure(mock) = true")
+	}
+}
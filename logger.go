@@ -0,0 +1,19 @@
+package http
+
+//
+// WithLogger will report noteworthy client events, such as warnings about
+// insecure options, to logf, e.g. log.Printf. Without it, the client logs
+// nothing.
+//
+func WithLogger(logf func(format string, args ...interface{})) ClientOption {
+	return func(c *clientConfig) {
+		c.logf = logf
+	}
+}
+
+// logf logs through the WithLogger function, if any.
+func (c *client) logf(format string, args ...interface{}) {
+	if c.config.logf != nil {
+		c.config.logf(format, args...)
+	}
+}
@@ -0,0 +1,22 @@
+// Package msgpack adds MessagePack support to the gohttp client.
+package msgpack
+
+import (
+	http "github.com/alphaflow/gohttp"
+	msgpackv5 "github.com/vmihailenco/msgpack/v5"
+)
+
+// ContentType is the media type of MessagePack documents.
+const ContentType = "application/msgpack"
+
+//
+// WithMsgpackResponse will MessagePack Unmarshal the successful HTTP response
+// body into o, sending ContentType as the Accept header.
+//
+// A body that does not decode, such as a JSON fallback, is reported as an
+// *http.DecodeError holding the Content-Type actually received. With
+// NewMockClient, o is the Request's DecodedOutput.
+//
+func WithMsgpackResponse(o interface{}) http.RequestOption {
+	return http.WithDecodedResponse("MessagePack", ContentType, o, msgpackv5.Unmarshal)
+}
@@ -0,0 +1,56 @@
+package msgpack
+
+import (
+	"context"
+	"errors"
+	nethttp "net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	http "github.com/alphaflow/gohttp"
+	msgpackv5 "github.com/vmihailenco/msgpack/v5"
+)
+
+type sample struct {
+	ID   int64    `msgpack:"id"`
+	Tags []string `msgpack:"tags"`
+}
+
+func TestWithMsgpackResponse_round_trip(t *testing.T) {
+	t.Parallel()
+	want := sample{ID: 1 << 40, Tags: []string{"a", "b"}}
+	srv := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		if got := r.Header.Get("Accept"); got != ContentType {
+			t.Errorf("Accept = %q, want %q", got, ContentType)
+		}
+		buf, _ := msgpackv5.Marshal(want)
+		w.Header().Set("Content-Type", ContentType)
+		w.Write(buf)
+	}))
+	defer srv.Close()
+
+	var got sample
+	if err := http.NewClient().Get(context.Background(), srv.URL, WithMsgpackResponse(&got)); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Get() = %+v, want %+v", got, want)
+	}
+}
+
+func TestWithMsgpackResponse_json_fallback(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id": 1, "tags": ["a"]}`))
+	}))
+	defer srv.Close()
+
+	var got sample
+	err := http.NewClient().Get(context.Background(), srv.URL, WithMsgpackResponse(&got))
+	var de *http.DecodeError
+	if !errors.As(err, &de) || de.ContentType != "application/json" {
+		t.Errorf("Get() error = %v, want a DecodeError for application/json", err)
+	}
+}
@@ -0,0 +1,55 @@
+package http
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+//
+// WithNDJSONResponse will decode a newline-delimited JSON response one record
+// at a time, unmarshaling each line into a value from newItem and passing it
+// to onItem.
+//
+// Only one record is held in memory at a time, whatever the length of the
+// stream. A UTF-8 byte order mark before the first line is skipped.
+// Decoding stops at the first malformed line or onItem error, and the error
+// names the line.
+//
+func WithNDJSONResponse(newItem func() interface{}, onItem func(interface{}) error) RequestOption {
+	return func(r *Request) {
+		url := r.URL
+		r.Header.Add("Accept", "application/x-ndjson")
+		r.ResponseCallback = func(status int, header http.Header, body io.Reader) error {
+			return decodeNDJSON(url, header.Get("Content-Type"), body, newItem, onItem)
+		}
+	}
+}
+
+func decodeNDJSON(url, contentType string, body io.Reader, newItem func() interface{}, onItem func(interface{}) error) error {
+	br := bufio.NewReader(body)
+	for line := 1; ; line++ {
+		buf, err := br.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+		if line == 1 {
+			buf = bytes.TrimPrefix(buf, utf8BOM)
+		}
+		if record := bytes.TrimSpace(buf); len(record) > 0 {
+			item := newItem()
+			if jsonErr := json.Unmarshal(record, item); jsonErr != nil {
+				return &DecodeError{Format: "NDJSON", URL: url, ContentType: contentType, Err: fmt.Errorf("line %d: %w", line, jsonErr)}
+			}
+			if cbErr := onItem(item); cbErr != nil {
+				return fmt.Errorf("NDJSON record on line %d of %s: %w", line, url, cbErr)
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+	}
+}
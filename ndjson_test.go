@@ -0,0 +1,106 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type ndjsonRecord struct {
+	N    int
+	Text string
+}
+
+func TestGet_ndjson(t *testing.T) {
+	t.Parallel()
+	long := strings.Repeat("x", 1<<20)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "{\"N\":1}\n\n{\"N\":2,\"Text\":\""+long+"\"}\r\n{\"N\":3}")
+	}))
+	defer srv.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	var got []int
+	err := NewClient().Get(ctx, srv.URL, WithNDJSONResponse(
+		func() interface{} { return &ndjsonRecord{} },
+		func(v interface{}) error {
+			rec := v.(*ndjsonRecord)
+			if rec.N == 2 && rec.Text != long {
+				t.Errorf("record 2 Text has %d bytes, want %d", len(rec.Text), len(long))
+			}
+			got = append(got, rec.N)
+			return nil
+		}))
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if want := []int{1, 2, 3}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Get() records = %v, want %v", got, want)
+	}
+}
+
+func TestDecodeNDJSON_errors(t *testing.T) {
+	t.Parallel()
+	newItem := func() interface{} { return &ndjsonRecord{} }
+
+	var n int
+	err := decodeNDJSON("http://example.com", "", strings.NewReader("{\"N\":1}\n{bad\n{\"N\":3}\n"), newItem, func(interface{}) error {
+		n++
+		return nil
+	})
+	var de *DecodeError
+	if !errors.As(err, &de) || !strings.Contains(err.Error(), "line 2") || n != 1 {
+		t.Errorf("decodeNDJSON() error = %v after %d records, want *DecodeError on line 2 after 1 record", err, n)
+	}
+
+	errStop := errors.New("stop")
+	err = decodeNDJSON("http://example.com", "", strings.NewReader("{\"N\":1}\n{\"N\":2}\n"), newItem, func(v interface{}) error {
+		if v.(*ndjsonRecord).N == 2 {
+			return errStop
+		}
+		return nil
+	})
+	if !errors.Is(err, errStop) || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("decodeNDJSON() error = %v, want %v on line 2", err, errStop)
+	}
+}
+
+func TestMockClient_ndjson(t *testing.T) {
+	t.Parallel()
+	cli := NewMockClient(func(ctx context.Context, r *Request) error {
+		return r.ResponseCallback(200, http.Header{}, strings.NewReader("{\"N\":1}\n{\"N\":2}\n"))
+	})
+
+	var got []int
+	err := cli.Get(context.Background(), "http://example.com", WithNDJSONResponse(
+		func() interface{} { return &ndjsonRecord{} },
+		func(v interface{}) error {
+			got = append(got, v.(*ndjsonRecord).N)
+			return nil
+		}))
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if want := []int{1, 2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Get() records = %v, want %v", got, want)
+	}
+}
+
+func TestDecodeNDJSON_bom(t *testing.T) {
+	t.Parallel()
+	var got []int
+	err := decodeNDJSON("http://example.com", "", strings.NewReader("\xef\xbb\xbf{\"N\":1}\n{\"N\":2}\n"), func() interface{} { return &ndjsonRecord{} }, func(v interface{}) error {
+		got = append(got, v.(*ndjsonRecord).N)
+		return nil
+	})
+	if want := []int{1, 2}; err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("decodeNDJSON() = %v, %v, want %v with the BOM skipped", got, err, want)
+	}
+}
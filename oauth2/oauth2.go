@@ -0,0 +1,48 @@
+// Package oauth2 lets the gohttp client authenticate with a golang.org/x/oauth2 TokenSource.
+package oauth2
+
+import (
+	"context"
+	"fmt"
+
+	http "github.com/alphaflow/gohttp"
+	xoauth2 "golang.org/x/oauth2"
+)
+
+//
+// WithOAuth2TokenSource will authenticate every request of the client with
+// an access token from ts, like http.WithTokenSource.
+//
+// Tokens are reused until they expire and then refreshed from ts. A request
+// whose context is done while a token is fetched returns the context error
+// instead of waiting for ts.
+//
+func WithOAuth2TokenSource(ts xoauth2.TokenSource) http.ClientOption {
+	ts = xoauth2.ReuseTokenSource(nil, ts)
+	return http.WithTokenSource(func(ctx context.Context) (string, error) {
+		return token(ctx, ts)
+	})
+}
+
+// token returns the access token of ts, giving up when ctx is done.
+func token(ctx context.Context, ts xoauth2.TokenSource) (string, error) {
+	type result struct {
+		tok *xoauth2.Token
+		err error
+	}
+	ch := make(chan result, 1)
+	go func() {
+		tok, err := ts.Token()
+		ch <- result{tok, err}
+	}()
+
+	select {
+	case <-ctx.Done():
+		return "", ctx.Err()
+	case r := <-ch:
+		if r.err != nil {
+			return "", fmt.Errorf("oauth2 token: %w", r.err)
+		}
+		return r.tok.AccessToken, nil
+	}
+}
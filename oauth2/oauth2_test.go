@@ -0,0 +1,90 @@
+package oauth2
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	nethttp "net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	http "github.com/alphaflow/gohttp"
+	xoauth2 "golang.org/x/oauth2"
+)
+
+// fakeTokenSource issues numbered tokens expiring after ttl.
+type fakeTokenSource struct {
+	mu    sync.Mutex
+	n     int
+	ttl   time.Duration
+	block chan struct{}
+	err   error
+}
+
+func (f *fakeTokenSource) Token() (*xoauth2.Token, error) {
+	if f.block != nil {
+		<-f.block
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return nil, f.err
+	}
+	f.n++
+	return &xoauth2.Token{AccessToken: fmt.Sprintf("token-%d", f.n), Expiry: time.Now().Add(f.ttl)}, nil
+}
+
+func newAuthEchoServer(t *testing.T) *httptest.Server {
+	srv := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		w.Write([]byte(r.Header.Get("Authorization")))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestWithOAuth2TokenSource_refresh(t *testing.T) {
+	t.Parallel()
+	srv := newAuthEchoServer(t)
+	ctx := context.Background()
+
+	// Tokens within the refresh margin of their expiry count as expired.
+	expiring := &fakeTokenSource{ttl: time.Second}
+	cli := http.NewClient(WithOAuth2TokenSource(expiring))
+	for _, want := range []string{"Bearer token-1", "Bearer token-2"} {
+		var got string
+		if err := cli.Get(ctx, srv.URL, http.WithStringResponse(&got)); err != nil || got != want {
+			t.Errorf("Get() = %q, %v, want %q", got, err, want)
+		}
+	}
+
+	valid := &fakeTokenSource{ttl: time.Hour}
+	cli = http.NewClient(WithOAuth2TokenSource(valid))
+	for i := 0; i < 2; i++ {
+		var got string
+		if err := cli.Get(ctx, srv.URL, http.WithStringResponse(&got)); err != nil || got != "Bearer token-1" {
+			t.Errorf("Get() = %q, %v, want the reused token-1", got, err)
+		}
+	}
+}
+
+func TestWithOAuth2TokenSource_errors(t *testing.T) {
+	t.Parallel()
+	srv := newAuthEchoServer(t)
+
+	errDenied := errors.New("invalid_client")
+	cli := http.NewClient(WithOAuth2TokenSource(&fakeTokenSource{err: errDenied}))
+	if err := cli.Get(context.Background(), srv.URL); !errors.Is(err, errDenied) {
+		t.Errorf("Get() error = %v, want %v", err, errDenied)
+	}
+
+	block := make(chan struct{})
+	defer close(block)
+	cli = http.NewClient(WithOAuth2TokenSource(&fakeTokenSource{ttl: time.Hour, block: block}))
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := cli.Get(ctx, srv.URL); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Get() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
@@ -0,0 +1,286 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"sync/atomic"
+)
+
+// PipeSource is the GET request whose response body Pipe reads.
+type PipeSource struct {
+	URL     string
+	Options []RequestOption
+}
+
+// PipeDest is the request that Pipe sends the body to.
+type PipeDest struct {
+	// Method defaults to PUT.
+	Method  string
+	URL     string
+	Options []RequestOption
+}
+
+// PipeError is returned by Pipe, naming the side of the pipe that failed.
+type PipeError struct {
+	// Side is "source" or "destination".
+	Side string
+	Err  error
+}
+
+func (pe *PipeError) Error() string {
+	return fmt.Sprintf("pipe %s: %v", pe.Side, pe.Err)
+}
+
+func (pe *PipeError) Unwrap() error {
+	return pe.Err
+}
+
+// PipeOption controls the behavior of Pipe.
+type PipeOption func(*pipeConfig)
+
+type pipeConfig struct {
+	checksum hash.Hash
+	bytes    *int64
+	spool    int64
+	retries  int
+}
+
+// WithPipeChecksum will write every byte sent to the destination into h.
+func WithPipeChecksum(h hash.Hash) PipeOption {
+	return func(c *pipeConfig) {
+		c.checksum = h
+	}
+}
+
+// WithPipeBytes will store the number of bytes sent to the destination.
+func WithPipeBytes(n *int64) PipeOption {
+	return func(c *pipeConfig) {
+		c.bytes = n
+	}
+}
+
+//
+// WithPipeSpool will buffer bodies of up to threshold bytes in memory before
+// sending them to the destination, which allows the destination request to
+// be retried.
+//
+// Larger bodies are streamed and never retried.
+//
+func WithPipeSpool(threshold int64) PipeOption {
+	return func(c *pipeConfig) {
+		c.spool = threshold
+	}
+}
+
+//
+// WithPipeRetries will retry a spooled destination request up to n times
+// after transport errors and 5xx responses.
+//
+// It requires WithPipeSpool.
+//
+func WithPipeRetries(n int) PipeOption {
+	return func(c *pipeConfig) {
+		c.retries = n
+	}
+}
+
+//
+// Pipe streams the response body of a GET to from into the body of a request
+// to to, with backpressure between them.
+//
+// The Content-Length of the source is forwarded when it is known. Failure on
+// either side cancels the other, and the returned *PipeError names the side
+// that failed first.
+//
+func Pipe(ctx context.Context, c Client, from PipeSource, to PipeDest, opts ...PipeOption) error {
+	var cfg pipeConfig
+	for _, o := range opts {
+		o(&cfg)
+	}
+	if cfg.retries > 0 && cfg.spool <= 0 {
+		return fmt.Errorf("%w: WithPipeRetries requires WithPipeSpool", ErrInvalidOption)
+	}
+	if to.Method == "" {
+		to.Method = "PUT"
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	src := startPipeSource(ctx, c, from)
+	defer func() {
+		// Unblock the source if the pipe ended early, and wait for it to return.
+		cancel()
+		src.reader.Close()
+		src.wait()
+	}()
+
+	length, err := src.started()
+	if err != nil {
+		return &PipeError{Side: "source", Err: err}
+	}
+
+	var body io.Reader = src.reader
+	if cfg.spool > 0 && (length < 0 || length <= cfg.spool) {
+		buf, err := ioutil.ReadAll(io.LimitReader(src.reader, cfg.spool+1))
+		if err != nil {
+			return &PipeError{Side: "source", Err: src.errOr(err)}
+		}
+		if int64(len(buf)) <= cfg.spool {
+			if err := src.wait(); err != nil {
+				return &PipeError{Side: "source", Err: err}
+			}
+			return cfg.sendSpooled(ctx, c, to, buf)
+		}
+		body = io.MultiReader(bytes.NewReader(buf), src.reader)
+	}
+
+	counter := countingReader{r: body}
+	body = &counter
+	if cfg.checksum != nil {
+		body = io.TeeReader(body, cfg.checksum)
+	}
+	err = c.Do(ctx, to.Method, to.URL, append(to.Options[:len(to.Options):len(to.Options)], WithBodyReader(body, length))...)
+	if cfg.bytes != nil {
+		*cfg.bytes = atomic.LoadInt64(&counter.n)
+	}
+	if err != nil {
+		if srcErr := src.failure(); srcErr != nil {
+			return &PipeError{Side: "source", Err: srcErr}
+		}
+		return &PipeError{Side: "destination", Err: err}
+	}
+	if err := src.wait(); err != nil {
+		return &PipeError{Side: "source", Err: err}
+	}
+	return nil
+}
+
+func (cfg *pipeConfig) sendSpooled(ctx context.Context, c Client, to PipeDest, buf []byte) error {
+	if cfg.checksum != nil {
+		cfg.checksum.Write(buf)
+	}
+	if cfg.bytes != nil {
+		*cfg.bytes = int64(len(buf))
+	}
+
+	var err error
+	for attempt := 0; attempt <= cfg.retries; attempt++ {
+		err = c.Do(ctx, to.Method, to.URL, append(to.Options[:len(to.Options):len(to.Options)], WithBodyReader(bytes.NewReader(buf), int64(len(buf))))...)
+		if !retryable(ctx, err) {
+			break
+		}
+	}
+	if err != nil {
+		return &PipeError{Side: "destination", Err: err}
+	}
+	return nil
+}
+
+// pipeSource runs the source GET, writing its body into a pipe.
+type pipeSource struct {
+	reader *io.PipeReader
+	writer *io.PipeWriter
+
+	start     sync.Once
+	startedCh chan int64
+	done      chan struct{}
+	err       error
+}
+
+func startPipeSource(ctx context.Context, c Client, from PipeSource) *pipeSource {
+	pr, pw := io.Pipe()
+	src := &pipeSource{
+		reader:    pr,
+		writer:    pw,
+		startedCh: make(chan int64, 1),
+		done:      make(chan struct{}),
+	}
+
+	options := append(from.Options[:len(from.Options):len(from.Options)],
+		WithResponse(src),
+		func(r *Request) {
+			r.onResponse = func(resp *http.Response) {
+				src.markStarted(resp.ContentLength)
+			}
+		})
+	go func() {
+		src.err = c.Get(ctx, from.URL, options...)
+		// Mark the source done before the reader sees its error, so that
+		// a destination failing on that error is blamed on the source.
+		close(src.done)
+		pw.CloseWithError(src.err)
+	}()
+	return src
+}
+
+func (src *pipeSource) markStarted(length int64) {
+	src.start.Do(func() {
+		src.startedCh <- length
+	})
+}
+
+// Write implements io.Writer for the source response body.
+func (src *pipeSource) Write(p []byte) (int, error) {
+	// Clients that never report response headers, such as the mock, start on the first write.
+	src.markStarted(-1)
+	return src.writer.Write(p)
+}
+
+// started waits for the source response, returning its Content-Length or the error of the GET.
+func (src *pipeSource) started() (int64, error) {
+	select {
+	case length := <-src.startedCh:
+		return length, nil
+	case <-src.done:
+		select {
+		case length := <-src.startedCh:
+			return length, nil
+		default:
+		}
+		if src.err != nil {
+			return 0, src.err
+		}
+		// An empty body never writes.
+		return 0, nil
+	}
+}
+
+// wait waits for the source GET to finish and returns its error.
+func (src *pipeSource) wait() error {
+	<-src.done
+	return src.err
+}
+
+// failure returns the error of the source GET if it has already failed.
+func (src *pipeSource) failure() error {
+	select {
+	case <-src.done:
+		return src.err
+	default:
+		return nil
+	}
+}
+
+// errOr prefers the error of the source GET over err, an error reading the pipe.
+func (src *pipeSource) errOr(err error) error {
+	if srcErr := src.wait(); srcErr != nil {
+		return srcErr
+	}
+	return err
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	atomic.AddInt64(&cr.n, int64(n))
+	return n, err
+}
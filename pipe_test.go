@@ -0,0 +1,168 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestPipe(t *testing.T) {
+	t.Parallel()
+	payload := bytes.Repeat([]byte("0123456789abcdef"), 1<<16)
+	source := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", strconv.Itoa(len(payload)))
+		w.Write(payload)
+	}))
+	defer source.Close()
+
+	var received []byte
+	var receivedLength int64
+	dest := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "PUT" {
+			t.Errorf("destination method = %s, want PUT", r.Method)
+		}
+		receivedLength = r.ContentLength
+		received, _ = ioutil.ReadAll(r.Body)
+	}))
+	defer dest.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	checksum := sha256.New()
+	var n int64
+	err := Pipe(ctx, NewClient(), PipeSource{URL: source.URL}, PipeDest{URL: dest.URL},
+		WithPipeChecksum(checksum), WithPipeBytes(&n))
+	if err != nil {
+		t.Fatalf("Pipe() error = %v", err)
+	}
+
+	want := sha256.Sum256(payload)
+	got := sha256.Sum256(received)
+	if !bytes.Equal(checksum.Sum(nil), want[:]) || got != want {
+		t.Errorf("checksums: pipe %x, destination %x, want %x", checksum.Sum(nil), got, want)
+	}
+	if n != int64(len(payload)) || receivedLength != int64(len(payload)) {
+		t.Errorf("transferred %d bytes with Content-Length %d, want %d", n, receivedLength, len(payload))
+	}
+}
+
+func TestPipe_sourceFailure(t *testing.T) {
+	t.Parallel()
+	source := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "1000000")
+		w.Write(make([]byte, 100000))
+		panic(http.ErrAbortHandler)
+	}))
+	defer source.Close()
+	dest := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ioutil.ReadAll(r.Body)
+	}))
+	defer dest.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err := Pipe(ctx, NewClient(), PipeSource{URL: source.URL}, PipeDest{URL: dest.URL})
+	var pe *PipeError
+	if !errors.As(err, &pe) || pe.Side != "source" {
+		t.Errorf("Pipe() error = %v, want source *PipeError", err)
+	}
+}
+
+func TestPipe_destinationRejects(t *testing.T) {
+	t.Parallel()
+	sourceDone := make(chan struct{})
+	source := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer close(sourceDone)
+		chunk := make([]byte, 32<<10)
+		for {
+			if _, err := w.Write(chunk); err != nil {
+				return
+			}
+		}
+	}))
+	defer source.Close()
+	dest := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer dest.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err := Pipe(ctx, NewClient(), PipeSource{URL: source.URL}, PipeDest{Method: "POST", URL: dest.URL})
+	var pe *PipeError
+	var bse *BadStatusError
+	if !errors.As(err, &pe) || pe.Side != "destination" || !errors.As(err, &bse) || bse.Code != http.StatusForbidden {
+		t.Errorf("Pipe() error = %v, want destination *PipeError with 403", err)
+	}
+	select {
+	case <-sourceDone:
+	case <-time.After(1 * time.Second):
+		t.Errorf("source request was not aborted")
+	}
+}
+
+func TestPipe_spoolRetries(t *testing.T) {
+	t.Parallel()
+	source := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("small body"))
+	}))
+	defer source.Close()
+	attempts := 0
+	dest := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if body, _ := ioutil.ReadAll(r.Body); string(body) != "small body" {
+			t.Errorf("destination body = %q", body)
+		}
+		if attempts < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer dest.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := Pipe(ctx, NewClient(), PipeSource{URL: source.URL}, PipeDest{URL: dest.URL}); err == nil {
+		t.Errorf("Pipe() without retries succeeded against a failing destination")
+	}
+	attempts = 0
+	if err := Pipe(ctx, NewClient(), PipeSource{URL: source.URL}, PipeDest{URL: dest.URL}, WithPipeRetries(2)); !errors.Is(err, ErrInvalidOption) {
+		t.Errorf("Pipe() error = %v, want %v for retries without spool", err, ErrInvalidOption)
+	}
+	if err := Pipe(ctx, NewClient(), PipeSource{URL: source.URL}, PipeDest{URL: dest.URL}, WithPipeSpool(1024), WithPipeRetries(2)); err != nil {
+		t.Errorf("Pipe() error = %v", err)
+	}
+	if attempts != 3 {
+		t.Errorf("destination saw %d attempts, want 3", attempts)
+	}
+}
+
+func TestPipe_mock(t *testing.T) {
+	t.Parallel()
+	var received string
+	cli := NewMockClient(func(ctx context.Context, r *Request) error {
+		switch r.Method {
+		case "GET":
+			_, err := r.Output.Write([]byte("from mock"))
+			return err
+		default:
+			body, err := ioutil.ReadAll(r.BodyReader)
+			received = string(body)
+			return err
+		}
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := Pipe(ctx, cli, PipeSource{URL: "http://a"}, PipeDest{URL: "http://b"}); err != nil {
+		t.Fatalf("Pipe() error = %v", err)
+	}
+	if received != "from mock" {
+		t.Errorf("destination received %q", received)
+	}
+}
@@ -0,0 +1,77 @@
+package http
+
+import (
+	"net/http"
+	"time"
+)
+
+//
+// WithMaxIdleConns limits the idle connections kept open for reuse across
+// all hosts, 100 for http.DefaultTransport. Zero means no limit.
+//
+// This option and the other connection pool options configure a copy of the
+// transport: that of NewTLSClient or WithTransport, or else
+// http.DefaultTransport. With WithTransport, it must be an *http.Transport.
+//
+func WithMaxIdleConns(n int) ClientOption {
+	return configureTransport("WithMaxIdleConns", func(t *http.Transport) {
+		t.MaxIdleConns = n
+	})
+}
+
+//
+// WithMaxIdleConnsPerHost limits the idle connections kept open for reuse
+// per host. The default of 2 causes connection churn when more requests to
+// one host run concurrently.
+//
+func WithMaxIdleConnsPerHost(n int) ClientOption {
+	return configureTransport("WithMaxIdleConnsPerHost", func(t *http.Transport) {
+		t.MaxIdleConnsPerHost = n
+	})
+}
+
+// WithMaxConnsPerHost limits the connections per host, in use or idle; requests beyond it wait. Zero means no limit.
+func WithMaxConnsPerHost(n int) ClientOption {
+	return configureTransport("WithMaxConnsPerHost", func(t *http.Transport) {
+		t.MaxConnsPerHost = n
+	})
+}
+
+// WithIdleConnTimeout closes idle connections after d, 90 seconds for http.DefaultTransport. Zero means never.
+func WithIdleConnTimeout(d time.Duration) ClientOption {
+	return configureTransport("WithIdleConnTimeout", func(t *http.Transport) {
+		t.IdleConnTimeout = d
+	})
+}
+
+type transportOption struct {
+	name  string
+	apply func(*http.Transport)
+}
+
+// configureTransport returns an option making newClient apply configure to its transport.
+func configureTransport(name string, configure func(*http.Transport)) ClientOption {
+	return func(c *clientConfig) {
+		c.transportOptions = append(c.transportOptions, transportOption{name: name, apply: configure})
+	}
+}
+
+// useTransportOptions applies the transport options to a copy of the transport of c.
+func (c *client) useTransportOptions(options []transportOption) {
+	t := c.cloneTransport(options[0].name)
+	for _, o := range options {
+		o.apply(t)
+	}
+	c.client.Transport = t
+}
+
+//
+// WithDisableKeepAlives will use each connection for a single request,
+// closing it once the response body has been read, so that no idle
+// connections linger. Requests are sent with Connection: close.
+//
+func WithDisableKeepAlives() ClientOption {
+	return configureTransport("WithDisableKeepAlives", func(t *http.Transport) {
+		t.DisableKeepAlives = true
+	})
+}
@@ -0,0 +1,130 @@
+package http
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newPortCountingServer returns a server counting the distinct client ports it sees, holding each request until concurrency requests have arrived.
+func newPortCountingServer(t *testing.T, concurrency int) (*httptest.Server, func() int) {
+	t.Helper()
+	var mu sync.Mutex
+	ports := map[string]bool{}
+	arrived := 0
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		ports[r.RemoteAddr] = true
+		arrived++
+		ch := release
+		if arrived == concurrency {
+			arrived = 0
+			close(release)
+			release = make(chan struct{})
+		}
+		mu.Unlock()
+		<-ch
+	}))
+	return srv, func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(ports)
+	}
+}
+
+// getConcurrently makes rounds of concurrent requests to url.
+func getConcurrently(t *testing.T, cli Client, url string, rounds, concurrency int) {
+	t.Helper()
+	for i := 0; i < rounds; i++ {
+		var wg sync.WaitGroup
+		for j := 0; j < concurrency; j++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if err := cli.Get(context.Background(), url); err != nil {
+					t.Errorf("Get() error = %v", err)
+				}
+			}()
+		}
+		wg.Wait()
+	}
+}
+
+func TestWithMaxIdleConnsPerHost(t *testing.T) {
+	t.Parallel()
+	const concurrency = 8
+
+	srv, ports := newPortCountingServer(t, concurrency)
+	defer srv.Close()
+	getConcurrently(t, NewClient(WithMaxIdleConnsPerHost(concurrency)), srv.URL, 3, concurrency)
+	if got := ports(); got != concurrency {
+		t.Errorf("distinct client ports = %d, want %d reused connections", got, concurrency)
+	}
+
+	srv, ports = newPortCountingServer(t, concurrency)
+	defer srv.Close()
+	getConcurrently(t, NewClient(), srv.URL, 3, concurrency)
+	if got := ports(); got <= concurrency {
+		t.Errorf("distinct client ports with the default pool = %d, want churn beyond %d", got, concurrency)
+	}
+}
+
+func TestConnectionPoolOptions(t *testing.T) {
+	t.Parallel()
+	config := &tls.Config{ServerName: "api.example.com"}
+	cli := NewTLSClient(config,
+		WithMaxIdleConns(50),
+		WithMaxIdleConnsPerHost(20),
+		WithMaxConnsPerHost(30),
+		WithIdleConnTimeout(time.Minute),
+	)
+	tr := cli.(*client).client.Transport.(*http.Transport)
+	if tr.MaxIdleConns != 50 || tr.MaxIdleConnsPerHost != 20 || tr.MaxConnsPerHost != 30 || tr.IdleConnTimeout != time.Minute {
+		t.Errorf("transport pool = %d, %d, %d, %v, want 50, 20, 30, 1m0s", tr.MaxIdleConns, tr.MaxIdleConnsPerHost, tr.MaxConnsPerHost, tr.IdleConnTimeout)
+	}
+	if tr.TLSClientConfig == nil || tr.TLSClientConfig.ServerName != "api.example.com" {
+		t.Errorf("transport TLS config = %+v, want the NewTLSClient config", tr.TLSClientConfig)
+	}
+
+	if tr := NewClient(WithMaxIdleConnsPerHost(20)).(*client).client.Transport.(*http.Transport); tr.Proxy == nil || tr.MaxIdleConnsPerHost != 20 {
+		t.Errorf("transport = %+v, want a copy of http.DefaultTransport", tr)
+	}
+	if http.DefaultTransport.(*http.Transport).MaxIdleConnsPerHost != 0 {
+		t.Errorf("http.DefaultTransport was modified")
+	}
+}
+
+func TestWithDisableKeepAlives(t *testing.T) {
+	t.Parallel()
+	var mu sync.Mutex
+	ports := map[string]bool{}
+	var closes int
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		ports[r.RemoteAddr] = true
+		if r.Close {
+			closes++
+		}
+		w.Write([]byte("body"))
+	}))
+	defer srv.Close()
+
+	cli := NewTLSClient(srv.Client().Transport.(*http.Transport).TLSClientConfig, WithDisableKeepAlives())
+	for i := 0; i < 3; i++ {
+		var body string
+		if err := cli.Get(context.Background(), srv.URL, WithStringResponse(&body)); err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(ports) != 3 || closes != 3 {
+		t.Errorf("distinct client ports = %d with %d Connection: close requests, want 3 and 3", len(ports), closes)
+	}
+}
@@ -0,0 +1,43 @@
+package http
+
+import (
+	"io"
+	"sync/atomic"
+)
+
+//
+// WithDownloadProgress will call fn as the body of a successful response is
+// read, with the bytes received so far and the Content-Length, or -1 if it
+// is unknown.
+//
+// fn is called from the goroutine making the request, and never after the
+// request returns.
+//
+func WithDownloadProgress(fn func(bytesReceived, total int64)) RequestOption {
+	return func(r *Request) {
+		r.downloadProgress = fn
+	}
+}
+
+// progressReader reports the bytes read through it until stopped.
+type progressReader struct {
+	r        io.Reader
+	received int64
+	total    int64
+	fn       func(bytesReceived, total int64)
+	stopped  int32
+}
+
+func (pr *progressReader) Read(p []byte) (int, error) {
+	n, err := pr.r.Read(p)
+	if n > 0 && atomic.LoadInt32(&pr.stopped) == 0 {
+		pr.received += int64(n)
+		pr.fn(pr.received, pr.total)
+	}
+	return n, err
+}
+
+// stop ends the reports, for a body kept by the caller after the request returns.
+func (pr *progressReader) stop() {
+	atomic.StoreInt32(&pr.stopped, 1)
+}
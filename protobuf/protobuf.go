@@ -0,0 +1,32 @@
+// Package protobuf adds Protocol Buffers support to the gohttp client.
+package protobuf
+
+import (
+	"fmt"
+
+	http "github.com/alphaflow/gohttp"
+	"google.golang.org/protobuf/proto"
+)
+
+// ContentType is the media type of binary Protocol Buffers messages.
+const ContentType = "application/x-protobuf"
+
+//
+// WithProtobufResponse will unmarshal the successful HTTP response body into
+// m, sending ContentType as the Accept header.
+//
+// A body that does not unmarshal, such as a JSON error page, is reported as
+// an *http.DecodeError holding the Content-Type actually received. With
+// NewMockClient, m is the Request's DecodedOutput.
+//
+func WithProtobufResponse(m proto.Message) http.RequestOption {
+	return http.WithDecodedResponse("protobuf", ContentType, m, unmarshal)
+}
+
+func unmarshal(buf []byte, v interface{}) error {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return fmt.Errorf("%T is not a proto.Message", v)
+	}
+	return proto.Unmarshal(buf, m)
+}
@@ -0,0 +1,66 @@
+package protobuf
+
+import (
+	"context"
+	"errors"
+	nethttp "net/http"
+	"net/http/httptest"
+	"testing"
+
+	http "github.com/alphaflow/gohttp"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestWithProtobufResponse(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		if got := r.Header.Get("Accept"); got != ContentType {
+			t.Errorf("Accept = %q, want %q", got, ContentType)
+		}
+		buf, _ := proto.Marshal(wrapperspb.String("hello"))
+		w.Header().Set("Content-Type", ContentType)
+		w.Write(buf)
+	}))
+	defer srv.Close()
+
+	var got wrapperspb.StringValue
+	if err := http.NewClient().Get(context.Background(), srv.URL, WithProtobufResponse(&got)); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if got.GetValue() != "hello" {
+		t.Errorf("Get() = %q, want hello", got.GetValue())
+	}
+}
+
+func TestWithProtobufResponse_json_body(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"error": "use the JSON API"}`))
+	}))
+	defer srv.Close()
+
+	var got wrapperspb.StringValue
+	err := http.NewClient().Get(context.Background(), srv.URL, WithProtobufResponse(&got))
+	var de *http.DecodeError
+	if !errors.As(err, &de) || de.Format != "protobuf" || de.ContentType != "application/json" {
+		t.Errorf("Get() error = %v, want a protobuf DecodeError for application/json", err)
+	}
+}
+
+func TestWithProtobufResponse_mock(t *testing.T) {
+	t.Parallel()
+	cli := http.NewMockClient(func(ctx context.Context, r *http.Request) error {
+		proto.Merge(r.DecodedOutput.(proto.Message), wrapperspb.String("stubbed"))
+		return nil
+	})
+
+	var got wrapperspb.StringValue
+	if err := cli.Get(context.Background(), "/message", WithProtobufResponse(&got)); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if got.GetValue() != "stubbed" {
+		t.Errorf("Get() = %q, want stubbed", got.GetValue())
+	}
+}
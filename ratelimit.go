@@ -0,0 +1,165 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+//
+// NewRateLimitedClient wraps inner to send at most rps requests per second,
+// with bursts of up to burst requests.
+//
+// A request over the limit waits for its turn. If the context is done
+// first, or its deadline would pass before the turn comes, the request
+// fails at once with an error wrapping the context error.
+//
+func NewRateLimitedClient(inner Client, rps float64, burst int) Client {
+	return newRateLimitedClient(inner, rps, burst, false)
+}
+
+// NewHostRateLimitedClient wraps inner like NewRateLimitedClient, but with a separate limit for each host.
+func NewHostRateLimitedClient(inner Client, rps float64, burst int) Client {
+	return newRateLimitedClient(inner, rps, burst, true)
+}
+
+func newRateLimitedClient(inner Client, rps float64, burst int, perHost bool) *rateLimitedClient {
+	if rps <= 0 {
+		panic(fmt.Sprintf("rate limit must be positive, not %v", rps))
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	return &rateLimitedClient{Client: inner, rps: rps, burst: burst, perHost: perHost, clock: realClock{}, buckets: map[string]*tokenBucket{}}
+}
+
+// RateLimiterState is a snapshot of one limit of a client from NewRateLimitedClient or NewHostRateLimitedClient.
+type RateLimiterState struct {
+	// Host is the host the limit applies to, or "" for a limit on every request.
+	Host  string
+	RPS   float64
+	Burst int
+	// Tokens is the number of requests that can be sent without waiting. It is negative while requests wait.
+	Tokens float64
+	// Waiting is the number of requests waiting for their turn.
+	Waiting int
+}
+
+// RateLimits returns the state of the limits of c, sorted by host, if c is a client from NewRateLimitedClient or NewHostRateLimitedClient.
+func RateLimits(c Client) ([]RateLimiterState, bool) {
+	rc, ok := c.(*rateLimitedClient)
+	if !ok {
+		return nil, false
+	}
+	return rc.states(), true
+}
+
+type rateLimitedClient struct {
+	Client
+	rps     float64
+	burst   int
+	perHost bool
+	clock   clock
+
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+}
+
+// tokenBucket holds the tokens of one limit, refilled at rps up to burst. Requests may take them ahead, leaving it negative.
+type tokenBucket struct {
+	tokens  float64
+	last    time.Time
+	waiting int
+}
+
+func (rc *rateLimitedClient) Get(ctx context.Context, url string, options ...RequestOption) error {
+	if err := rc.wait(ctx, url); err != nil {
+		return err
+	}
+	return rc.Client.Get(ctx, url, options...)
+}
+
+func (rc *rateLimitedClient) Post(ctx context.Context, url string, options ...RequestOption) error {
+	if err := rc.wait(ctx, url); err != nil {
+		return err
+	}
+	return rc.Client.Post(ctx, url, options...)
+}
+
+func (rc *rateLimitedClient) Do(ctx context.Context, method, url string, options ...RequestOption) error {
+	if err := rc.wait(ctx, url); err != nil {
+		return err
+	}
+	return rc.Client.Do(ctx, method, url, options...)
+}
+
+// wait takes a token for a request to url, waiting for it if needed.
+func (rc *rateLimitedClient) wait(ctx context.Context, url string) error {
+	var host string
+	if rc.perHost {
+		host = hostKey(url)
+	}
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("waiting for rate limit: %w", err)
+	}
+
+	rc.mu.Lock()
+	now := rc.clock.Now()
+	b := rc.bucket(host, now)
+	b.tokens--
+	delay := time.Duration(-b.tokens / rc.rps * float64(time.Second))
+	if delay <= 0 {
+		rc.mu.Unlock()
+		return nil
+	}
+	if deadline, ok := ctx.Deadline(); ok && deadline.Sub(now) < delay {
+		b.tokens++
+		rc.mu.Unlock()
+		return fmt.Errorf("rate limit wait of %v exceeds the context deadline: %w", delay, context.DeadlineExceeded)
+	}
+	b.waiting++
+	rc.mu.Unlock()
+
+	err := rc.clock.Sleep(ctx, delay)
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	b.waiting--
+	if err != nil {
+		// Give the token back, for the requests queued behind this one.
+		b.tokens++
+		return fmt.Errorf("waiting for rate limit: %w", err)
+	}
+	return nil
+}
+
+// bucket returns the bucket of host, refilled up to now. rc.mu must be held.
+func (rc *rateLimitedClient) bucket(host string, now time.Time) *tokenBucket {
+	b := rc.buckets[host]
+	if b == nil {
+		b = &tokenBucket{tokens: float64(rc.burst), last: now}
+		rc.buckets[host] = b
+	}
+	if now.After(b.last) {
+		b.tokens += now.Sub(b.last).Seconds() * rc.rps
+		if b.tokens > float64(rc.burst) {
+			b.tokens = float64(rc.burst)
+		}
+		b.last = now
+	}
+	return b
+}
+
+func (rc *rateLimitedClient) states() []RateLimiterState {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	now := rc.clock.Now()
+	states := make([]RateLimiterState, 0, len(rc.buckets))
+	for host := range rc.buckets {
+		b := rc.bucket(host, now)
+		states = append(states, RateLimiterState{Host: host, RPS: rc.rps, Burst: rc.burst, Tokens: b.tokens, Waiting: b.waiting})
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].Host < states[j].Host })
+	return states
+}
@@ -0,0 +1,100 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestNewRateLimitedClient(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	var sent []time.Time
+	clock := &fakeRetryClock{fakeClock: newFakeClock()}
+	start := clock.Now()
+	c := NewRateLimitedClient(NewMockClient(func(ctx context.Context, req *Request) error {
+		sent = append(sent, clock.Now())
+		return nil
+	}), 2, 2)
+	c.(*rateLimitedClient).clock = clock
+
+	for i := 0; i < 5; i++ {
+		if err := c.Get(ctx, "http://example.com/"); err != nil {
+			t.Fatalf("Get() #%d error = %v", i+1, err)
+		}
+	}
+	// The burst of 2 goes at once, then one request every 500ms.
+	want := []time.Duration{0, 0, 500 * time.Millisecond, time.Second, 1500 * time.Millisecond}
+	for i, at := range sent {
+		if got := at.Sub(start); got != want[i] {
+			t.Errorf("request #%d sent at %v, want %v", i+1, got, want[i])
+		}
+	}
+
+	states, ok := RateLimits(c)
+	if !ok || len(states) != 1 || states[0].Host != "" || states[0].Tokens != 0 || states[0].Waiting != 0 {
+		t.Errorf("RateLimits() = %+v, %v, want one exhausted limit", states, ok)
+	}
+	clock.Advance(time.Hour)
+	if states, _ := RateLimits(c); states[0].Tokens != 2 {
+		t.Errorf("RateLimits() tokens = %v after an hour, want the burst of 2", states[0].Tokens)
+	}
+}
+
+func TestNewHostRateLimitedClient(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	clock := &fakeRetryClock{fakeClock: newFakeClock()}
+	c := NewHostRateLimitedClient(NewMockClient(func(ctx context.Context, req *Request) error { return nil }), 1, 1)
+	c.(*rateLimitedClient).clock = clock
+
+	for _, url := range []string{"http://a.example/1", "http://b.example/1", "http://a.example/2"} {
+		if err := c.Get(ctx, url); err != nil {
+			t.Fatalf("Get(%q) error = %v", url, err)
+		}
+	}
+	if len(clock.delays) != 1 || clock.delays[0] != time.Second {
+		t.Errorf("waits = %v, want one second for the second a.example request", clock.delays)
+	}
+	states, _ := RateLimits(c)
+	if len(states) != 2 || states[0].Host != "a.example" || states[1].Host != "b.example" {
+		t.Errorf("RateLimits() = %+v, want a.example and b.example", states)
+	}
+}
+
+func TestRateLimitedClientDeadline(t *testing.T) {
+	t.Parallel()
+	var calls int
+	c := NewRateLimitedClient(NewMockClient(func(ctx context.Context, req *Request) error {
+		calls++
+		return nil
+	}), 0.1, 1)
+	if err := c.Get(context.Background(), "http://example.com/"); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+
+	// The next token comes in 10s, past the deadline.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	start := time.Now()
+	err := c.Get(ctx, "http://example.com/")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Get() error = %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Get() returned after %v, want at once", elapsed)
+	}
+	if calls != 1 {
+		t.Errorf("inner client called %d times, want 1", calls)
+	}
+	if states, _ := RateLimits(c); states[0].Tokens < -0.01 {
+		t.Errorf("RateLimits() tokens = %v, want the refused request's token given back", states[0].Tokens)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	if err := c.Get(ctx, "http://example.com/"); !errors.Is(err, context.Canceled) {
+		t.Errorf("Get() error = %v, want context.Canceled", err)
+	}
+}
@@ -0,0 +1,124 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+)
+
+//
+// WithReauth will call reauth when a response is 401 Unauthorized, and then
+// send the request once more, with a fresh token if the Authorization header
+// came from WithTokenSource.
+//
+// Concurrent requests failing together share a single call to reauth, and a
+// request sent before a successful call replays without calling it again. A
+// request whose streamed body cannot be sent again returns the 401.
+//
+func WithReauth(reauth func(ctx context.Context) error) ClientOption {
+	return func(c *clientConfig) {
+		c.reauth = reauth
+	}
+}
+
+// reauthGroup deduplicates the WithReauth calls of concurrent requests.
+type reauthGroup struct {
+	mu sync.Mutex
+	// gen counts the successful calls.
+	gen  uint64
+	call *reauthCall
+}
+
+type reauthCall struct {
+	done chan struct{}
+	err  error
+}
+
+func (g *reauthGroup) generation() uint64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.gen
+}
+
+// refresh calls fn unless a call succeeded since generation gen, sharing the call in progress if there is one.
+func (g *reauthGroup) refresh(ctx context.Context, gen uint64, fn func(context.Context) error) error {
+	g.mu.Lock()
+	if g.gen != gen {
+		g.mu.Unlock()
+		return nil
+	}
+	if call := g.call; call != nil {
+		g.mu.Unlock()
+		select {
+		case <-call.done:
+			return call.err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	call := &reauthCall{done: make(chan struct{})}
+	g.call = call
+	g.mu.Unlock()
+
+	call.err = fn(ctx)
+
+	g.mu.Lock()
+	g.call = nil
+	if call.err == nil {
+		g.gen++
+	}
+	g.mu.Unlock()
+	close(call.done)
+	return call.err
+}
+
+// reauthenticate replays r after a WithReauth call if resp is a 401, returning resp otherwise.
+func (c *client) reauthenticate(ctx context.Context, req *Request, r *http.Request, resp *http.Response, gen uint64) (*http.Response, error) {
+	if resp.StatusCode != http.StatusUnauthorized || !canReplay(r) {
+		return resp, nil
+	}
+	discardResponse(resp)
+	if err := c.reauth.refresh(ctx, gen, c.config.reauth); err != nil {
+		return nil, fmt.Errorf("reauthenticating after 401 from %s: %w", req.URL, err)
+	}
+
+	retry, err := replayRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	if req.tokenAuthorized {
+		retry.Header.Del("Authorization")
+		if err := c.authorize(ctx, req, retry.Header); err != nil {
+			return nil, err
+		}
+	}
+	// The replay goes through the request's own redirect, retry, timeout and hedging settings.
+	return c.send(ctx, req, retry)
+}
+
+// canReplay reports whether the body of r, if any, can be sent again.
+func canReplay(r *http.Request) bool {
+	return r.Body == nil || r.Body == http.NoBody || r.GetBody != nil
+}
+
+// replayRequest returns a copy of r with a fresh body, to be sent again.
+func replayRequest(r *http.Request) (*http.Request, error) {
+	retry := r.Clone(r.Context())
+	if r.GetBody != nil {
+		body, err := r.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		retry.Body = body
+	}
+	return retry, nil
+}
+
+// discardResponse drains a little of the body of resp, so that its connection can be reused, and closes it.
+func discardResponse(resp *http.Response) {
+	io.Copy(ioutil.Discard, io.LimitReader(resp.Body, maxDrainBytes))
+	resp.Body.Close()
+}
@@ -0,0 +1,52 @@
+package http
+
+import (
+	"net/http"
+	"net/url"
+)
+
+// redacted replaces the value of sensitive headers in debug output.
+const redacted = "REDACTED"
+
+// sensitiveHeaders are never shown in debug output.
+var sensitiveHeaders = []string{
+	"Authorization",
+	"Proxy-Authorization",
+	"Cookie",
+	"Set-Cookie",
+}
+
+// redactHeader returns a copy of h with the values of sensitive headers, and of extra, redacted.
+func redactHeader(h http.Header, extra ...string) http.Header {
+	ret := h.Clone()
+	for _, names := range [][]string{sensitiveHeaders, extra} {
+		for _, k := range names {
+			if vs, ok := ret[http.CanonicalHeaderKey(k)]; ok {
+				for i := range vs {
+					vs[i] = redacted
+				}
+			}
+		}
+	}
+	return ret
+}
+
+// redactURL returns u with the values of the query parameters params redacted.
+func redactURL(u *url.URL, params ...string) string {
+	query := u.Query()
+	changed := false
+	for _, p := range params {
+		if vs, ok := query[p]; ok {
+			for i := range vs {
+				vs[i] = redacted
+			}
+			changed = true
+		}
+	}
+	if !changed {
+		return u.String()
+	}
+	ret := *u
+	ret.RawQuery = query.Encode()
+	return ret.String()
+}
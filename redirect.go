@@ -0,0 +1,68 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+)
+
+//
+// WithRedirectPolicy will follow at most maxRedirects redirects, and with
+// sameHostOnly, none to another host, so that credentials such as the
+// Authorization header cannot leak to it.
+//
+// A redirect the policy refuses is not followed: its 3xx response is handled
+// like any other, failing with a *BadStatusError unless allowed, e.g. by
+// WithAllowedStatuses, and its Location header is available to
+// WithResponseHeaders.
+//
+func WithRedirectPolicy(maxRedirects int, sameHostOnly bool) ClientOption {
+	return func(c *clientConfig) {
+		c.redirects = &redirectPolicy{max: maxRedirects, sameHostOnly: sameHostOnly}
+	}
+}
+
+//
+// WithNoRedirects will not follow redirects for this request, handling a 3xx
+// response like WithRedirectPolicy handles a refused redirect.
+//
+func WithNoRedirects() RequestOption {
+	return func(r *Request) {
+		r.noRedirects = true
+	}
+}
+
+type redirectPolicy struct {
+	max          int
+	sameHostOnly bool
+}
+
+// checkRedirect is the http.Client CheckRedirect implementing the policy.
+func (p *redirectPolicy) checkRedirect(r *http.Request, via []*http.Request) error {
+	if len(via) > p.max {
+		return http.ErrUseLastResponse
+	}
+	if p.sameHostOnly && r.URL.Host != via[0].URL.Host {
+		return http.ErrUseLastResponse
+	}
+	return nil
+}
+
+func (p *redirectPolicy) describe() string {
+	detail := fmt.Sprintf("follows up to %d redirects", p.max)
+	if p.sameHostOnly {
+		detail += " to the same host"
+	}
+	return detail
+}
+
+// httpClient returns the http.Client to send req with.
+func (c *client) httpClient(req *Request) *http.Client {
+	if !req.noRedirects {
+		return &c.client
+	}
+	hc := c.client
+	hc.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	return &hc
+}
@@ -0,0 +1,101 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// newRedirectServer returns a server where /hops/N redirects N times before answering "done", and /away redirects to target.
+func newRedirectServer(t *testing.T, target string) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/away" {
+			http.Redirect(w, r, target, http.StatusFound)
+			return
+		}
+		n, _ := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/hops/"))
+		if n == 0 {
+			w.Write([]byte("done"))
+			return
+		}
+		http.Redirect(w, r, "/hops/"+strconv.Itoa(n-1), http.StatusFound)
+	}))
+}
+
+func TestWithRedirectPolicy(t *testing.T) {
+	t.Parallel()
+	var otherHits int32
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&otherHits, 1)
+		w.Write([]byte("other"))
+	}))
+	defer other.Close()
+	srv := newRedirectServer(t, other.URL+"/landing")
+	defer srv.Close()
+	ctx := context.Background()
+
+	for _, tt := range []struct {
+		name     string
+		opts     []ClientOption
+		path     string
+		want     string
+		wantCode int
+	}{
+		{name: "within limit", opts: []ClientOption{WithRedirectPolicy(3, false)}, path: "/hops/3", want: "done"},
+		{name: "over limit", opts: []ClientOption{WithRedirectPolicy(3, false)}, path: "/hops/4", wantCode: http.StatusFound},
+		{name: "none allowed", opts: []ClientOption{WithRedirectPolicy(0, false)}, path: "/hops/1", wantCode: http.StatusFound},
+		{name: "same host chain", opts: []ClientOption{WithRedirectPolicy(5, true)}, path: "/hops/2", want: "done"},
+		{name: "cross host refused", opts: []ClientOption{WithRedirectPolicy(5, true)}, path: "/away", wantCode: http.StatusFound},
+		{name: "cross host allowed", opts: []ClientOption{WithRedirectPolicy(5, false)}, path: "/away", want: "other"},
+		{name: "default", path: "/hops/5", want: "done"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			err := NewClient(tt.opts...).Get(ctx, srv.URL+tt.path, WithStringResponse(&got))
+			var bse *BadStatusError
+			if tt.wantCode != 0 {
+				if !errors.As(err, &bse) || bse.Code != tt.wantCode {
+					t.Errorf("Get() error = %v, want *BadStatusError with code %d", err, tt.wantCode)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("Get() = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+	if n := atomic.LoadInt32(&otherHits); n != 1 {
+		t.Errorf("other host hit %d times, want once, only when cross-host redirects are allowed", n)
+	}
+}
+
+func TestWithNoRedirects(t *testing.T) {
+	t.Parallel()
+	srv := newRedirectServer(t, "")
+	defer srv.Close()
+	cli := NewClient()
+
+	var header http.Header
+	var code int
+	err := cli.Get(context.Background(), srv.URL+"/hops/2",
+		WithNoRedirects(),
+		WithAllowedStatuses(http.StatusFound),
+		WithResponseHeaders(&header),
+		WithStatusCode(&code),
+	)
+	if err != nil || code != http.StatusFound || header.Get("Location") != "/hops/1" {
+		t.Errorf("Get() = %d with Location %q, %v, want 302 to /hops/1", code, header.Get("Location"), err)
+	}
+
+	// Other requests of the client still follow redirects.
+	var got string
+	if err := cli.Get(context.Background(), srv.URL+"/hops/2", WithStringResponse(&got)); err != nil || got != "done" {
+		t.Errorf("Get() without WithNoRedirects = %q, %v, want done", got, err)
+	}
+}
@@ -0,0 +1,491 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RetryPolicy controls how WithRetry sends a request again after a transient failure.
+type RetryPolicy struct {
+	// MaxAttempts is the number of attempts, including the first. Below 2, requests are not retried.
+	MaxAttempts int
+	// BaseDelay is the delay before the first retry.
+	BaseDelay time.Duration
+	// Multiplier scales the delay before each later retry. Zero means 2.
+	Multiplier float64
+	// MaxDelay caps the delay before a retry. Zero means no cap.
+	MaxDelay time.Duration
+	// MaxRetryAfter caps the wait a Retry-After header asks for. Zero means MaxDelay.
+	MaxRetryAfter time.Duration
+	// Retryable reports whether an attempt is worth retrying. Nil means DefaultRetryable.
+	Retryable func(resp *http.Response, err error) bool
+	// AttemptTimeout bounds each attempt, including reading its body, within the request context. Zero means no limit.
+	// Attempts it ends fail with an error matching ErrAttemptTimeout, which DefaultRetryable retries.
+	AttemptTimeout time.Duration
+	// MaxElapsedTime stops retrying once the next attempt would start this long after the first. Zero means no limit.
+	MaxElapsedTime time.Duration
+	// Budget, if set, limits the share of requests retried; give every policy of a client the same one.
+	Budget *RetryBudget
+	// OnRetry, if set, is called after each retryable failure with whether the request is retried.
+	OnRetry func(RetryEvent)
+}
+
+// RetryDecision tells whether a retryable failure is followed by another attempt, or why not.
+type RetryDecision string
+
+const (
+	RetryScheduled         RetryDecision = "scheduled"
+	RetryAttemptsExhausted RetryDecision = "attempts exhausted"
+	RetryDeadline          RetryDecision = "context deadline"
+	RetryMaxElapsedTime    RetryDecision = "max elapsed time"
+	RetryBudgetExhausted   RetryDecision = "budget exhausted"
+)
+
+// RetryEvent describes a retryable failure, for RetryPolicy.OnRetry.
+type RetryEvent struct {
+	// Attempt is the number of the attempt that failed, from 1.
+	Attempt int
+	// StatusCode is the response status, or zero if Err is set.
+	StatusCode int
+	Err        error
+	// Delay is the wait before the next attempt, if it is made.
+	Delay time.Duration
+	// Elapsed is the time since the first attempt started.
+	Elapsed  time.Duration
+	Decision RetryDecision
+}
+
+//
+// DefaultRetryable reports whether an attempt failed transiently: with a
+// transport error that IsRetryable accepts, such as a connection reset, by
+// running out of its AttemptTimeout, or with a 429, 502, 503 or 504 status.
+//
+// resp is nil when err is not.
+//
+func DefaultRetryable(resp *http.Response, err error) bool {
+	if errors.Is(err, ErrAttemptTimeout) {
+		return true
+	}
+	if err != nil {
+		return IsRetryable(err)
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+//
+// WithRetry will send requests again, after a growing delay, when an attempt
+// fails transiently according to policy. The response of the last attempt
+// is handled like that of a request without retries.
+//
+// A 429 or 503 response with a Retry-After header, in seconds or as an HTTP
+// date, is retried after the wait it asks for, up to MaxRetryAfter, instead
+// of the backoff delay.
+//
+// Only GET, HEAD, PUT and DELETE requests are retried by default, as
+// sending others twice may repeat their effect; see WithIdempotentRetry.
+// A connection error before the request was written, such as a refused
+// dial, is retried for any method.
+//
+// Only requests whose body can be sent again are retried, as a WithJSONBody
+// body can; a streamed WithBodyReader body is sent once. Canceling the
+// request context ends the wait for the next attempt at once, and no retry
+// is made that could not start before its deadline. WithRetries overrides
+// the policy for one request. Mock clients ignore the option.
+//
+func WithRetry(policy RetryPolicy) ClientOption {
+	return func(c *clientConfig) {
+		c.retry = &policy
+	}
+}
+
+// RetryOption adjusts the RetryPolicy of a single request given WithRetries.
+type RetryOption func(*RetryPolicy)
+
+// WithRetryBackoff will wait base before the first retry, growing up to max. Zero max means no cap.
+func WithRetryBackoff(base, max time.Duration) RetryOption {
+	return func(p *RetryPolicy) {
+		p.BaseDelay = base
+		p.MaxDelay = max
+	}
+}
+
+// WithMaxRetryAfter will wait at most max when a response asks to wait longer with Retry-After.
+func WithMaxRetryAfter(max time.Duration) RetryOption {
+	return func(p *RetryPolicy) {
+		p.MaxRetryAfter = max
+	}
+}
+
+// WithAttemptTimeout will give each attempt d to complete, while the request context still bounds them all.
+func WithAttemptTimeout(d time.Duration) RetryOption {
+	return func(p *RetryPolicy) {
+		p.AttemptTimeout = d
+	}
+}
+
+// WithMaxElapsedTime will stop retrying once the next attempt would start d after the first.
+func WithMaxElapsedTime(d time.Duration) RetryOption {
+	return func(p *RetryPolicy) {
+		p.MaxElapsedTime = d
+	}
+}
+
+// WithRetryMultiplier will scale the delay before each later retry by m.
+func WithRetryMultiplier(m float64) RetryOption {
+	return func(p *RetryPolicy) {
+		p.Multiplier = m
+	}
+}
+
+// WithRetryIf will retry the attempts for which retryable returns true, instead of those DefaultRetryable accepts.
+func WithRetryIf(retryable func(resp *http.Response, err error) bool) RetryOption {
+	return func(p *RetryPolicy) {
+		p.Retryable = retryable
+	}
+}
+
+// defaultRetryBaseDelay is the backoff of WithRetries on a client without a WithRetry policy.
+const defaultRetryBaseDelay = 100 * time.Millisecond
+
+type retryOverride struct {
+	maxAttempts int
+	options     []RetryOption
+}
+
+//
+// WithRetries will make up to maxAttempts attempts at this request, instead
+// of following the WithRetry policy of the client; 0 disables retries. The
+// other settings of the client policy still apply unless opts change them.
+// On a client without one, the delay starts at 100ms and doubles.
+//
+// The request context deadline bounds all the attempts together: a retry
+// that could not start before it is not made, and the last response or
+// error is returned instead.
+//
+func WithRetries(maxAttempts int, opts ...RetryOption) RequestOption {
+	return func(r *Request) {
+		r.retries = &retryOverride{maxAttempts: maxAttempts, options: opts}
+	}
+}
+
+//
+// WithIdempotentRetry will let the WithRetry policy retry this request
+// whatever its method, as the server handles it safely when received twice.
+//
+// A request carrying an Idempotency-Key header, as from WithIdempotencyKey,
+// is retried without it.
+//
+func WithIdempotentRetry() RequestOption {
+	return func(r *Request) {
+		r.idempotentRetry = true
+	}
+}
+
+// retrySafe reports whether sending r again cannot repeat an effect its failed attempt, with err, had on the server.
+func (req *Request) retrySafe(r *http.Request, err error) bool {
+	return idempotent(r.Method, r.Header) || req.idempotentRetry || err != nil && notSent(err)
+}
+
+// idempotent reports whether a request with method and header has the same effect on the server when sent twice.
+func idempotent(method string, header http.Header) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return header.Get("Idempotency-Key") != ""
+}
+
+// notSent reports whether err happened before a request could be written, while connecting.
+func notSent(err error) bool {
+	var oe *net.OpError
+	if errors.As(err, &oe) && (oe.Op == "dial" || oe.Op == "proxyconnect") {
+		return true
+	}
+	var de *net.DNSError
+	return errors.As(err, &de)
+}
+
+// retryPolicy returns the policy for req, or nil if it is not retried.
+func (c *client) retryPolicy(req *Request) *RetryPolicy {
+	if req.retries == nil {
+		return c.config.retry
+	}
+	p := RetryPolicy{BaseDelay: defaultRetryBaseDelay}
+	if c.config.retry != nil {
+		p = *c.config.retry
+	}
+	p.MaxAttempts = req.retries.maxAttempts
+	for _, o := range req.retries.options {
+		o(&p)
+	}
+	return &p
+}
+
+// delay returns the delay before retry number n, starting at 1.
+func (p *RetryPolicy) delay(n int) time.Duration {
+	multiplier := p.Multiplier
+	if multiplier == 0 {
+		multiplier = 2
+	}
+	d := float64(p.BaseDelay)
+	for i := 1; i < n; i++ {
+		d *= multiplier
+		if p.MaxDelay > 0 && d >= float64(p.MaxDelay) {
+			break
+		}
+	}
+	if p.MaxDelay > 0 && d > float64(p.MaxDelay) {
+		return p.MaxDelay
+	}
+	return time.Duration(d)
+}
+
+// retryDelay returns the delay before retry number n, after resp, honoring its Retry-After header.
+func (p *RetryPolicy) retryDelay(n int, resp *http.Response, now time.Time) time.Duration {
+	if resp == nil || resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusServiceUnavailable {
+		return p.delay(n)
+	}
+	d, ok := parseRetryAfter(resp.Header.Get("Retry-After"), now)
+	if !ok {
+		return p.delay(n)
+	}
+	max := p.MaxRetryAfter
+	if max == 0 {
+		max = p.MaxDelay
+	}
+	if max > 0 && d > max {
+		return max
+	}
+	return d
+}
+
+// parseRetryAfter parses a Retry-After header, given in seconds or as an HTTP date, into a wait from now.
+func parseRetryAfter(v string, now time.Time) (time.Duration, bool) {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.ParseInt(v, 10, 64); err == nil {
+		if secs < 0 {
+			return 0, false
+		}
+		if secs > int64(math.MaxInt64/time.Second) {
+			return time.Duration(math.MaxInt64), true
+		}
+		return time.Duration(secs) * time.Second, true
+	}
+	t, err := http.ParseTime(v)
+	if err != nil {
+		return 0, false
+	}
+	if d := t.Sub(now); d > 0 {
+		return d, true
+	}
+	return 0, true
+}
+
+func (p *RetryPolicy) retryable(resp *http.Response, err error) bool {
+	if p.Retryable != nil {
+		return p.Retryable(resp, err)
+	}
+	return DefaultRetryable(resp, err)
+}
+
+func (p *RetryPolicy) describe() string {
+	parts := []string{fmt.Sprintf("up to %d attempts, backoff from %v", p.MaxAttempts, p.BaseDelay)}
+	if p.AttemptTimeout > 0 {
+		parts = append(parts, fmt.Sprintf("%v per attempt", p.AttemptTimeout))
+	}
+	if p.MaxElapsedTime > 0 {
+		parts = append(parts, fmt.Sprintf("no retry past %v", p.MaxElapsedTime))
+	}
+	if p.Budget != nil {
+		parts = append(parts, p.Budget.describe())
+	}
+	return strings.Join(parts, ", ")
+}
+
+// send sends r with the http.Client for req, retrying it according to the WithRetry policy.
+func (c *client) send(ctx context.Context, req *Request, r *http.Request) (*http.Response, error) {
+	hc := c.httpClient(req)
+	policy := c.retryPolicy(req)
+	if policy == nil {
+		return c.attempt(hc, req, r)
+	}
+	resp, attempts, attemptTimedOut, err := c.sendAttempts(ctx, hc, req, r, policy)
+	if err != nil && (attemptTimedOut || errors.Is(err, context.DeadlineExceeded)) {
+		return nil, &TimeoutError{Attempts: attempts, PerAttempt: attemptTimedOut, Err: err}
+	}
+	return resp, err
+}
+
+// sendAttempts makes the attempts of send, returning their number and whether the last ran out of its attempt timeout.
+func (c *client) sendAttempts(ctx context.Context, hc *http.Client, req *Request, r *http.Request, policy *RetryPolicy) (*http.Response, int, bool, error) {
+	clock := c.clockOrDefault()
+	start := clock.Now()
+	resp, timedOut, err := c.timedAttempt(hc, req, r, policy.AttemptTimeout)
+	if policy.MaxAttempts < 2 || !canReplay(r) {
+		return resp, 1, timedOut, err
+	}
+	policy.Budget.addRequest(start)
+	attempt := 1
+	for ; ctx.Err() == nil && policy.retryable(resp, err) && req.retrySafe(r, err); attempt++ {
+		now := clock.Now()
+		delay := policy.retryDelay(attempt, resp, now)
+		event := RetryEvent{Attempt: attempt, Err: err, Delay: delay, Elapsed: now.Sub(start)}
+		if resp != nil {
+			event.StatusCode = resp.StatusCode
+		}
+		event.Decision = policy.decide(ctx, event, now)
+		if policy.OnRetry != nil {
+			policy.OnRetry(event)
+		}
+		if event.Decision != RetryScheduled {
+			break
+		}
+
+		if resp != nil {
+			discardResponse(resp)
+		}
+		if err := clock.Sleep(ctx, delay); err != nil {
+			return nil, attempt, false, err
+		}
+		retry, rerr := replayRequest(r)
+		if rerr != nil {
+			return nil, attempt, false, rerr
+		}
+		resp, timedOut, err = c.timedAttempt(hc, req, retry, policy.AttemptTimeout)
+	}
+	return resp, attempt, timedOut, err
+}
+
+// timedAttempt sends r once like attempt, within timeout unless it is zero, reporting whether that timeout ended it.
+func (c *client) timedAttempt(hc *http.Client, req *Request, r *http.Request, timeout time.Duration) (*http.Response, bool, error) {
+	if timeout <= 0 {
+		resp, err := c.attempt(hc, req, r)
+		return resp, false, err
+	}
+	ctx, cancel := c.clockOrDefault().WithTimeout(r.Context(), timeout)
+	resp, err := c.attempt(hc, req, r.WithContext(ctx))
+	if err != nil {
+		timedOut := ctx.Err() == context.DeadlineExceeded && r.Context().Err() == nil
+		cancel()
+		if timedOut {
+			err = &attemptTimeoutError{err: err}
+		}
+		return nil, timedOut, err
+	}
+	// The attempt also bounds reading the body.
+	resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	return resp, false, nil
+}
+
+// ErrAttemptTimeout matches the error of an attempt ended by the AttemptTimeout of a RetryPolicy, as given to its Retryable.
+var ErrAttemptTimeout = errors.New("attempt timeout exceeded")
+
+// attemptTimeoutError is the error of an attempt ended by its AttemptTimeout, matching both ErrAttemptTimeout and the error of the attempt.
+type attemptTimeoutError struct {
+	err error
+}
+
+func (ate *attemptTimeoutError) Error() string        { return ate.err.Error() }
+func (ate *attemptTimeoutError) Unwrap() error        { return ate.err }
+func (ate *attemptTimeoutError) Is(target error) bool { return target == ErrAttemptTimeout }
+
+//
+// TimeoutError is returned when a request under a WithRetry policy runs out
+// of time, telling which deadline ended it.
+//
+type TimeoutError struct {
+	// Attempts is the number of attempts made.
+	Attempts int
+	// PerAttempt reports whether the last attempt ran out of its AttemptTimeout, rather than the request context.
+	PerAttempt bool
+	Err        error
+}
+
+func (te *TimeoutError) Error() string {
+	deadline := "request deadline"
+	if te.PerAttempt {
+		deadline = "attempt timeout"
+	}
+	attempts := "attempts"
+	if te.Attempts == 1 {
+		attempts = "attempt"
+	}
+	return fmt.Sprintf("%s exceeded after %d %s: %v", deadline, te.Attempts, attempts, te.Err)
+}
+
+func (te *TimeoutError) Unwrap() error {
+	return te.Err
+}
+
+// decide returns whether the attempt of e is followed by another after e.Delay.
+func (p *RetryPolicy) decide(ctx context.Context, e RetryEvent, now time.Time) RetryDecision {
+	if e.Attempt >= p.MaxAttempts {
+		return RetryAttemptsExhausted
+	}
+	if deadline, ok := ctx.Deadline(); ok && deadline.Sub(now) <= e.Delay {
+		return RetryDeadline
+	}
+	if p.MaxElapsedTime > 0 && e.Elapsed+e.Delay > p.MaxElapsedTime {
+		return RetryMaxElapsedTime
+	}
+	if !p.Budget.allowRetry(now) {
+		return RetryBudgetExhausted
+	}
+	return RetryScheduled
+}
+
+// clock tells the time, waits between retries and times attempts.
+type clock interface {
+	Now() time.Time
+	// Sleep waits for d, or until ctx is done.
+	Sleep(ctx context.Context, d time.Duration) error
+	// WithTimeout derives a context from ctx ending with context.DeadlineExceeded after d, like context.WithTimeout.
+	WithTimeout(ctx context.Context, d time.Duration) (context.Context, context.CancelFunc)
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time { return time.Now() }
+
+func (realClock) Sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (realClock) WithTimeout(ctx context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, d)
+}
+
+func (c *client) clockOrDefault() clock {
+	if c.clock != nil {
+		return c.clock
+	}
+	return realClock{}
+}
+
+func (mc *mockClient) clockOrDefault() clock {
+	if mc.clock != nil {
+		return mc.clock
+	}
+	return realClock{}
+}
@@ -0,0 +1,677 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newFlakyServer returns a server failing with code until its nth request, and the count of requests it received.
+func newFlakyServer(t *testing.T, n int32, code int) (*httptest.Server, *int32) {
+	t.Helper()
+	var attempts int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) < n {
+			w.WriteHeader(code)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	return srv, &attempts
+}
+
+// fakeRetryClock is a fakeClock that advances by the retry delays, recording them, instead of waiting, and ends the contexts of its timeouts as it passes their deadlines.
+type fakeRetryClock struct {
+	*fakeClock
+	delays []time.Duration
+
+	timerMu sync.Mutex
+	timers  []fakeTimer
+}
+
+// fakeTimer ends a context of a fakeRetryClock at its deadline.
+type fakeTimer struct {
+	deadline time.Time
+	cancel   context.CancelCauseFunc
+}
+
+func (fc *fakeRetryClock) Sleep(ctx context.Context, d time.Duration) error {
+	fc.delays = append(fc.delays, d)
+	fc.Advance(d)
+	return ctx.Err()
+}
+
+func (fc *fakeRetryClock) Advance(d time.Duration) {
+	fc.fakeClock.Advance(d)
+	now := fc.Now()
+	fc.timerMu.Lock()
+	var due, pending []fakeTimer
+	for _, t := range fc.timers {
+		if t.deadline.After(now) {
+			pending = append(pending, t)
+		} else {
+			due = append(due, t)
+		}
+	}
+	fc.timers = pending
+	fc.timerMu.Unlock()
+	sort.Slice(due, func(i, j int) bool { return due[i].deadline.Before(due[j].deadline) })
+	for _, t := range due {
+		t.cancel(context.DeadlineExceeded)
+	}
+}
+
+func (fc *fakeRetryClock) WithTimeout(ctx context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	deadline := fc.Now().Add(d)
+	inner, cancel := context.WithCancelCause(ctx)
+	fc.timerMu.Lock()
+	fc.timers = append(fc.timers, fakeTimer{deadline: deadline, cancel: cancel})
+	fc.timerMu.Unlock()
+	return &fakeDeadlineContext{Context: inner, deadline: deadline}, func() { cancel(context.Canceled) }
+}
+
+// fakeDeadlineContext is a context a fakeRetryClock ends with context.DeadlineExceeded at its deadline.
+type fakeDeadlineContext struct {
+	context.Context
+	deadline time.Time
+}
+
+func (ctx *fakeDeadlineContext) Deadline() (time.Time, bool) {
+	if deadline, ok := ctx.Context.Deadline(); ok && deadline.Before(ctx.deadline) {
+		return deadline, true
+	}
+	return ctx.deadline, true
+}
+
+func (ctx *fakeDeadlineContext) Err() error {
+	if ctx.Context.Err() == nil {
+		return nil
+	}
+	return context.Cause(ctx.Context)
+}
+
+// fakeSleeper gives c a fake clock, returning the delays it records.
+func fakeSleeper(c *client) *[]time.Duration {
+	fc := &fakeRetryClock{fakeClock: newFakeClock()}
+	c.clock = fc
+	return &fc.delays
+}
+
+func TestWithRetry(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	policy := RetryPolicy{MaxAttempts: 4, BaseDelay: 100 * time.Millisecond, Multiplier: 3, MaxDelay: 500 * time.Millisecond}
+
+	for _, tt := range []struct {
+		name         string
+		succeedOn    int32
+		code         int
+		options      []RequestOption
+		wantAttempts int32
+		wantDelays   []time.Duration
+		wantCode     int
+	}{
+		{name: "succeeds on third attempt", succeedOn: 3, code: http.StatusServiceUnavailable, wantAttempts: 3, wantDelays: []time.Duration{100 * time.Millisecond, 300 * time.Millisecond}},
+		{name: "gives up", succeedOn: 10, code: http.StatusBadGateway, wantAttempts: 4, wantDelays: []time.Duration{100 * time.Millisecond, 300 * time.Millisecond, 500 * time.Millisecond}, wantCode: http.StatusBadGateway},
+		{name: "not retryable status", succeedOn: 3, code: http.StatusBadRequest, wantAttempts: 1, wantCode: http.StatusBadRequest},
+		{name: "replayable body", succeedOn: 2, code: http.StatusGatewayTimeout, options: []RequestOption{WithJSONBody(map[string]int{"a": 1})}, wantAttempts: 2, wantDelays: []time.Duration{100 * time.Millisecond}},
+		{name: "streamed body sent once", succeedOn: 2, code: http.StatusGatewayTimeout, options: []RequestOption{WithBodyReader(io.MultiReader(strings.NewReader("x")), -1)}, wantAttempts: 1, wantCode: http.StatusGatewayTimeout},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, attempts := newFlakyServer(t, tt.succeedOn, tt.code)
+			defer srv.Close()
+			c := newClient(http.Client{}, []ClientOption{WithRetry(policy)})
+			delays := fakeSleeper(c)
+
+			var got string
+			err := c.Do(ctx, http.MethodPut, srv.URL, append(tt.options, WithStringResponse(&got))...)
+			if tt.wantCode != 0 {
+				var bse *BadStatusError
+				if !errors.As(err, &bse) || bse.Code != tt.wantCode {
+					t.Errorf("Do() error = %v, want *BadStatusError with code %d", err, tt.wantCode)
+				}
+			} else if err != nil || got != "ok" {
+				t.Errorf("Do() = %q, %v, want %q", got, err, "ok")
+			}
+			if n := atomic.LoadInt32(attempts); n != tt.wantAttempts {
+				t.Errorf("server got %d attempts, want %d", n, tt.wantAttempts)
+			}
+			var elapsed, wantElapsed time.Duration
+			for _, d := range *delays {
+				elapsed += d
+			}
+			for _, d := range tt.wantDelays {
+				wantElapsed += d
+			}
+			if len(*delays) != len(tt.wantDelays) || elapsed != wantElapsed {
+				t.Errorf("delays = %v, want %v", *delays, tt.wantDelays)
+			}
+		})
+	}
+}
+
+func TestWithRetryNetworkError(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.NotFoundHandler())
+	url := srv.URL
+	srv.Close()
+
+	c := newClient(http.Client{}, []ClientOption{WithRetry(RetryPolicy{MaxAttempts: 3, BaseDelay: time.Second})})
+	delays := fakeSleeper(c)
+	if err := c.Get(context.Background(), url); err == nil {
+		t.Fatal("Get() succeeded against a closed server")
+	}
+	if len(*delays) != 2 {
+		t.Errorf("retried %d times, want 2", len(*delays))
+	}
+}
+
+func TestWithRetryContextCanceled(t *testing.T) {
+	t.Parallel()
+	srv, attempts := newFlakyServer(t, 10, http.StatusServiceUnavailable)
+	defer srv.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	c := NewClient(WithRetry(RetryPolicy{MaxAttempts: 3, BaseDelay: time.Hour}))
+	start := time.Now()
+	err := c.Get(ctx, srv.URL)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Get() error = %v, want context.Canceled", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Get() returned after %v, want promptly after cancel", elapsed)
+	}
+	if n := atomic.LoadInt32(attempts); n != 1 {
+		t.Errorf("server got %d attempts, want 1", n)
+	}
+}
+
+func TestWithRetryMockClient(t *testing.T) {
+	t.Parallel()
+	var calls int
+	c := NewMockClient(func(ctx context.Context, req *Request) error {
+		calls++
+		return &BadStatusError{Code: http.StatusServiceUnavailable}
+	}, WithRetry(RetryPolicy{MaxAttempts: 3}))
+	c.Get(context.Background(), "http://example.com")
+	if calls != 1 {
+		t.Errorf("handler called %d times, want 1", calls)
+	}
+}
+
+func TestWithRetries(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	clientPolicy := WithRetry(RetryPolicy{MaxAttempts: 3, BaseDelay: 10 * time.Millisecond})
+
+	for _, tt := range []struct {
+		name         string
+		clientOpts   []ClientOption
+		option       RequestOption
+		wantAttempts int32
+		wantDelays   []time.Duration
+		wantErr      bool
+	}{
+		{name: "override up", clientOpts: []ClientOption{clientPolicy}, option: WithRetries(5), wantAttempts: 5, wantDelays: []time.Duration{10 * time.Millisecond, 20 * time.Millisecond, 40 * time.Millisecond, 80 * time.Millisecond}},
+		{name: "override down", clientOpts: []ClientOption{clientPolicy}, option: WithRetries(2), wantAttempts: 2, wantDelays: []time.Duration{10 * time.Millisecond}, wantErr: true},
+		{name: "disable", clientOpts: []ClientOption{clientPolicy}, option: WithRetries(0), wantAttempts: 1, wantErr: true},
+		{name: "without client policy", option: WithRetries(5), wantAttempts: 5, wantDelays: []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond}},
+		{name: "with options", option: WithRetries(5, WithRetryBackoff(time.Second, 3*time.Second), WithRetryMultiplier(4)), wantAttempts: 5, wantDelays: []time.Duration{time.Second, 3 * time.Second, 3 * time.Second, 3 * time.Second}},
+		{name: "custom retryable", option: WithRetries(5, WithRetryIf(func(*http.Response, error) bool { return false })), wantAttempts: 1, wantErr: true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, attempts := newFlakyServer(t, 5, http.StatusServiceUnavailable)
+			defer srv.Close()
+			c := newClient(http.Client{}, tt.clientOpts)
+			delays := fakeSleeper(c)
+
+			err := c.Get(ctx, srv.URL, tt.option)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Get() error = %v, want error %v", err, tt.wantErr)
+			}
+			if n := atomic.LoadInt32(attempts); n != tt.wantAttempts {
+				t.Errorf("server got %d attempts, want %d", n, tt.wantAttempts)
+			}
+			if len(*delays) != len(tt.wantDelays) {
+				t.Fatalf("delays = %v, want %v", *delays, tt.wantDelays)
+			}
+			for i, d := range *delays {
+				if d != tt.wantDelays[i] {
+					t.Errorf("delays = %v, want %v", *delays, tt.wantDelays)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestWithRetriesDeadline(t *testing.T) {
+	t.Parallel()
+	srv, attempts := newFlakyServer(t, 10, http.StatusServiceUnavailable)
+	defer srv.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+
+	// The delays are 100ms and 200ms, so the third attempt would start after the deadline.
+	start := time.Now()
+	err := NewClient().Get(ctx, srv.URL, WithRetries(10, WithRetryBackoff(100*time.Millisecond, 0)))
+	var bse *BadStatusError
+	if !errors.As(err, &bse) || bse.Code != http.StatusServiceUnavailable {
+		t.Errorf("Get() error = %v, want the last *BadStatusError", err)
+	}
+	if n := atomic.LoadInt32(attempts); n != 2 {
+		t.Errorf("server got %d attempts, want 2", n)
+	}
+	if elapsed := time.Since(start); elapsed > 300*time.Millisecond {
+		t.Errorf("Get() returned after %v, past its deadline", elapsed)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	for _, tt := range []struct {
+		header string
+		want   time.Duration
+		wantOK bool
+	}{
+		{header: "120", want: 2 * time.Minute, wantOK: true},
+		{header: " 0 ", want: 0, wantOK: true},
+		{header: "Fri, 01 Mar 2024 12:00:30 GMT", want: 30 * time.Second, wantOK: true},
+		{header: "Friday, 01-Mar-24 12:01:00 GMT", want: time.Minute, wantOK: true},
+		{header: "Fri, 01 Mar 2024 11:00:00 GMT", want: 0, wantOK: true},
+		{header: "", wantOK: false},
+		{header: "-5", wantOK: false},
+		{header: "soon", wantOK: false},
+	} {
+		got, ok := parseRetryAfter(tt.header, now)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("parseRetryAfter(%q) = %v, %v, want %v, %v", tt.header, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestWithRetryRetryAfter(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	inAMinute := newFakeClock().Now().Add(time.Minute).Format(http.TimeFormat)
+
+	for _, tt := range []struct {
+		name       string
+		code       int
+		retryAfter string
+		opts       []RetryOption
+		wantMin    time.Duration
+		wantMax    time.Duration
+	}{
+		{name: "seconds", code: http.StatusTooManyRequests, retryAfter: "7", wantMin: 7 * time.Second, wantMax: 7 * time.Second},
+		{name: "HTTP date", code: http.StatusServiceUnavailable, retryAfter: inAMinute, wantMin: time.Minute, wantMax: time.Minute},
+		{name: "capped", code: http.StatusTooManyRequests, retryAfter: inAMinute, opts: []RetryOption{WithMaxRetryAfter(5 * time.Second)}, wantMin: 5 * time.Second, wantMax: 5 * time.Second},
+		{name: "capped by max delay", code: http.StatusTooManyRequests, retryAfter: "3600", opts: []RetryOption{WithRetryBackoff(time.Millisecond, 2*time.Second)}, wantMin: 2 * time.Second, wantMax: 2 * time.Second},
+		{name: "unparseable", code: http.StatusTooManyRequests, retryAfter: "later", wantMin: 100 * time.Millisecond, wantMax: 100 * time.Millisecond},
+		{name: "ignored on 502", code: http.StatusBadGateway, retryAfter: "7", wantMin: 100 * time.Millisecond, wantMax: 100 * time.Millisecond},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if atomic.AddInt32(&attempts, 1) == 1 {
+					w.Header().Set("Retry-After", tt.retryAfter)
+					w.WriteHeader(tt.code)
+				}
+			}))
+			defer srv.Close()
+			c := newClient(http.Client{}, nil)
+			delays := fakeSleeper(c)
+
+			if err := c.Get(ctx, srv.URL, WithRetries(2, tt.opts...)); err != nil {
+				t.Fatalf("Get() error = %v", err)
+			}
+			if len(*delays) != 1 || (*delays)[0] < tt.wantMin || (*delays)[0] > tt.wantMax {
+				t.Errorf("delays = %v, want one in [%v, %v]", *delays, tt.wantMin, tt.wantMax)
+			}
+		})
+	}
+}
+
+func TestBadStatusErrorRetryAfter(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "30")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	err := NewClient().Get(context.Background(), srv.URL)
+	var bse *BadStatusError
+	if !errors.As(err, &bse) || bse.RetryAfter != 30*time.Second {
+		t.Errorf("Get() error = %#v, want *BadStatusError with RetryAfter 30s", err)
+	}
+}
+
+func TestBadStatusErrorRetryAfter_clock(t *testing.T) {
+	t.Parallel()
+	clock := &fakeRetryClock{fakeClock: newFakeClock()}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", clock.Now().Add(time.Minute).Format(http.TimeFormat))
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	c := newClient(http.Client{}, nil)
+	c.clock = clock
+	err := c.Get(context.Background(), srv.URL)
+	var bse *BadStatusError
+	if !errors.As(err, &bse) || bse.RetryAfter != time.Minute {
+		t.Errorf("Get() error = %#v, want *BadStatusError with RetryAfter 1m on the client clock", err)
+	}
+}
+
+func TestWithMaxElapsedTime(t *testing.T) {
+	t.Parallel()
+	srv, attempts := newFlakyServer(t, 100, http.StatusServiceUnavailable)
+	defer srv.Close()
+	var events []RetryEvent
+	c := newClient(http.Client{}, []ClientOption{WithRetry(RetryPolicy{
+		MaxAttempts:    10,
+		BaseDelay:      time.Second,
+		MaxElapsedTime: 10 * time.Second,
+		OnRetry:        func(e RetryEvent) { events = append(events, e) },
+	})})
+	delays := fakeSleeper(c)
+
+	// Waiting 1s, 2s and 4s takes 7s, so the 8s wait for a fifth attempt would end past 10s.
+	if err := c.Get(context.Background(), srv.URL); err == nil {
+		t.Fatal("Get() succeeded")
+	}
+	if n := atomic.LoadInt32(attempts); n != 4 {
+		t.Errorf("server got %d attempts, want 4", n)
+	}
+	if len(*delays) != 3 {
+		t.Errorf("delays = %v, want 3", *delays)
+	}
+	want := RetryEvent{Attempt: 4, StatusCode: http.StatusServiceUnavailable, Delay: 8 * time.Second, Elapsed: 7 * time.Second, Decision: RetryMaxElapsedTime}
+	if len(events) != 4 || events[3] != want {
+		t.Errorf("events = %+v, want the last to be %+v", events, want)
+	}
+
+	// Attempts taking 6s each count too: the first already leaves only 4s.
+	clock := &fakeRetryClock{fakeClock: newFakeClock()}
+	var slowAttempts int32
+	events = nil
+	slow := newClient(http.Client{Transport: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		atomic.AddInt32(&slowAttempts, 1)
+		clock.Advance(6 * time.Second)
+		return &http.Response{StatusCode: http.StatusServiceUnavailable, Body: http.NoBody, Request: r}, nil
+	})}, []ClientOption{WithRetry(RetryPolicy{
+		MaxAttempts:    10,
+		BaseDelay:      time.Second,
+		MaxElapsedTime: 10 * time.Second,
+		OnRetry:        func(e RetryEvent) { events = append(events, e) },
+	})})
+	slow.clock = clock
+	if err := slow.Get(context.Background(), "http://example.com"); err == nil {
+		t.Fatal("Get() of slow attempts succeeded")
+	}
+	if n := atomic.LoadInt32(&slowAttempts); n != 2 {
+		t.Errorf("transport got %d slow attempts, want 2", n)
+	}
+	if len(events) == 0 || events[0].Elapsed != 6*time.Second {
+		t.Errorf("events = %+v, want the first to have taken 6s", events)
+	}
+}
+
+func TestRetryBudget(t *testing.T) {
+	t.Parallel()
+	clock := newFakeClock()
+	b := NewRetryBudget(0.5, 10*time.Second)
+	b.random = func() float64 { return 0.99 }
+
+	for i := 0; i < 4; i++ {
+		b.addRequest(clock.Now())
+	}
+	for i, want := range []bool{true, true, false} {
+		if got := b.allowRetry(clock.Now()); got != want {
+			t.Errorf("allowRetry() #%d = %v, want %v", i+1, got, want)
+		}
+	}
+
+	b.random = func() float64 { return 0.3 }
+	if !b.allowRetry(clock.Now()) {
+		t.Error("allowRetry() = false over budget with a lucky draw, want true")
+	}
+
+	clock.Advance(time.Hour)
+	b.random = func() float64 { return 0.99 }
+	b.addRequest(clock.Now())
+	b.addRequest(clock.Now())
+	if !b.allowRetry(clock.Now()) {
+		t.Error("allowRetry() = false after the earlier retries decayed, want true")
+	}
+}
+
+func TestWithRetryBudget(t *testing.T) {
+	t.Parallel()
+	srv, attempts := newFlakyServer(t, 100, http.StatusServiceUnavailable)
+	defer srv.Close()
+	budget := NewRetryBudget(0.2, time.Hour)
+	budget.random = func() float64 { return 0.99 }
+	decisions := map[RetryDecision]int{}
+	c := newClient(http.Client{}, []ClientOption{WithRetry(RetryPolicy{
+		MaxAttempts: 2,
+		Budget:      budget,
+		OnRetry:     func(e RetryEvent) { decisions[e.Decision]++ },
+	})})
+	fakeSleeper(c)
+
+	for i := 0; i < 10; i++ {
+		c.Get(context.Background(), srv.URL)
+	}
+	// 20% of 10 requests allows the 5th and 10th to retry once each.
+	if n := atomic.LoadInt32(attempts); n != 12 {
+		t.Errorf("server got %d attempts, want 12", n)
+	}
+	want := map[RetryDecision]int{RetryScheduled: 2, RetryBudgetExhausted: 8, RetryAttemptsExhausted: 2}
+	for decision, n := range want {
+		if decisions[decision] != n {
+			t.Errorf("%d %q decisions, want %d (all: %v)", decisions[decision], decision, n, decisions)
+		}
+	}
+}
+
+func TestWithRetryIdempotency(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+
+	for _, tt := range []struct {
+		name         string
+		method       string
+		options      []RequestOption
+		unreachable  bool
+		wantAttempts int32
+		wantRetries  int
+	}{
+		{name: "GET", method: http.MethodGet, wantAttempts: 2, wantRetries: 1},
+		{name: "DELETE", method: http.MethodDelete, wantAttempts: 2, wantRetries: 1},
+		{name: "POST", method: http.MethodPost, wantAttempts: 1},
+		{name: "PATCH", method: http.MethodPatch, wantAttempts: 1},
+		{name: "POST with WithIdempotentRetry", method: http.MethodPost, options: []RequestOption{WithIdempotentRetry()}, wantAttempts: 2, wantRetries: 1},
+		{name: "POST with Idempotency-Key", method: http.MethodPost, options: []RequestOption{WithHeader("Idempotency-Key", "k1")}, wantAttempts: 2, wantRetries: 1},
+		{name: "POST not connected", method: http.MethodPost, unreachable: true, wantRetries: 1},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, attempts := newFlakyServer(t, 2, http.StatusServiceUnavailable)
+			defer srv.Close()
+			url := srv.URL
+			if tt.unreachable {
+				url = closed.URL
+			}
+			c := newClient(http.Client{}, []ClientOption{WithRetry(RetryPolicy{MaxAttempts: 2})})
+			delays := fakeSleeper(c)
+
+			options := tt.options
+			if tt.method != http.MethodGet {
+				options = append(options, WithJSONBody(map[string]int{"a": 1}))
+			}
+			c.Do(ctx, tt.method, url, options...)
+			if n := atomic.LoadInt32(attempts); n != tt.wantAttempts {
+				t.Errorf("server got %d attempts, want %d", n, tt.wantAttempts)
+			}
+			if len(*delays) != tt.wantRetries {
+				t.Errorf("retried %d times, want %d", len(*delays), tt.wantRetries)
+			}
+		})
+	}
+}
+
+func TestWithAttemptTimeout(t *testing.T) {
+	t.Parallel()
+	// newSlowServer returns a server stalling its first slow requests until they are canceled, telling each on arrived.
+	newSlowServer := func(slow int32, arrived chan<- struct{}) (*httptest.Server, *int32) {
+		var hits int32
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(&hits, 1) <= slow {
+				arrived <- struct{}{}
+				select {
+				case <-r.Context().Done():
+				case <-time.After(10 * time.Second):
+				}
+				return
+			}
+			w.Write([]byte("ok"))
+		})), &hits
+	}
+
+	for _, tt := range []struct {
+		name           string
+		slow           int32
+		attempts       int
+		attemptTimeout time.Duration
+		deadline       time.Duration
+		wantHits       int32
+		wantErr        *TimeoutError
+	}{
+		{name: "slow attempts retried", slow: 2, attempts: 3, attemptTimeout: time.Second, wantHits: 3},
+		{name: "every attempt slow", slow: 10, attempts: 2, attemptTimeout: time.Second, wantHits: 2, wantErr: &TimeoutError{Attempts: 2, PerAttempt: true}},
+		{name: "overall deadline", slow: 10, attempts: 5, attemptTimeout: time.Minute, deadline: time.Second, wantHits: 1, wantErr: &TimeoutError{Attempts: 1}},
+		{name: "overall deadline across attempts", slow: 10, attempts: 50, attemptTimeout: time.Second, deadline: 2500 * time.Millisecond, wantErr: &TimeoutError{PerAttempt: false}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			arrived := make(chan struct{}, tt.slow)
+			srv, hits := newSlowServer(tt.slow, arrived)
+			defer srv.Close()
+			c := newClient(http.Client{}, nil)
+			clock := &fakeRetryClock{fakeClock: newFakeClock()}
+			c.clock = clock
+			ctx := context.Background()
+			if tt.deadline > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = clock.WithTimeout(ctx, tt.deadline)
+				defer cancel()
+			}
+
+			var got string
+			done := make(chan error, 1)
+			go func() {
+				done <- c.Get(ctx, srv.URL, WithRetries(tt.attempts, WithAttemptTimeout(tt.attemptTimeout)), WithStringResponse(&got))
+			}()
+			// Each stalled attempt waits for the clock to pass the nearest deadline.
+			step := tt.attemptTimeout
+			if tt.deadline > 0 && tt.deadline < step {
+				step = tt.deadline
+			}
+			var err error
+		wait:
+			for {
+				select {
+				case <-arrived:
+					clock.Advance(step)
+				case err = <-done:
+					break wait
+				case <-time.After(5 * time.Second):
+					t.Fatal("Get() did not return, want the timeouts to end it")
+				}
+			}
+			if tt.wantErr == nil {
+				if err != nil || got != "ok" {
+					t.Errorf("Get() = %q, %v, want %q", got, err, "ok")
+				}
+			} else {
+				var te *TimeoutError
+				if !errors.As(err, &te) || te.PerAttempt != tt.wantErr.PerAttempt || tt.wantErr.Attempts != 0 && te.Attempts != tt.wantErr.Attempts {
+					t.Errorf("Get() error = %v, want a *TimeoutError like %+v", err, *tt.wantErr)
+				} else if !errors.Is(err, context.DeadlineExceeded) {
+					t.Errorf("Get() error = %v, want it to wrap context.DeadlineExceeded", err)
+				}
+			}
+			if n := atomic.LoadInt32(hits); tt.wantHits != 0 && n != tt.wantHits {
+				t.Errorf("server got %d requests, want %d", n, tt.wantHits)
+			}
+		})
+	}
+}
+
+func TestTimeoutErrorMessage(t *testing.T) {
+	t.Parallel()
+	for _, tt := range []struct {
+		err  *TimeoutError
+		want string
+	}{
+		{err: &TimeoutError{Attempts: 1, Err: context.DeadlineExceeded}, want: "request deadline exceeded after 1 attempt: context deadline exceeded"},
+		{err: &TimeoutError{Attempts: 3, PerAttempt: true, Err: context.DeadlineExceeded}, want: "attempt timeout exceeded after 3 attempts: context deadline exceeded"},
+	} {
+		if got := tt.err.Error(); got != tt.want {
+			t.Errorf("Error() = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestWithAttemptTimeoutRetryIf(t *testing.T) {
+	t.Parallel()
+	for _, tt := range []struct {
+		name         string
+		retry        bool
+		wantAttempts int32
+	}{
+		{name: "retried", retry: true, wantAttempts: 3},
+		{name: "not retried", retry: false, wantAttempts: 1},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts int32
+			clock := &fakeRetryClock{fakeClock: newFakeClock()}
+			c := newClient(http.Client{Transport: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+				atomic.AddInt32(&attempts, 1)
+				clock.Advance(time.Second)
+				<-r.Context().Done()
+				return nil, r.Context().Err()
+			})}, nil)
+			c.clock = clock
+
+			var sawTimeout bool
+			retryIf := func(resp *http.Response, err error) bool {
+				sawTimeout = errors.Is(err, ErrAttemptTimeout)
+				return tt.retry
+			}
+			err := c.Get(context.Background(), "http://example.com", WithRetries(3, WithAttemptTimeout(time.Second), WithRetryIf(retryIf)))
+			if !errors.Is(err, context.DeadlineExceeded) {
+				t.Errorf("Get() error = %v, want it to wrap context.DeadlineExceeded", err)
+			}
+			if !sawTimeout {
+				t.Error("WithRetryIf got an error not matching ErrAttemptTimeout")
+			}
+			if n := atomic.LoadInt32(&attempts); n != tt.wantAttempts {
+				t.Errorf("transport got %d attempts, want %d", n, tt.wantAttempts)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package http
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+//
+// RetryBudget limits retries to a share of the requests made, so that a
+// struggling server is not sent a wave of retries on top of its regular
+// load.
+//
+// While retries stay under ratio times the requests, each is allowed. Past
+// that, a retry is allowed with the probability that keeps the share near
+// ratio. Both counts decay over window, so the budget follows recent
+// traffic. A RetryBudget is safe for concurrent use.
+//
+type RetryBudget struct {
+	ratio  float64
+	window time.Duration
+	random func() float64
+
+	mu       sync.Mutex
+	last     time.Time
+	requests float64
+	retries  float64
+}
+
+// NewRetryBudget returns a budget allowing retries for about ratio of the requests made in the last window.
+func NewRetryBudget(ratio float64, window time.Duration) *RetryBudget {
+	return &RetryBudget{ratio: ratio, window: window, random: rand.Float64}
+}
+
+func (b *RetryBudget) describe() string {
+	return fmt.Sprintf("budget of %g%% of requests retried over %v", b.ratio*100, b.window)
+}
+
+// addRequest counts a request made at now.
+func (b *RetryBudget) addRequest(now time.Time) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.decay(now)
+	b.requests++
+}
+
+// allowRetry reports whether a retry at now fits the budget, counting it if so.
+func (b *RetryBudget) allowRetry(now time.Time) bool {
+	if b == nil {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.decay(now)
+	allowed := b.ratio * b.requests
+	if b.retries+1 > allowed && b.random() >= allowed/(b.retries+1) {
+		return false
+	}
+	b.retries++
+	return true
+}
+
+// decay ages the counts to now.
+func (b *RetryBudget) decay(now time.Time) {
+	if b.window > 0 && !b.last.IsZero() && now.After(b.last) {
+		f := math.Exp(-float64(now.Sub(b.last)) / float64(b.window))
+		b.requests *= f
+		b.retries *= f
+	}
+	if now.After(b.last) {
+		b.last = now
+	}
+}
@@ -0,0 +1,74 @@
+package http
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+)
+
+//
+// WithRootCAsFile will also trust the CA certificates in the PEM file at
+// path to verify servers, on top of the system roots unless
+// WithoutSystemRootCAs is given. It can be given more than once.
+//
+// NewClient panics if the file cannot be read or holds no certificate.
+//
+func WithRootCAsFile(path string) ClientOption {
+	return func(c *clientConfig) {
+		c.rootCAs = append(c.rootCAs, rootCAs{name: path, read: func() ([]byte, error) {
+			return ioutil.ReadFile(path)
+		}})
+	}
+}
+
+// WithRootCAsPEM will also trust the PEM CA certificates in pem to verify servers, like WithRootCAsFile.
+func WithRootCAsPEM(pem []byte) ClientOption {
+	return func(c *clientConfig) {
+		c.rootCAs = append(c.rootCAs, rootCAs{name: "PEM", read: func() ([]byte, error) {
+			return pem, nil
+		}})
+	}
+}
+
+// WithoutSystemRootCAs will only trust the WithRootCAsFile and WithRootCAsPEM certificates, not the system roots.
+func WithoutSystemRootCAs() ClientOption {
+	return func(c *clientConfig) {
+		c.noSystemRootCAs = true
+	}
+}
+
+// rootCAs is a source of PEM root CA certificates.
+type rootCAs struct {
+	name string
+	read func() ([]byte, error)
+}
+
+// useRootCAs configures the transport of c to verify servers against the configured root CAs.
+func (c *client) useRootCAs() {
+	pool := x509.NewCertPool()
+	if !c.config.noSystemRootCAs {
+		// The system pool is unavailable on some platforms, leaving only the given roots.
+		if system, err := x509.SystemCertPool(); err == nil {
+			pool = system
+		}
+	}
+	for _, src := range c.config.rootCAs {
+		pem, err := src.read()
+		if err != nil {
+			panic(fmt.Sprintf("reading root CAs: %v", err))
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			panic(fmt.Sprintf("no PEM root CA certificates found in %s", src.name))
+		}
+	}
+
+	t := c.cloneTransport("WithRootCAsFile")
+	config := &tls.Config{}
+	if t.TLSClientConfig != nil {
+		config = t.TLSClientConfig.Clone()
+	}
+	config.RootCAs = pool
+	t.TLSClientConfig = config
+	c.client.Transport = t
+}
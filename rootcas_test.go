@@ -0,0 +1,75 @@
+package http
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newCATLSServer starts a TLS server for 127.0.0.1 and localhost with a certificate signed by ca.
+func newCATLSServer(t *testing.T, ca *testCA) *httptest.Server {
+	t.Helper()
+	certPEM, keyPEM := ca.issue(t, x509.ExtKeyUsageServerAuth)
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	srv.TLS = &tls.Config{Certificates: []tls.Certificate{cert}}
+	srv.StartTLS()
+	return srv
+}
+
+func TestWithRootCAs(t *testing.T) {
+	t.Parallel()
+	ca := newTestCA(t, "private-ca")
+	srv := newCATLSServer(t, ca)
+	defer srv.Close()
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	if err := ioutil.WriteFile(caFile, ca.pem, 0600); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	if err := NewClient().Get(ctx, srv.URL); err == nil {
+		t.Errorf("Get() without the private CA error = nil, want certificate error")
+	}
+	for name, cli := range map[string]Client{
+		"file":       NewClient(WithRootCAsFile(caFile)),
+		"PEM":        NewClient(WithRootCAsPEM(ca.pem)),
+		"replace":    NewClient(WithRootCAsPEM(ca.pem), WithoutSystemRootCAs()),
+		"TLS client": NewTLSClient(&tls.Config{MinVersion: tls.VersionTLS12}, WithRootCAsFile(caFile)),
+	} {
+		if err := cli.Get(ctx, srv.URL); err != nil {
+			t.Errorf("%s: Get() error = %v", name, err)
+		}
+	}
+}
+
+func TestWithRootCAs_errors(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	for _, tt := range []struct {
+		name       string
+		opt        ClientOption
+		wantErrStr string
+	}{
+		{name: "missing file", opt: WithRootCAsFile(filepath.Join(dir, "missing.pem")), wantErrStr: "missing.pem"},
+		{name: "no certificates", opt: WithRootCAsPEM([]byte("not PEM")), wantErrStr: "no PEM root CA certificates found in PEM"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if p, _ := recover().(string); !strings.Contains(p, tt.wantErrStr) {
+					t.Errorf("NewClient() panic = %q, want containing %q", p, tt.wantErrStr)
+				}
+			}()
+			NewClient(tt.opt)
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// verifyHMAC checks a request signed by HMACSigner the way a partner server would.
+func verifyHMAC(r *http.Request, secret []byte) error {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return err
+	}
+	timestamp := r.Header.Get("X-Timestamp")
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || time.Since(time.Unix(unix, 0)) > time.Minute {
+		return errors.New("stale or missing timestamp")
+	}
+	want := hmacSignature(secret, r.Method, r.URL.RequestURI(), timestamp, body)
+	if !hmac.Equal([]byte(r.Header.Get("X-Signature")), []byte(want)) {
+		return errors.New("bad signature")
+	}
+	return nil
+}
+
+func TestHMACSigner(t *testing.T) {
+	t.Parallel()
+	secret := []byte("partner-secret")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := verifyHMAC(r, secret); err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+		}
+	}))
+	defer srv.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+	cli := NewClient()
+	sign := WithRequestSigner(HMACSigner(secret))
+
+	if err := cli.Post(ctx, srv.URL+"/orders", WithParam("dry", "1"), WithJSONBody(map[string]int{"qty": 2}), sign); err != nil {
+		t.Errorf("Post() error = %v, want the signature accepted", err)
+	}
+	if err := cli.Get(ctx, srv.URL+"/orders", sign); err != nil {
+		t.Errorf("Get() error = %v, want the signature accepted", err)
+	}
+	var bse *BadStatusError
+	if err := cli.Get(ctx, srv.URL, WithRequestSigner(HMACSigner([]byte("wrong")))); !errors.As(err, &bse) || bse.Code != http.StatusUnauthorized {
+		t.Errorf("Get() error = %v, want 401 for the wrong secret", err)
+	}
+
+	stream := struct{ *bytes.Buffer }{bytes.NewBufferString("streamed")}
+	if err := cli.Post(ctx, srv.URL, WithBodyReader(stream, -1), sign); !errors.Is(err, ErrUnsignableBody) {
+		t.Errorf("Post() error = %v, want %v", err, ErrUnsignableBody)
+	}
+}
+
+func TestWithRequestSigner_attempts(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	// stamp signs each attempt with its count and the Authorization it covers.
+	newStamp := func() RequestSigner {
+		var n int32
+		return func(r *http.Request, body []byte) error {
+			r.Header.Set("X-Signed-Attempt", strconv.Itoa(int(atomic.AddInt32(&n, 1))))
+			r.Header.Set("X-Signed-Authorization", r.Header.Get("Authorization"))
+			return nil
+		}
+	}
+	var mu sync.Mutex
+	var got []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		got = append(got, r.Header.Get("X-Signed-Attempt"))
+		n := len(got)
+		mu.Unlock()
+		if r.Header.Get("X-Signed-Authorization") != r.Header.Get("Authorization") {
+			t.Errorf("attempt %d sent Authorization %q, signed %q", n, r.Header.Get("Authorization"), r.Header.Get("X-Signed-Authorization"))
+		}
+		switch {
+		case r.URL.Path == "/flaky" && n == 1:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case r.URL.Path == "/auth" && r.Header.Get("Authorization") != "Bearer token-2":
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer srv.Close()
+
+	t.Run("retry", func(t *testing.T) {
+		got = nil
+		c := newClient(http.Client{}, []ClientOption{WithRetry(RetryPolicy{MaxAttempts: 3})})
+		fakeSleeper(c)
+		if err := c.Get(ctx, srv.URL+"/flaky", WithRequestSigner(newStamp())); err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+		if strings.Join(got, ",") != "1,2" {
+			t.Errorf("server got attempts signed %q, want each attempt signed again", got)
+		}
+	})
+
+	t.Run("reauth", func(t *testing.T) {
+		got = nil
+		var token atomic.Value
+		token.Store("token-1")
+		c := NewClient(
+			WithTokenSource(func(ctx context.Context) (string, error) { return token.Load().(string), nil }),
+			WithReauth(func(ctx context.Context) error {
+				token.Store("token-2")
+				return nil
+			}))
+		if err := c.Get(ctx, srv.URL+"/auth", WithRequestSigner(newStamp())); err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+		if strings.Join(got, ",") != "1,2" {
+			t.Errorf("server got attempts signed %q, want the replay signed again", got)
+		}
+	})
+}
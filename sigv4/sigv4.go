@@ -0,0 +1,212 @@
+// Package sigv4 signs gohttp requests with AWS Signature Version 4.
+package sigv4
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	nethttp "net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	http "github.com/alphaflow/gohttp"
+)
+
+// UnsignedPayload is the payload hash of requests whose streamed body cannot be read twice.
+const UnsignedPayload = "UNSIGNED-PAYLOAD"
+
+// Credentials are the AWS credentials a request is signed with.
+type Credentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	// SessionToken is sent as X-Amz-Security-Token for temporary credentials.
+	SessionToken string
+}
+
+// CredentialsProvider returns the credentials to sign a request with, fetching or refreshing them as needed.
+type CredentialsProvider interface {
+	Retrieve(ctx context.Context) (Credentials, error)
+}
+
+// StaticCredentials is a CredentialsProvider always returning itself.
+type StaticCredentials Credentials
+
+func (sc StaticCredentials) Retrieve(ctx context.Context) (Credentials, error) {
+	return Credentials(sc), nil
+}
+
+//
+// WithSigV4 will sign the request for service in region with credentials
+// from creds, setting its X-Amz-Date, X-Amz-Content-Sha256 and Authorization
+// headers.
+//
+// The body is hashed if it can be read twice, as a WithJSONBody body can;
+// a streamed WithBodyReader body is sent as UNSIGNED-PAYLOAD instead, which
+// only some services, such as S3, accept.
+//
+// Every attempt of the request is signed again, with the time it is sent,
+// so retries do not carry an X-Amz-Date AWS has come to reject.
+//
+func WithSigV4(creds CredentialsProvider, region, service string) http.RequestOption {
+	return withSigV4(creds, region, service, time.Now)
+}
+
+// withSigV4 is WithSigV4 signing with the time now returns.
+func withSigV4(creds CredentialsProvider, region, service string, now func() time.Time) http.RequestOption {
+	return http.WithRequestSigner(func(r *nethttp.Request, body []byte) error {
+		c, err := creds.Retrieve(r.Context())
+		if err != nil {
+			return fmt.Errorf("retrieving AWS credentials: %w", err)
+		}
+		hash := payloadHash(body)
+		r.Header.Set("X-Amz-Content-Sha256", hash)
+		s := signer{creds: c, region: region, service: service}
+		s.sign(r, hash, now())
+		return nil
+	})
+}
+
+// payloadHash returns the hex SHA-256 of body, or UnsignedPayload for a streamed body.
+func payloadHash(body []byte) string {
+	if body == nil {
+		return UnsignedPayload
+	}
+	return hexSHA256(string(body))
+}
+
+// ignoredHeaders are left unsigned, as proxies and the transport may change them.
+var ignoredHeaders = map[string]bool{
+	"authorization":   true,
+	"user-agent":      true,
+	"x-amzn-trace-id": true,
+	"expect":          true,
+}
+
+type signer struct {
+	creds   Credentials
+	region  string
+	service string
+}
+
+// sign sets the X-Amz-Date and Authorization headers of r, signing every other header it has.
+func (s signer) sign(r *nethttp.Request, payloadHash string, t time.Time) {
+	t = t.UTC()
+	amzDate := t.Format("20060102T150405Z")
+	r.Header.Set("X-Amz-Date", amzDate)
+	if s.creds.SessionToken != "" {
+		r.Header.Set("X-Amz-Security-Token", s.creds.SessionToken)
+	}
+
+	signedHeaders, canonicalHeaders := s.canonicalHeaders(r)
+	canonicalRequest := strings.Join([]string{
+		r.Method,
+		s.canonicalURI(r.URL),
+		canonicalQuery(r.URL),
+		canonicalHeaders,
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := strings.Join([]string{t.Format("20060102"), s.region, s.service, "aws4_request"}, "/")
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope, hexSHA256(canonicalRequest)}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.creds.SecretAccessKey), t.Format("20060102"))
+	for _, part := range []string{s.region, s.service, "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	r.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s", s.creds.AccessKeyID, scope, signedHeaders, signature))
+}
+
+// canonicalURI returns the path of u, escaped once more for services other than S3.
+func (s signer) canonicalURI(u *url.URL) string {
+	path := u.EscapedPath()
+	if path == "" {
+		return "/"
+	}
+	if s.service == "s3" {
+		return path
+	}
+	return uriEncode(path, false)
+}
+
+func canonicalQuery(u *url.URL) string {
+	query := u.Query()
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var pairs []string
+	for _, k := range keys {
+		values := append([]string(nil), query[k]...)
+		sort.Strings(values)
+		for _, v := range values {
+			pairs = append(pairs, uriEncode(k, true)+"="+uriEncode(v, true))
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+// canonicalHeaders returns the signed header names and the canonical header block of r, including Host.
+func (s signer) canonicalHeaders(r *nethttp.Request) (string, string) {
+	headers := map[string][]string{}
+	for k, vs := range r.Header {
+		if k = strings.ToLower(k); !ignoredHeaders[k] {
+			headers[k] = append(headers[k], vs...)
+		}
+	}
+	host := r.Host
+	if host == "" {
+		host = r.URL.Host
+	}
+	headers["host"] = []string{host}
+
+	names := make([]string, 0, len(headers))
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, k := range names {
+		values := make([]string, len(headers[k]))
+		for i, v := range headers[k] {
+			values[i] = strings.Join(strings.Fields(v), " ")
+		}
+		b.WriteString(k + ":" + strings.Join(values, ",") + "\n")
+	}
+	return strings.Join(names, ";"), b.String()
+}
+
+// uriEncode escapes s as SigV4 requires: every byte except unreserved characters, and '/' unless encodeSlash.
+func uriEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || c == '-' || c == '_' || c == '.' || c == '~' || c == '/' && !encodeSlash {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func hexSHA256(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	io.WriteString(h, data)
+	return h.Sum(nil)
+}
@@ -0,0 +1,161 @@
+package sigv4
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	nethttp "net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	http "github.com/alphaflow/gohttp"
+)
+
+// exampleCreds are the credentials of the AWS Signature Version 4 test suite.
+var exampleCreds = Credentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"}
+
+func TestSigner_test_suite(t *testing.T) {
+	t.Parallel()
+	date := time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		method  string
+		url     string
+		header  map[string]string
+		body    string
+		service string
+		want    string
+	}{
+		{
+			name:    "get-vanilla",
+			method:  "GET",
+			url:     "https://example.amazonaws.com/",
+			service: "service",
+			want:    "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=host;x-amz-date, Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31",
+		},
+		{
+			name:    "get-vanilla-query-order-key-case",
+			method:  "GET",
+			url:     "https://example.amazonaws.com/?Param2=value2&Param1=value1",
+			service: "service",
+			want:    "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=host;x-amz-date, Signature=b97d918cfa904a5beff61c982a1b6f458b799221646efd99d3219ec94cdf2500",
+		},
+		{
+			name:    "post-x-www-form-urlencoded",
+			method:  "POST",
+			url:     "https://example.amazonaws.com/",
+			header:  map[string]string{"Content-Type": "application/x-www-form-urlencoded"},
+			body:    "Param1=value1",
+			service: "service",
+			want:    "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=content-type;host;x-amz-date, Signature=ff11897932ad3f4e8b18135d722051e5ac45fc38421b1da7b9d196a0fe09473a",
+		},
+		{
+			name:    "iam-list-users",
+			method:  "GET",
+			url:     "https://iam.amazonaws.com/?Action=ListUsers&Version=2010-05-08",
+			header:  map[string]string{"Content-Type": "application/x-www-form-urlencoded; charset=utf-8"},
+			service: "iam",
+			want:    "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/iam/aws4_request, SignedHeaders=content-type;host;x-amz-date, Signature=5d672d79c15b13162d9279b0855cfba6789a8edb4c82c400e06b5924a6f2b5d7",
+		},
+	}
+	for _, tt := range tests {
+		r, err := nethttp.NewRequest(tt.method, tt.url, strings.NewReader(tt.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for k, v := range tt.header {
+			r.Header.Set(k, v)
+		}
+		hash := payloadHash([]byte(tt.body))
+		signer{creds: exampleCreds, region: "us-east-1", service: tt.service}.sign(r, hash, date)
+		if got := r.Header.Get("Authorization"); got != tt.want {
+			t.Errorf("%s: Authorization = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestWithSigV4(t *testing.T) {
+	t.Parallel()
+	var got *nethttp.Request
+	srv := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		got = r
+	}))
+	defer srv.Close()
+	ctx := context.Background()
+	creds := StaticCredentials{AccessKeyID: "AKID", SecretAccessKey: "secret", SessionToken: "session"}
+	cli := http.NewClient()
+
+	body := map[string]string{"hello": "world"}
+	if err := cli.Post(ctx, srv.URL+"/items", http.WithParam("b", "2"), http.WithJSONBody(body), WithSigV4(creds, "eu-west-1", "execute-api")); err != nil {
+		t.Fatalf("Post() error = %v", err)
+	}
+	sum := sha256.Sum256([]byte(`{"hello":"world"}`))
+	if h := got.Header.Get("X-Amz-Content-Sha256"); h != hex.EncodeToString(sum[:]) {
+		t.Errorf("X-Amz-Content-Sha256 = %s, want the body hash", h)
+	}
+	auth := got.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKID/") || !strings.Contains(auth, "/eu-west-1/execute-api/aws4_request") ||
+		!strings.Contains(auth, "SignedHeaders=content-type;host;x-amz-content-sha256;x-amz-date;x-amz-security-token,") {
+		t.Errorf("Authorization = %s, want a SigV4 signature over the final request", auth)
+	}
+	if got.Header.Get("X-Amz-Date") == "" || got.Header.Get("X-Amz-Security-Token") != "session" {
+		t.Errorf("headers = %v, want X-Amz-Date and X-Amz-Security-Token", got.Header)
+	}
+
+	if err := cli.Post(ctx, srv.URL, http.WithBodyReader(struct{ *bytes.Buffer }{bytes.NewBufferString("stream")}, -1), WithSigV4(creds, "eu-west-1", "s3")); err != nil {
+		t.Fatalf("Post() error = %v", err)
+	}
+	if h := got.Header.Get("X-Amz-Content-Sha256"); h != UnsignedPayload {
+		t.Errorf("X-Amz-Content-Sha256 = %s, want %s for a streamed body", h, UnsignedPayload)
+	}
+}
+
+type failingProvider struct{ err error }
+
+func (fp failingProvider) Retrieve(ctx context.Context) (Credentials, error) {
+	return Credentials{}, fp.err
+}
+
+func TestWithSigV4_credentials_error(t *testing.T) {
+	t.Parallel()
+	errExpired := errors.New("credentials expired")
+	err := http.NewClient().Get(context.Background(), "http://example.invalid", WithSigV4(failingProvider{errExpired}, "us-east-1", "s3"))
+	if !errors.Is(err, errExpired) {
+		t.Errorf("Get() error = %v, want %v", err, errExpired)
+	}
+}
+
+func TestWithSigV4_retry(t *testing.T) {
+	t.Parallel()
+	var dates, signatures []string
+	srv := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		dates = append(dates, r.Header.Get("X-Amz-Date"))
+		signatures = append(signatures, r.Header.Get("Authorization"))
+		if len(dates) == 1 {
+			w.WriteHeader(nethttp.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	// Each signature is made ten minutes after the last, past the five AWS allows.
+	signedAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := func() time.Time {
+		signedAt = signedAt.Add(10 * time.Minute)
+		return signedAt
+	}
+	cli := http.NewClient(http.WithRetry(http.RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond}))
+	if err := cli.Get(context.Background(), srv.URL+"/items", withSigV4(StaticCredentials(exampleCreds), "us-east-1", "execute-api", now)); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	want := []string{"20240101T001000Z", "20240101T002000Z"}
+	if len(dates) != 2 || dates[0] != want[0] || dates[1] != want[1] {
+		t.Errorf("X-Amz-Date of the attempts = %q, want %q", dates, want)
+	}
+	if len(signatures) != 2 || signatures[0] == signatures[1] {
+		t.Errorf("Authorization of the attempts = %q, want the retry signed again", signatures)
+	}
+}
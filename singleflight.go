@@ -0,0 +1,160 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+//
+// WithSingleflight will share one response among concurrent GET requests
+// for the same URL and headers, sending only the first of them. Each
+// request decodes its own copy of the body, so every JSONOutput gets its
+// own value.
+//
+// Requests streaming their response, with WithResponse, WithRawResponse,
+// WithTeeResponse, WithErrorBody or a response callback, are sent on
+// their own, and so are requests with a body, WithRetries or
+// WithRequestSigner. The shared request goes on while any request waiting
+// for it does, so canceling one of them only fails that one. Client.Close
+// cancels it.
+//
+// The shared body is buffered, up to the WithMaxResponseBytes limit or
+// 10 MiB; a longer one fails the requests with a *ResponseTooLargeError.
+//
+func WithSingleflight() ClientOption {
+	return func(c *clientConfig) {
+		c.singleflight = &flightGroup{calls: map[string]*flightCall{}}
+	}
+}
+
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall
+}
+
+type flightCall struct {
+	done    chan struct{}
+	resp    *http.Response
+	body    []byte
+	err     error
+	waiters int
+	cancel  context.CancelFunc
+}
+
+// sendShared sends r like send, sharing the response with identical concurrent requests under WithSingleflight.
+func (c *client) sendShared(ctx context.Context, req *Request, r *http.Request) (*http.Response, error) {
+	if c.config.singleflight == nil || !req.shareable() {
+		return c.send(ctx, req, r)
+	}
+	limit := int64(maxSharedBodyBytes)
+	if req.maxResponseBytes > 0 {
+		limit = req.maxResponseBytes
+	}
+	call, err := c.config.singleflight.do(ctx, &c.tasks, flightKey(req, r), func(ctx context.Context) (*http.Response, []byte, error) {
+		resp, err := c.send(ctx, req, r.WithContext(ctx))
+		if err != nil {
+			return nil, nil, err
+		}
+		body, ok, err := bufferBody(resp, limit)
+		if err == nil && !ok {
+			resp.Body.Close()
+			err = &ResponseTooLargeError{Limit: limit}
+		}
+		return resp, body, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	resp := *call.resp
+	resp.Header = call.resp.Header.Clone()
+	resp.Trailer = call.resp.Trailer.Clone()
+	resp.Body = ioutil.NopCloser(bytes.NewReader(call.body))
+	resp.Request = r
+	return &resp, nil
+}
+
+// maxSharedBodyBytes bounds the body of a shared response without WithMaxResponseBytes.
+const maxSharedBodyBytes = 10 << 20
+
+// shareable reports whether the response to req can be shared with other requests.
+// Requests with a body, their own retry policy or signers, which cannot be compared, are sent on their own.
+func (req *Request) shareable() bool {
+	return req.Method == http.MethodGet && req.Output == nil && req.RawResponse == nil && req.ResponseCallback == nil &&
+		len(req.teeWriters) == 0 && req.errorBody == nil && req.Body == nil && req.BodyReader == nil && req.retries == nil && len(req.signers) == 0
+}
+
+// flightKey identifies the requests sharing a response: those with the same method, URL, headers and sending options.
+func flightKey(req *Request, r *http.Request) string {
+	var b strings.Builder
+	b.WriteString(r.Method + " " + r.URL.String() + "\n")
+	fmt.Fprintf(&b, "noRedirects=%t maxResponseBytes=%d", req.noRedirects, req.maxResponseBytes)
+	if req.hedging != nil {
+		fmt.Fprintf(&b, " hedging=%v/%d", req.hedging.delay, req.hedging.maxHedges)
+	}
+	b.WriteString("\n")
+	keys := make([]string, 0, len(r.Header))
+	for k := range r.Header {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		b.WriteString(k + ": " + strings.Join(r.Header[k], ", ") + "\n")
+	}
+	return b.String()
+}
+
+// do returns the result of the call for key, making it with send in a task of tasks unless one is in flight.
+func (g *flightGroup) do(ctx context.Context, tasks *taskRegistry, key string, send func(context.Context) (*http.Response, []byte, error)) (*flightCall, error) {
+	g.mu.Lock()
+	call := g.calls[key]
+	if call == nil {
+		// The call outlives the request starting it, as long as any request waits for it or the client is open.
+		callCtx, cancel := context.WithCancel(detachedContext{ctx})
+		call = &flightCall{done: make(chan struct{}), cancel: cancel}
+		g.calls[key] = call
+		err := tasks.start("singleflight", func(taskCtx context.Context) {
+			stop := context.AfterFunc(taskCtx, cancel)
+			defer stop()
+			call.resp, call.body, call.err = send(callCtx)
+			g.mu.Lock()
+			if g.calls[key] == call {
+				delete(g.calls, key)
+			}
+			g.mu.Unlock()
+			cancel()
+			close(call.done)
+		})
+		if err != nil {
+			delete(g.calls, key)
+			g.mu.Unlock()
+			cancel()
+			return nil, err
+		}
+	}
+	call.waiters++
+	g.mu.Unlock()
+
+	select {
+	case <-call.done:
+		if call.err != nil {
+			return nil, call.err
+		}
+		return call, nil
+	case <-ctx.Done():
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		if call.waiters--; call.waiters == 0 {
+			call.cancel()
+			if g.calls[key] == call {
+				delete(g.calls, key)
+			}
+		}
+		return nil, ctx.Err()
+	}
+}
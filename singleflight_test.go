@@ -0,0 +1,206 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// waitForWaiters waits until the only call in flight in g has n requests waiting for it.
+func waitForWaiters(t *testing.T, g *flightGroup, n int) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		g.mu.Lock()
+		var waiters int
+		for _, call := range g.calls {
+			waiters = call.waiters
+		}
+		g.mu.Unlock()
+		if waiters == n {
+			return
+		}
+	}
+	t.Fatalf("%d requests never all waited for the shared call", n)
+}
+
+func TestWithSingleflight(t *testing.T) {
+	t.Parallel()
+	var hits int32
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		<-release
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"items": [1, 2, 3]}`))
+	}))
+	defer srv.Close()
+	c := newClient(http.Client{}, []ClientOption{WithSingleflight()})
+
+	const n = 20
+	results := make([]struct{ Items []int }, n)
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = c.Get(context.Background(), srv.URL+"/list", WithParam("page", "1"), WithJSONResponse(&results[i]))
+		}(i)
+	}
+	waitForWaiters(t, c.config.singleflight, n)
+	close(release)
+	wg.Wait()
+
+	if got := atomic.LoadInt32(&hits); got != 1 {
+		t.Errorf("server got %d requests, want 1", got)
+	}
+	for i := range results {
+		if errs[i] != nil || len(results[i].Items) != 3 {
+			t.Errorf("Get() #%d = %v, %v, want 3 items", i, results[i], errs[i])
+		}
+	}
+	// Each caller decoded its own value.
+	results[0].Items[0] = 42
+	if results[1].Items[0] != 1 {
+		t.Error("callers share one decoded value, want their own")
+	}
+}
+
+func TestWithSingleflightBypass(t *testing.T) {
+	t.Parallel()
+	var hits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		time.Sleep(50 * time.Millisecond)
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+	c := NewClient(WithSingleflight())
+
+	for _, tt := range []struct {
+		name    string
+		method  string
+		options func() []RequestOption
+	}{
+		{name: "POST", method: http.MethodPost, options: func() []RequestOption { return nil }},
+		{name: "Output writer", method: http.MethodGet, options: func() []RequestOption { return []RequestOption{WithResponse(&bytes.Buffer{})} }},
+		{name: "different headers", method: http.MethodGet, options: func() []RequestOption {
+			return []RequestOption{WithHeader("X-Tenant", time.Now().String())}
+		}},
+		{name: "GET body", method: http.MethodGet, options: func() []RequestOption {
+			return []RequestOption{WithBodyReader(strings.NewReader("query"), 5)}
+		}},
+		{name: "WithRetries", method: http.MethodGet, options: func() []RequestOption { return []RequestOption{WithRetries(2)} }},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			atomic.StoreInt32(&hits, 0)
+			var wg sync.WaitGroup
+			for i := 0; i < 3; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					c.Do(context.Background(), tt.method, srv.URL, tt.options()...)
+				}()
+			}
+			wg.Wait()
+			if got := atomic.LoadInt32(&hits); got != 3 {
+				t.Errorf("server got %d requests, want 3", got)
+			}
+		})
+	}
+}
+
+func TestWithSingleflightCancel(t *testing.T) {
+	t.Parallel()
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+	defer close(release)
+	c := newClient(http.Client{}, []ClientOption{WithSingleflight()})
+
+	// The request that started the shared call gives up; the other still gets the response.
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan error)
+	go func() { first <- c.Get(ctx, srv.URL) }()
+	waitForWaiters(t, c.config.singleflight, 1)
+
+	var got string
+	second := make(chan error)
+	go func() { second <- c.Get(context.Background(), srv.URL, WithStringResponse(&got)) }()
+	waitForWaiters(t, c.config.singleflight, 2)
+
+	cancel()
+	if err := <-first; err != context.Canceled {
+		t.Errorf("canceled Get() error = %v, want context.Canceled", err)
+	}
+	release <- struct{}{}
+	if err := <-second; err != nil || got != "ok" {
+		t.Errorf("Get() = %q, %v, want the shared response", got, err)
+	}
+}
+
+func TestFlightKey(t *testing.T) {
+	t.Parallel()
+	c := newClient(http.Client{}, nil)
+	key := func(options ...RequestOption) string {
+		req, err := c.newRequest(http.MethodGet, "http://example.com/list", options)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r, err := req.prepareRequest(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return flightKey(req, r)
+	}
+	plain := key()
+	if again := key(); again != plain {
+		t.Errorf("flightKey() = %q, then %q, want identical requests to share a key", plain, again)
+	}
+	for name, options := range map[string][]RequestOption{
+		"WithNoRedirects":      {WithNoRedirects()},
+		"WithMaxResponseBytes": {WithMaxResponseBytes(10)},
+		"WithRequestHedging":   {WithRequestHedging(time.Millisecond, 1)},
+	} {
+		if got := key(options...); got == plain {
+			t.Errorf("flightKey() with %s = %q, want a key of its own", name, got)
+		}
+	}
+}
+
+func TestWithSingleflightMaxResponseBytes(t *testing.T) {
+	t.Parallel()
+	const size = 64 << 20
+	var written int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		chunk := bytes.Repeat([]byte("a"), 32<<10)
+		for atomic.LoadInt64(&written) < size {
+			n, err := w.Write(chunk)
+			atomic.AddInt64(&written, int64(n))
+			if err != nil {
+				return
+			}
+		}
+	}))
+	defer srv.Close()
+	c := NewClient(WithSingleflight())
+
+	var got string
+	var rtle *ResponseTooLargeError
+	if err := c.Get(context.Background(), srv.URL, WithMaxResponseBytes(1<<10), WithStringResponse(&got)); !errors.As(err, &rtle) {
+		t.Fatalf("Get() error = %v, want a *ResponseTooLargeError", err)
+	}
+	if n := atomic.LoadInt64(&written); n >= size {
+		t.Errorf("server wrote the whole %d byte body before the request failed, want the shared read bounded", n)
+	}
+}
@@ -0,0 +1,105 @@
+package http
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Event is a Server-Sent Event received by WithEventStream.
+type Event struct {
+	// ID is the last event ID of the stream, to send with WithLastEventID when reconnecting.
+	ID   string
+	Type string
+	Data string
+	// Retry is the reconnection time last requested by the server, or zero.
+	Retry time.Duration
+}
+
+//
+// WithEventStream will read the response as a text/event-stream and call
+// onEvent as each event arrives.
+//
+// The request returns when the stream ends, onEvent returns an error, or the
+// context is done. Reconnecting is left to the caller.
+//
+func WithEventStream(onEvent func(Event) error) RequestOption {
+	return func(r *Request) {
+		r.Header.Set("Accept", "text/event-stream")
+		r.Header.Set("Cache-Control", "no-cache")
+		r.ResponseCallback = func(status int, header http.Header, body io.Reader) error {
+			return readEventStream(body, onEvent)
+		}
+	}
+}
+
+// WithLastEventID will ask the server to resume an event stream after the event with this ID.
+func WithLastEventID(id string) RequestOption {
+	return func(r *Request) {
+		r.Header.Set("Last-Event-ID", id)
+	}
+}
+
+// readEventStream parses an event stream as specified by the HTML Living Standard.
+func readEventStream(body io.Reader, onEvent func(Event) error) error {
+	br := bufio.NewReader(body)
+	var (
+		event Event
+		data  strings.Builder
+	)
+	for first := true; ; first = false {
+		line, err := br.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+		if err == io.EOF && line == "" {
+			// An event without a terminating blank line is discarded.
+			return nil
+		}
+		line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+		if first {
+			line = strings.TrimPrefix(line, "\ufeff")
+		}
+
+		if line == "" {
+			if data.Len() > 0 {
+				event.Data = strings.TrimSuffix(data.String(), "\n")
+				if event.Type == "" {
+					event.Type = "message"
+				}
+				if err := onEvent(event); err != nil {
+					return err
+				}
+			}
+			event.Type, event.Data = "", ""
+			data.Reset()
+		} else if !strings.HasPrefix(line, ":") {
+			field, value := line, ""
+			if i := strings.IndexByte(line, ':'); i >= 0 {
+				field, value = line[:i], strings.TrimPrefix(line[i+1:], " ")
+			}
+			switch field {
+			case "event":
+				event.Type = value
+			case "data":
+				data.WriteString(value)
+				data.WriteByte('\n')
+			case "id":
+				if !strings.ContainsRune(value, 0) {
+					event.ID = value
+				}
+			case "retry":
+				if ms, err := strconv.ParseUint(value, 10, 63); err == nil {
+					event.Retry = time.Duration(ms) * time.Millisecond
+				}
+			}
+		}
+
+		if err == io.EOF {
+			return nil
+		}
+	}
+}
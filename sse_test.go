@@ -0,0 +1,91 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGet_event_stream_incremental(t *testing.T) {
+	t.Parallel()
+	received := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Last-Event-ID"); got != "41" {
+			t.Errorf("Last-Event-ID = %q, want 41", got)
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		for i := 0; i < 3; i++ {
+			io.WriteString(w, "id: "+string(rune('a'+i))+"\ndata: event\n\n")
+			w.(http.Flusher).Flush()
+			// The next event is only written once the client has seen this one.
+			select {
+			case <-received:
+			case <-time.After(time.Second):
+				t.Errorf("event %d was not delivered before the stream continued", i)
+				return
+			}
+		}
+	}))
+	defer srv.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var ids []string
+	err := NewClient().Get(ctx, srv.URL, WithLastEventID("41"), WithEventStream(func(e Event) error {
+		ids = append(ids, e.ID)
+		received <- struct{}{}
+		return nil
+	}))
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if want := []string{"a", "b", "c"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("Get() event IDs = %v, want %v", ids, want)
+	}
+}
+
+func TestReadEventStream(t *testing.T) {
+	t.Parallel()
+	stream := "\ufeff: comment\n" +
+		"event: progress\r\n" +
+		"data: line one\n" +
+		"data:line two\n" +
+		"id: 7\n" +
+		"retry: 2500\n" +
+		"\n" +
+		"data\n" +
+		"\n" +
+		"id\n" +
+		"\n" +
+		"data: unterminated"
+
+	var got []Event
+	err := readEventStream(strings.NewReader(stream), func(e Event) error {
+		got = append(got, e)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("readEventStream() error = %v", err)
+	}
+	want := []Event{
+		{ID: "7", Type: "progress", Data: "line one\nline two", Retry: 2500 * time.Millisecond},
+		{ID: "7", Type: "message", Data: "", Retry: 2500 * time.Millisecond},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("readEventStream() = %+v, want %+v", got, want)
+	}
+
+	errStop := errors.New("stop")
+	err = readEventStream(strings.NewReader("data: a\n\ndata: b\n\n"), func(e Event) error {
+		return errStop
+	})
+	if err != errStop {
+		t.Errorf("readEventStream() error = %v, want %v", err, errStop)
+	}
+}
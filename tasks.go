@@ -0,0 +1,147 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ErrClientClosed is returned when work is started on a Client after Close.
+var ErrClientClosed = errors.New("gohttp: client closed")
+
+// TaskState is the lifecycle state of a background task.
+type TaskState string
+
+const (
+	// TaskRunning is a task that has not been asked to stop.
+	TaskRunning TaskState = "running"
+	// TaskStopping is a task that has been asked to stop by Close but has not returned yet.
+	TaskStopping TaskState = "stopping"
+)
+
+// BackgroundTask describes a goroutine owned by a Client.
+type BackgroundTask struct {
+	Name  string
+	State TaskState
+}
+
+//
+// taskRegistry tracks the background goroutines of a client.
+//
+// Every goroutine is started through the registry, receives a context that
+// is cancelled by close, and removes itself from the registry when it
+// returns. The zero value is ready to use.
+//
+type taskRegistry struct {
+	mu     sync.Mutex
+	ctx    context.Context
+	cancel context.CancelFunc
+	tasks  map[*task]struct{}
+	closed bool
+}
+
+type task struct {
+	name string
+	done chan struct{}
+}
+
+func (tr *taskRegistry) init() {
+	if tr.ctx == nil {
+		tr.ctx, tr.cancel = context.WithCancel(context.Background())
+		tr.tasks = map[*task]struct{}{}
+	}
+}
+
+// start runs fn in a new goroutine registered under name.
+func (tr *taskRegistry) start(name string, fn func(ctx context.Context)) error {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	if tr.closed {
+		return ErrClientClosed
+	}
+	tr.init()
+
+	t := &task{name: name, done: make(chan struct{})}
+	tr.tasks[t] = struct{}{}
+	go func() {
+		defer func() {
+			tr.mu.Lock()
+			delete(tr.tasks, t)
+			tr.mu.Unlock()
+			close(t.done)
+		}()
+		fn(tr.ctx)
+	}()
+	return nil
+}
+
+// list returns the live tasks sorted by name.
+func (tr *taskRegistry) list() []BackgroundTask {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+
+	var state = TaskRunning
+	if tr.closed {
+		state = TaskStopping
+	}
+	var ret []BackgroundTask
+	for t := range tr.tasks {
+		ret = append(ret, BackgroundTask{Name: t.name, State: state})
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].Name < ret[j].Name })
+	return ret
+}
+
+//
+// close cancels the context of every task and waits for them to return.
+//
+// If ctx is done first, the error names the tasks that are still running.
+// Calling close more than once is allowed.
+//
+func (tr *taskRegistry) close(ctx context.Context) error {
+	tr.mu.Lock()
+	tr.closed = true
+	tr.init()
+	var tasks []*task
+	for t := range tr.tasks {
+		tasks = append(tasks, t)
+	}
+	tr.mu.Unlock()
+
+	tr.cancel()
+	for _, t := range tasks {
+		select {
+		case <-t.done:
+		case <-ctx.Done():
+			var names []string
+			for _, bt := range tr.list() {
+				names = append(names, bt.Name)
+			}
+			return fmt.Errorf("gohttp: background tasks still running (%s): %w", strings.Join(names, ", "), ctx.Err())
+		}
+	}
+	return nil
+}
+
+// TB is the subset of testing.TB used by the test helpers in this package.
+type TB interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+	Cleanup(func())
+}
+
+//
+// CheckNoBackgroundTasks reports a test error for every background task of c
+// that is still live.
+//
+// Call it after Close to verify that the client did not leak goroutines.
+//
+func CheckNoBackgroundTasks(t TB, c Client) {
+	t.Helper()
+	for _, bt := range c.BackgroundTasks() {
+		t.Errorf("background task %q is still %s", bt.Name, bt.State)
+	}
+}
@@ -0,0 +1,154 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type recordingTB struct {
+	errors []string
+}
+
+func (r *recordingTB) Helper() {}
+
+func (r *recordingTB) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func (r *recordingTB) Cleanup(func()) {}
+
+func TestClose_stopsBackgroundTasks(t *testing.T) {
+	t.Parallel()
+	c := NewClient().(*client)
+
+	started := make(chan struct{}, 3)
+	for _, name := range []string{"revalidate", "refresh", "probe"} {
+		if err := c.tasks.start(name, func(ctx context.Context) {
+			started <- struct{}{}
+			<-ctx.Done()
+		}); err != nil {
+			t.Fatalf("start(%s) error = %v", name, err)
+		}
+	}
+	for i := 0; i < 3; i++ {
+		<-started
+	}
+
+	want := []BackgroundTask{
+		{Name: "probe", State: TaskRunning},
+		{Name: "refresh", State: TaskRunning},
+		{Name: "revalidate", State: TaskRunning},
+	}
+	if got := c.BackgroundTasks(); !reflect.DeepEqual(got, want) {
+		t.Errorf("BackgroundTasks() = %v, want %v", got, want)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+	if err := c.Close(ctx); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	CheckNoBackgroundTasks(t, c)
+
+	if err := c.tasks.start("late", func(ctx context.Context) {}); err != ErrClientClosed {
+		t.Errorf("start() after Close error = %v, want %v", err, ErrClientClosed)
+	}
+}
+
+func TestClose_deadline(t *testing.T) {
+	t.Parallel()
+	c := NewClient().(*client)
+
+	release := make(chan struct{})
+	defer close(release)
+	c.tasks.start("stuck", func(ctx context.Context) {
+		<-release
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err := c.Close(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Close() error = %v, want deadline exceeded", err)
+	}
+
+	var tb recordingTB
+	CheckNoBackgroundTasks(&tb, c)
+	if want := []string{`background task "stuck" is still stopping`}; !reflect.DeepEqual(tb.errors, want) {
+		t.Errorf("CheckNoBackgroundTasks() reported %v, want %v", tb.errors, want)
+	}
+}
+
+func TestClose_stopsFeatureTasks(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+	src, _ := newTestFileSource(t, newTestCA(t, "ca"), time.Hour)
+	c := NewClient(WithCertificateSource(src), WithSingleflight()).(*client)
+
+	shared := make(chan error, 1)
+	go func() {
+		shared <- c.Get(context.Background(), srv.URL+"/shared")
+	}()
+	waitForWaiters(t, c.config.singleflight, 1)
+
+	want := []BackgroundTask{
+		{Name: "certificate-source", State: TaskRunning},
+		{Name: "singleflight", State: TaskRunning},
+	}
+	if got := c.BackgroundTasks(); !reflect.DeepEqual(got, want) {
+		t.Errorf("BackgroundTasks() = %v, want %v", got, want)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+	if err := c.Close(ctx); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	CheckNoBackgroundTasks(t, c)
+	if err := <-shared; !errors.Is(err, context.Canceled) {
+		t.Errorf("Get() shared error = %v, want canceled by Close", err)
+	}
+}
+
+func TestClose_stopsHedgeDiscard(t *testing.T) {
+	t.Parallel()
+	release := make(chan struct{})
+	var calls int32
+	transport := roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			// The first attempt loses, and is only discarded once it returns.
+			<-release
+			return nil, r.Context().Err()
+		}
+		return &http.Response{StatusCode: http.StatusOK, Body: ioutil.NopCloser(strings.NewReader("ok")), Request: r}, nil
+	})
+	c := newClient(http.Client{Transport: transport}, []ClientOption{WithHedging(time.Millisecond, 1)})
+
+	if err := c.Get(context.Background(), "http://example.com"); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	want := []BackgroundTask{{Name: "hedge-discard", State: TaskRunning}}
+	if got := c.BackgroundTasks(); !reflect.DeepEqual(got, want) {
+		t.Errorf("BackgroundTasks() = %v, want %v", got, want)
+	}
+
+	close(release)
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+	if err := c.Close(ctx); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	CheckNoBackgroundTasks(t, c)
+}
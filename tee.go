@@ -0,0 +1,59 @@
+package http
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+//
+// WithTeeResponse will also write the HTTP response body to w, alongside
+// the output consuming it, such as WithJSONResponse or WithResponse. It can
+// be given more than once to write to several writers.
+//
+// The whole body is written, after decompression, including the parts the
+// output leaves unread and the body of an unsuccessful response. A failing
+// writer fails the request.
+//
+func WithTeeResponse(w io.Writer) RequestOption {
+	return func(r *Request) {
+		r.teeWriters = append(r.teeWriters, w)
+	}
+}
+
+// teeReader writes everything read from r to the tee writers of a request.
+type teeReader struct {
+	r io.Reader
+	w io.Writer
+}
+
+func (req *Request) newTeeReader(body io.Reader) *teeReader {
+	if len(req.teeWriters) == 0 {
+		return nil
+	}
+	return &teeReader{r: body, w: io.MultiWriter(req.teeWriters...)}
+}
+
+func (t *teeReader) Read(p []byte) (int, error) {
+	n, err := t.r.Read(p)
+	if n > 0 {
+		if _, werr := t.w.Write(p[:n]); werr != nil {
+			return n, fmt.Errorf("writing response tee: %w", werr)
+		}
+	}
+	return n, err
+}
+
+// finish tees the rest of the body the output left unread.
+func (t *teeReader) finish() error {
+	_, err := io.Copy(ioutil.Discard, t)
+	return err
+}
+
+// teeErrorBody writes the body of an unsuccessful response to the tee writers.
+// The request fails with a *BadStatusError either way, so write errors are dropped.
+func (req *Request) teeErrorBody(buf []byte) {
+	for _, w := range req.teeWriters {
+		w.Write(buf)
+	}
+}
@@ -0,0 +1,40 @@
+package http
+
+import (
+	"net/http"
+	"time"
+)
+
+//
+// WithTLSHandshakeTimeout limits the time a TLS handshake may take, 10
+// seconds for http.DefaultTransport but none for NewTLSClient. Zero means
+// no limit.
+//
+// This option and the other transport timeouts configure a copy of the
+// transport like the connection pool options, and unlike WithTimeout do not
+// limit reading the response body, so long streams are unaffected.
+//
+func WithTLSHandshakeTimeout(d time.Duration) ClientOption {
+	return configureTransport("WithTLSHandshakeTimeout", func(t *http.Transport) {
+		t.TLSHandshakeTimeout = d
+	})
+}
+
+// WithResponseHeaderTimeout limits the time to wait for the response headers once the request is sent. Zero means no limit.
+func WithResponseHeaderTimeout(d time.Duration) ClientOption {
+	return configureTransport("WithResponseHeaderTimeout", func(t *http.Transport) {
+		t.ResponseHeaderTimeout = d
+	})
+}
+
+//
+// WithExpectContinueTimeout limits the time to wait for a 100 Continue
+// response to a request with an Expect: 100-continue header before sending
+// its body anyway, 1 second for http.DefaultTransport. Zero sends the body
+// without waiting.
+//
+func WithExpectContinueTimeout(d time.Duration) ClientOption {
+	return configureTransport("WithExpectContinueTimeout", func(t *http.Transport) {
+		t.ExpectContinueTimeout = d
+	})
+}
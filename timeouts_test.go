@@ -0,0 +1,76 @@
+package http
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWithResponseHeaderTimeout(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow-headers" {
+			time.Sleep(200 * time.Millisecond)
+			return
+		}
+		// Headers come at once, the body slowly.
+		for i := 0; i < 4; i++ {
+			w.Write([]byte("chunk"))
+			w.(http.Flusher).Flush()
+			time.Sleep(50 * time.Millisecond)
+		}
+	}))
+	defer srv.Close()
+	cli := NewClient(WithResponseHeaderTimeout(50 * time.Millisecond))
+	ctx := context.Background()
+
+	if err := cli.Get(ctx, srv.URL+"/slow-headers"); err == nil || !strings.Contains(err.Error(), "timeout awaiting response headers") {
+		t.Errorf("Get() of slow headers error = %v, want response header timeout", err)
+	}
+	var body string
+	if err := cli.Get(ctx, srv.URL+"/slow-body", WithStringResponse(&body)); err != nil || body != strings.Repeat("chunk", 4) {
+		t.Errorf("Get() of slow body = %q, %v, want 4 chunks", body, err)
+	}
+}
+
+func TestWithTLSHandshakeTimeout(t *testing.T) {
+	t.Parallel()
+	// A server accepting connections but never completing a handshake.
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	cli := NewTLSClient(&tls.Config{}, WithTLSHandshakeTimeout(50*time.Millisecond))
+	if tr := cli.(*client).client.Transport.(*http.Transport); tr.TLSHandshakeTimeout != 50*time.Millisecond {
+		t.Errorf("TLSHandshakeTimeout = %v, want 50ms", tr.TLSHandshakeTimeout)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := cli.Get(ctx, "https://"+l.Addr().String()); err == nil || !strings.Contains(err.Error(), "TLS handshake timeout") {
+		t.Errorf("Get() error = %v, want TLS handshake timeout", err)
+	}
+}
+
+func TestWithExpectContinueTimeout(t *testing.T) {
+	t.Parallel()
+	tr := NewClient(WithExpectContinueTimeout(3 * time.Second)).(*client).client.Transport.(*http.Transport)
+	if tr.ExpectContinueTimeout != 3*time.Second {
+		t.Errorf("ExpectContinueTimeout = %v, want 3s", tr.ExpectContinueTimeout)
+	}
+}
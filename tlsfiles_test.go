@@ -0,0 +1,114 @@
+package http
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewTLSClientFromFiles(t *testing.T) {
+	t.Parallel()
+	ca := newTestCA(t, "ca")
+	srv, _ := newMTLSTestServer(t, ca)
+	defer srv.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	dir := t.TempDir()
+	writeClientFiles(t, dir, ca)
+	certFile, keyFile, caFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem"), filepath.Join(dir, "ca.pem")
+
+	for _, tt := range []struct {
+		name       string
+		opts       []ClientOption
+		wantErrStr string
+	}{
+		{name: "by IP"},
+		{name: "server name", opts: []ClientOption{WithTLSServerName("localhost")}},
+		{name: "wrong server name", opts: []ClientOption{WithTLSServerName("example.com")}, wantErrStr: "example.com"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			cli, err := NewTLSClientFromFiles(certFile, keyFile, caFile, tt.opts...)
+			if err != nil {
+				t.Fatalf("NewTLSClientFromFiles() error = %v", err)
+			}
+			defer cli.Close(ctx)
+			err = cli.Get(ctx, srv.URL)
+			if tt.wantErrStr == "" && err != nil {
+				t.Errorf("Get() error = %v", err)
+			}
+			if tt.wantErrStr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErrStr)) {
+				t.Errorf("Get() error = %v, want containing %q", err, tt.wantErrStr)
+			}
+		})
+	}
+}
+
+func TestNewTLSClientFromFiles_errors(t *testing.T) {
+	t.Parallel()
+	ca := newTestCA(t, "ca")
+	dir := t.TempDir()
+	writeClientFiles(t, dir, ca)
+	if err := ioutil.WriteFile(filepath.Join(dir, "garbage.pem"), []byte("not PEM"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	path := func(name string) string { return filepath.Join(dir, name) }
+
+	for _, tt := range []struct {
+		name                      string
+		certFile, keyFile, caFile string
+		wantErrStr                string
+	}{
+		{name: "missing cert", certFile: "missing.pem", keyFile: "key.pem", caFile: "ca.pem", wantErrStr: "missing.pem"},
+		{name: "malformed cert", certFile: "garbage.pem", keyFile: "key.pem", caFile: "ca.pem", wantErrStr: "loading client certificate " + path("garbage.pem")},
+		{name: "mismatched key", certFile: "ca.pem", keyFile: "key.pem", caFile: "ca.pem", wantErrStr: "with key " + path("key.pem")},
+		{name: "malformed CA", certFile: "cert.pem", keyFile: "key.pem", caFile: "garbage.pem", wantErrStr: "no PEM root CA certificates found in " + path("garbage.pem")},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewTLSClientFromFiles(path(tt.certFile), path(tt.keyFile), path(tt.caFile))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErrStr) {
+				t.Errorf("NewTLSClientFromFiles() error = %v, want containing %q", err, tt.wantErrStr)
+			}
+			if tt.name == "missing cert" && !errors.Is(err, os.ErrNotExist) {
+				t.Errorf("NewTLSClientFromFiles() error = %v, want not exist", err)
+			}
+		})
+	}
+}
+
+func TestWithTLSServerName_root_CAs(t *testing.T) {
+	t.Parallel()
+	ca := newTestCA(t, "ca")
+	certPEM, keyPEM := ca.issueFor(t, x509.ExtKeyUsageServerAuth, []string{"example.test"}, nil)
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	srv.TLS = &tls.Config{Certificates: []tls.Certificate{cert}}
+	srv.StartTLS()
+	defer srv.Close()
+	ctx := context.Background()
+
+	// srv.URL is https://127.0.0.1:port, which the certificate does not cover.
+	if err := NewClient(WithRootCAsPEM(ca.pem)).Get(ctx, srv.URL); err == nil {
+		t.Errorf("Get() by IP without a server name error = nil, want verification failure")
+	}
+	for _, opts := range [][]ClientOption{
+		{WithRootCAsPEM(ca.pem), WithTLSServerName("example.test")},
+		{WithTLSServerName("example.test"), WithRootCAsPEM(ca.pem), WithoutSystemRootCAs()},
+	} {
+		if err := NewClient(opts...).Get(ctx, srv.URL); err != nil {
+			t.Errorf("Get() with server name error = %v", err)
+		}
+	}
+}
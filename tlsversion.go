@@ -0,0 +1,30 @@
+package http
+
+import (
+	"crypto/tls"
+)
+
+//
+// WithMinTLSVersion will refuse TLS versions older than v, such as
+// tls.VersionTLS12, whichever TLS config the client uses: its default one,
+// that of NewTLSClient or NewTLSClientFromFiles, or that of WithTransport.
+//
+func WithMinTLSVersion(v uint16) ClientOption {
+	return configureTLS("WithMinTLSVersion", func(config *tls.Config) {
+		config.MinVersion = v
+	})
+}
+
+//
+// WithCipherSuites will only negotiate these cipher suites, such as
+// tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256, applying to the TLS config
+// like WithMinTLSVersion.
+//
+// As in crypto/tls, the TLS 1.3 suites are not configurable, so restricting
+// them requires limiting connections to TLS 1.2 with the server.
+//
+func WithCipherSuites(ids ...uint16) ClientOption {
+	return configureTLS("WithCipherSuites", func(config *tls.Config) {
+		config.CipherSuites = ids
+	})
+}
@@ -0,0 +1,70 @@
+package http
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// newVersionedTLSServer starts a TLS server restricted by configure.
+func newVersionedTLSServer(t *testing.T, configure func(*tls.Config)) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	srv.TLS = &tls.Config{}
+	configure(srv.TLS)
+	srv.StartTLS()
+	return srv
+}
+
+func TestWithMinTLSVersion(t *testing.T) {
+	t.Parallel()
+	tls12 := newVersionedTLSServer(t, func(c *tls.Config) { c.MaxVersion = tls.VersionTLS12 })
+	defer tls12.Close()
+	tls13 := newVersionedTLSServer(t, func(c *tls.Config) { c.MinVersion = tls.VersionTLS13 })
+	defer tls13.Close()
+	ctx := context.Background()
+
+	for _, tt := range []struct {
+		name    string
+		srv     *httptest.Server
+		min     uint16
+		wantErr bool
+	}{
+		{name: "1.3 server, 1.2 minimum", srv: tls13, min: tls.VersionTLS12},
+		{name: "1.3 server, 1.3 minimum", srv: tls13, min: tls.VersionTLS13},
+		{name: "1.2 server, 1.2 minimum", srv: tls12, min: tls.VersionTLS12},
+		{name: "1.2 server, 1.3 minimum", srv: tls12, min: tls.VersionTLS13, wantErr: true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			cli := NewTLSClient(tt.srv.Client().Transport.(*http.Transport).TLSClientConfig, WithMinTLSVersion(tt.min))
+			if err := cli.Get(ctx, tt.srv.URL); (err != nil) != tt.wantErr {
+				t.Errorf("Get() error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWithCipherSuites(t *testing.T) {
+	t.Parallel()
+	srv := newVersionedTLSServer(t, func(c *tls.Config) {
+		c.MaxVersion = tls.VersionTLS12
+		c.CipherSuites = []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256}
+	})
+	defer srv.Close()
+	ctx := context.Background()
+	config := srv.Client().Transport.(*http.Transport).TLSClientConfig
+
+	var resp *http.Response
+	if err := NewTLSClient(config, WithCipherSuites(tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256)).Get(ctx, srv.URL, WithRawResponse(&resp)); err != nil {
+		t.Fatalf("Get() with the server suite error = %v", err)
+	}
+	resp.Body.Close()
+	if resp.TLS.CipherSuite != tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256 {
+		t.Errorf("negotiated %s, want TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256", tls.CipherSuiteName(resp.TLS.CipherSuite))
+	}
+	if err := NewTLSClient(config, WithCipherSuites(tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256)).Get(ctx, srv.URL); err == nil {
+		t.Errorf("Get() without a common suite error = nil, want handshake failure")
+	}
+}
@@ -0,0 +1,202 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// CompensateFunc undoes a completed step of a Transaction, given the value the step returned.
+type CompensateFunc func(ctx context.Context, c Client, result interface{}) error
+
+// TransactionStepOption controls the behavior of a step of a Transaction.
+type TransactionStepOption func(*txStep)
+
+// WithCompensation will call fn to undo the step if a later step fails.
+func WithCompensation(fn CompensateFunc) TransactionStepOption {
+	return func(s *txStep) {
+		s.compensate = fn
+	}
+}
+
+// WithStepRetries will run the step up to n more times after transport errors and 5xx responses.
+func WithStepRetries(n int) TransactionStepOption {
+	return func(s *txStep) {
+		s.retries = n
+	}
+}
+
+//
+// Transaction runs requests in order, undoing the completed ones with their
+// compensations, in reverse order, when a later one fails.
+//
+// Steps are GraphNodeFuncs, such as GraphRequest, and receive the results
+// of the steps before them.
+//
+type Transaction struct {
+	client Client
+	steps  []*txStep
+	dryRun bool
+}
+
+type txStep struct {
+	name       string
+	fn         GraphNodeFunc
+	compensate CompensateFunc
+	retries    int
+}
+
+// NewTransaction constructs an empty Transaction sending its requests with c.
+func NewTransaction(c Client) *Transaction {
+	return &Transaction{client: c}
+}
+
+// Step adds a step after the existing ones.
+func (tx *Transaction) Step(name string, fn GraphNodeFunc, options ...TransactionStepOption) *Transaction {
+	s := &txStep{name: name, fn: fn}
+	for _, o := range options {
+		o(s)
+	}
+	tx.steps = append(tx.steps, s)
+	return tx
+}
+
+//
+// DryRun makes Execute send nothing. Every request of the steps and
+// compensations is passed to Client.DryRun instead, and the prepared
+// requests are reported in the result.
+//
+func (tx *Transaction) DryRun() *Transaction {
+	tx.dryRun = true
+	return tx
+}
+
+// TransactionResult reports the outcome of every step of a Transaction that ran.
+type TransactionResult struct {
+	Steps []StepResult
+}
+
+// StepResult is the outcome of one step of a Transaction and of its compensation.
+type StepResult struct {
+	Name     string
+	Value    interface{}
+	Err      error
+	Attempts int
+
+	Compensated     bool
+	CompensationErr error
+
+	// Prepared holds the requests of the step and its compensation in dry-run mode.
+	Prepared []*PreparedRequest
+}
+
+// TransactionError is returned by Execute when a step fails.
+type TransactionError struct {
+	Step string
+	Err  error
+	// CompensationErrs holds the errors of the compensations that failed.
+	CompensationErrs []error
+}
+
+func (te *TransactionError) Error() string {
+	if len(te.CompensationErrs) > 0 {
+		return fmt.Sprintf("transaction step %q: %v (%d compensations failed, first: %v)", te.Step, te.Err, len(te.CompensationErrs), te.CompensationErrs[0])
+	}
+	return fmt.Sprintf("transaction step %q: %v", te.Step, te.Err)
+}
+
+func (te *TransactionError) Unwrap() error {
+	return te.Err
+}
+
+//
+// Execute runs the steps in order. If a step fails, or ctx is done before
+// the transaction completes, the compensations of the completed steps run
+// in reverse order and a *TransactionError is returned.
+//
+// Compensations run even after ctx is done, with a context keeping only its
+// values.
+//
+func (tx *Transaction) Execute(ctx context.Context) (*TransactionResult, error) {
+	result := &TransactionResult{}
+	done := map[string]GraphResult{}
+	for _, s := range tx.steps {
+		sr := StepResult{Name: s.name}
+		c := tx.clientFor(&sr)
+		if sr.Err = ctx.Err(); sr.Err == nil {
+			for sr.Attempts = 1; ; sr.Attempts++ {
+				sr.Value, sr.Err = s.fn(ctx, c, done)
+				if sr.Attempts > s.retries || !retryable(ctx, sr.Err) {
+					break
+				}
+			}
+		}
+		result.Steps = append(result.Steps, sr)
+		if sr.Err != nil {
+			return result, &TransactionError{Step: s.name, Err: sr.Err, CompensationErrs: tx.compensate(detachedContext{ctx}, result)}
+		}
+		done[s.name] = GraphResult{Value: sr.Value}
+	}
+	return result, nil
+}
+
+// compensate undoes the completed steps of result in reverse order, returning the errors of the compensations.
+func (tx *Transaction) compensate(ctx context.Context, result *TransactionResult) []error {
+	var errs []error
+	for i := len(result.Steps) - 1; i >= 0; i-- {
+		sr := &result.Steps[i]
+		s := tx.steps[i]
+		if sr.Err != nil || s.compensate == nil {
+			continue
+		}
+		sr.Compensated = true
+		if sr.CompensationErr = s.compensate(ctx, tx.clientFor(sr), sr.Value); sr.CompensationErr != nil {
+			errs = append(errs, fmt.Errorf("compensating step %q: %w", s.name, sr.CompensationErr))
+		}
+	}
+	return errs
+}
+
+// clientFor returns the client a step runs with, recording its requests into sr in dry-run mode.
+func (tx *Transaction) clientFor(sr *StepResult) Client {
+	if !tx.dryRun {
+		return tx.client
+	}
+	return &dryRunClient{Client: tx.client, prepared: &sr.Prepared}
+}
+
+// dryRunClient passes every request to DryRun instead of sending it.
+type dryRunClient struct {
+	Client
+	prepared *[]*PreparedRequest
+}
+
+func (dc *dryRunClient) Get(ctx context.Context, url string, options ...RequestOption) error {
+	return dc.Do(ctx, "GET", url, options...)
+}
+
+func (dc *dryRunClient) Post(ctx context.Context, url string, options ...RequestOption) error {
+	return dc.Do(ctx, "POST", url, options...)
+}
+
+func (dc *dryRunClient) Do(ctx context.Context, method, url string, options ...RequestOption) error {
+	pr, err := dc.Client.DryRun(ctx, method, url, options...)
+	if err != nil {
+		return err
+	}
+	*dc.prepared = append(*dc.prepared, pr)
+	return nil
+}
+
+// detachedContext keeps the values of a context but is never done.
+type detachedContext struct {
+	parent context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}       { return nil }
+func (detachedContext) Err() error                  { return nil }
+
+func (dc detachedContext) Value(key interface{}) interface{} {
+	return dc.parent.Value(key)
+}
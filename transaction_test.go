@@ -0,0 +1,149 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// newTransactionTestClient records every request and fails those in failing with a 500.
+func newTransactionTestClient(failing map[string]bool) (Client, func() []string) {
+	var mu sync.Mutex
+	var calls []string
+	c := NewMockClient(func(ctx context.Context, r *Request) error {
+		mu.Lock()
+		defer mu.Unlock()
+		calls = append(calls, r.Method+" "+r.URL)
+		if failing[r.Method+" "+r.URL] {
+			return &BadStatusError{Code: 500}
+		}
+		return nil
+	})
+	return c, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), calls...)
+	}
+}
+
+func deleteCompensation(url string) CompensateFunc {
+	return func(ctx context.Context, c Client, result interface{}) error {
+		return c.Do(ctx, "DELETE", url)
+	}
+}
+
+func newProvisioningTransaction(c Client) *Transaction {
+	return NewTransaction(c).
+		Step("account", GraphRequest("POST", "/accounts", nil), WithCompensation(deleteCompensation("/accounts"))).
+		Step("project", GraphRequest("POST", "/projects", nil), WithCompensation(deleteCompensation("/projects"))).
+		Step("billing", GraphRequest("POST", "/billing", nil))
+}
+
+func TestTransaction_success(t *testing.T) {
+	t.Parallel()
+	c, calls := newTransactionTestClient(nil)
+	result, err := newProvisioningTransaction(c).Execute(context.Background())
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if want := []string{"POST /accounts", "POST /projects", "POST /billing"}; !reflect.DeepEqual(calls(), want) {
+		t.Errorf("requests = %v, want %v", calls(), want)
+	}
+	for _, sr := range result.Steps {
+		if sr.Err != nil || sr.Compensated || sr.Attempts != 1 {
+			t.Errorf("step %s = %+v, want one successful attempt", sr.Name, sr)
+		}
+	}
+}
+
+func TestTransaction_compensates_failed_step(t *testing.T) {
+	t.Parallel()
+	c, calls := newTransactionTestClient(map[string]bool{"POST /billing": true})
+	result, err := newProvisioningTransaction(c).Execute(context.Background())
+
+	var te *TransactionError
+	if !errors.As(err, &te) || te.Step != "billing" || len(te.CompensationErrs) != 0 {
+		t.Fatalf("Execute() error = %v, want *TransactionError for billing", err)
+	}
+	want := []string{"POST /accounts", "POST /projects", "POST /billing", "DELETE /projects", "DELETE /accounts"}
+	if !reflect.DeepEqual(calls(), want) {
+		t.Errorf("requests = %v, want %v", calls(), want)
+	}
+	if !result.Steps[0].Compensated || !result.Steps[1].Compensated || result.Steps[2].Compensated {
+		t.Errorf("Execute() result = %+v, want the first two steps compensated", result.Steps)
+	}
+}
+
+func TestTransaction_compensation_fails(t *testing.T) {
+	t.Parallel()
+	c, calls := newTransactionTestClient(map[string]bool{"POST /billing": true, "DELETE /projects": true})
+	result, err := newProvisioningTransaction(c).Execute(context.Background())
+
+	var te *TransactionError
+	if !errors.As(err, &te) || len(te.CompensationErrs) != 1 {
+		t.Fatalf("Execute() error = %v, want one failed compensation", err)
+	}
+	if result.Steps[1].CompensationErr == nil || result.Steps[0].CompensationErr != nil {
+		t.Errorf("Execute() result = %+v, want only the project compensation to fail", result.Steps)
+	}
+	// A failed compensation does not stop the earlier ones.
+	if got := calls(); got[len(got)-1] != "DELETE /accounts" {
+		t.Errorf("requests = %v, want the account compensation last", got)
+	}
+}
+
+func TestTransaction_step_retries(t *testing.T) {
+	t.Parallel()
+	attempts := 0
+	c := NewMockClient(func(ctx context.Context, r *Request) error {
+		if attempts++; attempts < 3 {
+			return &BadStatusError{Code: 503}
+		}
+		return nil
+	})
+	result, err := NewTransaction(c).Step("flaky", GraphRequest("POST", "/flaky", nil), WithStepRetries(2)).Execute(context.Background())
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if result.Steps[0].Attempts != 3 {
+		t.Errorf("Attempts = %d, want 3", result.Steps[0].Attempts)
+	}
+}
+
+func TestTransaction_canceled(t *testing.T) {
+	t.Parallel()
+	c, calls := newTransactionTestClient(nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	_, err := NewTransaction(c).
+		Step("account", GraphRequest("POST", "/accounts", nil), WithCompensation(deleteCompensation("/accounts"))).
+		Step("cancel", func(ctx context.Context, c Client, deps map[string]GraphResult) (interface{}, error) {
+			cancel()
+			return nil, ctx.Err()
+		}).
+		Step("project", GraphRequest("POST", "/projects", nil)).
+		Execute(ctx)
+
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Execute() error = %v, want %v", err, context.Canceled)
+	}
+	if want := []string{"POST /accounts", "DELETE /accounts"}; !reflect.DeepEqual(calls(), want) {
+		t.Errorf("requests = %v, want %v", calls(), want)
+	}
+}
+
+func TestTransaction_dry_run(t *testing.T) {
+	t.Parallel()
+	c, calls := newTransactionTestClient(nil)
+	result, err := newProvisioningTransaction(c).DryRun().Execute(context.Background())
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if got := calls(); len(got) != 0 {
+		t.Errorf("requests = %v, want none in dry-run mode", got)
+	}
+	if pr := result.Steps[1].Prepared; len(pr) != 1 || pr[0].Method != "POST" || pr[0].URL != "/projects" {
+		t.Errorf("project Prepared = %+v, want POST /projects", pr)
+	}
+}
@@ -0,0 +1,71 @@
+package http
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"syscall"
+
+	"golang.org/x/net/http2"
+)
+
+//
+// IsRetryable reports whether a request failing with the transport error
+// err may succeed if sent again: the connection was refused, reset or
+// closed early, as an idle reused connection can be, an HTTP/2 server sent
+// GOAWAY or refused the stream, a DNS lookup failed temporarily, or a
+// network operation timed out.
+//
+// Canceled requests, expired contexts and failed TLS certificate
+// verification are terminal, as are errors it does not recognize.
+// DefaultRetryable, used by WithRetry, classifies transport errors with it.
+//
+func IsRetryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) || isTLSVerificationError(err) {
+		return false
+	}
+	for _, transient := range []error{syscall.ECONNREFUSED, syscall.ECONNRESET, syscall.ECONNABORTED, syscall.EPIPE, io.EOF, io.ErrUnexpectedEOF} {
+		if errors.Is(err, transient) {
+			return true
+		}
+	}
+	if isHTTP2Refusal(err) {
+		return true
+	}
+	var de *net.DNSError
+	if errors.As(err, &de) {
+		return de.IsTemporary || de.IsTimeout
+	}
+	var ne net.Error
+	return errors.As(err, &ne) && ne.Timeout()
+}
+
+func isTLSVerificationError(err error) bool {
+	var (
+		cve *tls.CertificateVerificationError
+		uae x509.UnknownAuthorityError
+		cie x509.CertificateInvalidError
+		he  x509.HostnameError
+		rhe tls.RecordHeaderError
+	)
+	return errors.As(err, &cve) || errors.As(err, &uae) || errors.As(err, &cie) || errors.As(err, &he) || errors.As(err, &rhe)
+}
+
+// isHTTP2Refusal reports whether err is an HTTP/2 GOAWAY or REFUSED_STREAM, which leave the request unprocessed.
+func isHTTP2Refusal(err error) bool {
+	var gae http2.GoAwayError
+	if errors.As(err, &gae) {
+		return true
+	}
+	var se http2.StreamError
+	if errors.As(err, &se) {
+		return se.Code == http2.ErrCodeRefusedStream
+	}
+	// The HTTP/2 transport bundled in net/http has unexported error types.
+	msg := err.Error()
+	return strings.Contains(msg, "http2: server sent GOAWAY") || strings.Contains(msg, "REFUSED_STREAM")
+}
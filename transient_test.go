@@ -0,0 +1,101 @@
+package http
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"syscall"
+	"testing"
+
+	"golang.org/x/net/http2"
+)
+
+func TestIsRetryable(t *testing.T) {
+	t.Parallel()
+	// wrap wraps err as net/http reports transport errors.
+	wrap := func(err error) error {
+		return &url.Error{Op: "Get", URL: "http://example.com", Err: err}
+	}
+	opErr := func(op string, err error) error {
+		return &net.OpError{Op: op, Net: "tcp", Err: os.NewSyscallError(op, err)}
+	}
+
+	for _, tt := range []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "nil", err: nil, want: false},
+		{name: "connection refused", err: wrap(opErr("dial", syscall.ECONNREFUSED)), want: true},
+		{name: "connection reset", err: wrap(opErr("read", syscall.ECONNRESET)), want: true},
+		{name: "broken pipe", err: wrap(opErr("write", syscall.EPIPE)), want: true},
+		{name: "EOF on reused connection", err: wrap(io.EOF), want: true},
+		{name: "unexpected EOF", err: wrap(fmt.Errorf("reading body: %w", io.ErrUnexpectedEOF)), want: true},
+		{name: "GOAWAY", err: wrap(http2.GoAwayError{ErrCode: http2.ErrCodeNo}), want: true},
+		{name: "bundled GOAWAY", err: wrap(errors.New("http2: server sent GOAWAY and closed the connection; LastStreamID=1")), want: true},
+		{name: "REFUSED_STREAM", err: wrap(http2.StreamError{StreamID: 3, Code: http2.ErrCodeRefusedStream}), want: true},
+		{name: "other stream error", err: wrap(http2.StreamError{StreamID: 3, Code: http2.ErrCodeProtocol}), want: false},
+		{name: "temporary DNS error", err: wrap(&net.DNSError{Err: "server misbehaving", Name: "example.com", IsTemporary: true}), want: true},
+		{name: "DNS timeout", err: wrap(&net.DNSError{Err: "i/o timeout", Name: "example.com", IsTimeout: true}), want: true},
+		{name: "unknown host", err: wrap(&net.DNSError{Err: "no such host", Name: "example.com", IsNotFound: true}), want: false},
+		{name: "read timeout", err: wrap(&net.OpError{Op: "read", Net: "tcp", Err: os.ErrDeadlineExceeded}), want: true},
+		{name: "unknown authority", err: wrap(x509.UnknownAuthorityError{}), want: false},
+		{name: "hostname mismatch", err: wrap(x509.HostnameError{Host: "example.com", Certificate: &x509.Certificate{}}), want: false},
+		{name: "canceled", err: wrap(context.Canceled), want: false},
+		{name: "deadline", err: wrap(context.DeadlineExceeded), want: false},
+		{name: "unrecognized", err: errors.New("unsupported protocol scheme"), want: false},
+	} {
+		if got := IsRetryable(tt.err); got != tt.want {
+			t.Errorf("IsRetryable(%s: %v) = %v, want %v", tt.name, tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestIsRetryableServerErrors(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	// A server closing the connection mid-request, as when an idle connection is reused after the server dropped it.
+	hangup := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		conn.Close()
+	}))
+	defer hangup.Close()
+	if err := NewClient().Get(ctx, hangup.URL); !IsRetryable(err) {
+		t.Errorf("IsRetryable(%v) = false for a closed connection, want true", err)
+	}
+
+	// A server answering with a truncated body.
+	truncated := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "100")
+		w.Write([]byte("short"))
+	}))
+	defer truncated.Close()
+	var got string
+	if err := NewClient().Get(ctx, truncated.URL, WithStringResponse(&got)); !IsRetryable(err) {
+		t.Errorf("IsRetryable(%v) = false for a truncated body, want true", err)
+	}
+
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+	if err := NewClient().Get(ctx, closed.URL); !IsRetryable(err) {
+		t.Errorf("IsRetryable(%v) = false for a refused connection, want true", err)
+	}
+
+	untrusted := httptest.NewTLSServer(http.NotFoundHandler())
+	defer untrusted.Close()
+	if err := NewClient().Get(ctx, untrusted.URL); err == nil || IsRetryable(err) {
+		t.Errorf("IsRetryable(%v) = true for an untrusted certificate, want false", err)
+	}
+}
@@ -0,0 +1,25 @@
+package http
+
+import (
+	"context"
+	"net"
+	"net/http"
+)
+
+//
+// NewUnixSocketClient constructs a Client connecting to the unix domain
+// socket at socketPath, e.g. /var/run/docker.sock, for every request.
+//
+// The host of request URLs is only used for the Host header, so a
+// placeholder can be used: http://unix/v1.41/containers/json.
+//
+func NewUnixSocketClient(socketPath string, opts ...ClientOption) Client {
+	var dialer net.Dialer
+	return newClient(http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return dialer.DialContext(ctx, "unix", socketPath)
+			},
+		},
+	}, opts)
+}
@@ -0,0 +1,49 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewUnixSocketClient(t *testing.T) {
+	t.Parallel()
+	socketPath := filepath.Join(t.TempDir(), "api.sock")
+	l, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Skipf("unix sockets unavailable: %v", err)
+	}
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var in map[string]string
+		json.NewDecoder(r.Body).Decode(&in)
+		json.NewEncoder(w).Encode(map[string]string{
+			"path":   r.URL.Path,
+			"all":    r.URL.Query().Get("all"),
+			"host":   r.Host,
+			"header": r.Header.Get("X-Registry-Auth"),
+			"image":  in["image"],
+		})
+	})}
+	go srv.Serve(l)
+	defer srv.Close()
+
+	var got map[string]string
+	err = NewUnixSocketClient(socketPath).Post(context.Background(), "http://unix/v1.41/containers/create",
+		WithParam("all", "1"),
+		WithHeader("X-Registry-Auth", "token"),
+		WithJSONBody(map[string]string{"image": "alpine"}),
+		WithJSONResponse(&got),
+	)
+	if err != nil {
+		t.Fatalf("Post() error = %v", err)
+	}
+	want := map[string]string{"path": "/v1.41/containers/create", "all": "1", "host": "unix", "header": "token", "image": "alpine"}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("server saw %s = %q, want %q", k, got[k], v)
+		}
+	}
+}
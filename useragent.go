@@ -0,0 +1,61 @@
+package http
+
+import (
+	"runtime/debug"
+)
+
+// modulePath is the module path of this package, for looking up its version.
+const modulePath = "github.com/alphaflow/gohttp"
+
+//
+// DefaultUserAgent is the User-Agent of requests from clients without
+// WithUserAgent, "gohttp/" followed by the version of this module the
+// program was built with, so that servers can tell its traffic apart from
+// other Go programs.
+//
+var DefaultUserAgent = defaultUserAgent()
+
+func defaultUserAgent() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "gohttp"
+	}
+	version := info.Main.Version
+	if info.Main.Path != modulePath {
+		version = ""
+		for _, dep := range info.Deps {
+			if dep.Path == modulePath {
+				version = dep.Version
+			}
+		}
+	}
+	if version == "" || version == "(devel)" {
+		return "gohttp"
+	}
+	return "gohttp/" + version
+}
+
+//
+// WithUserAgent will send ua as the User-Agent of every request, instead of
+// DefaultUserAgent. WithHeader("User-Agent", ...) on a request replaces it.
+//
+func WithUserAgent(ua string) ClientOption {
+	return func(c *clientConfig) {
+		c.userAgent = ua
+	}
+}
+
+// setUserAgent sets the User-Agent of the request, unless a default header already did.
+func (req *Request) setUserAgent(ua string) {
+	if req.Header.Get("User-Agent") != "" {
+		return
+	}
+	if ua == "" {
+		ua = DefaultUserAgent
+	}
+	req.Header.Set("User-Agent", ua)
+	if req.defaultHeaders == nil {
+		req.defaultHeaders = map[string]bool{}
+	}
+	req.defaultHeaders["User-Agent"] = true
+}
@@ -0,0 +1,40 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWithUserAgent(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.Join(r.Header["User-Agent"], ",")))
+	}))
+	defer srv.Close()
+
+	for _, tt := range []struct {
+		name    string
+		opts    []ClientOption
+		reqOpts []RequestOption
+		want    string
+	}{
+		{name: "default", want: DefaultUserAgent},
+		{name: "client", opts: []ClientOption{WithUserAgent("billing/1.0")}, want: "billing/1.0"},
+		{name: "request", opts: []ClientOption{WithUserAgent("billing/1.0")}, reqOpts: []RequestOption{WithHeader("User-Agent", "billing-job/2.0")}, want: "billing-job/2.0"},
+		{name: "default header", opts: []ClientOption{WithDefaultHeader("User-Agent", "from-header")}, want: "from-header"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			err := NewClient(tt.opts...).Get(context.Background(), srv.URL, append(tt.reqOpts, WithStringResponse(&got))...)
+			if err != nil || got != tt.want {
+				t.Errorf("Get() User-Agent = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+	if !strings.HasPrefix(DefaultUserAgent, "gohttp") {
+		t.Errorf("DefaultUserAgent = %q, want gohttp prefix", DefaultUserAgent)
+	}
+}
@@ -0,0 +1,40 @@
+package http
+
+import (
+	"bytes"
+)
+
+// utf8BOM is skipped at the start of every JSON response.
+var utf8BOM = []byte("\xef\xbb\xbf")
+
+// defaultXSSIPrefixes are the anti-XSSI guards stripped from JSON responses.
+var defaultXSSIPrefixes = []string{")]}',", ")]}'", "while(1);", "for(;;);"}
+
+//
+// WithXSSIPrefixes adds prefixes to strip from the start of a JSON response
+// before decoding it, on top of the common guards )]}' while(1); and for(;;);.
+//
+// A leading UTF-8 byte order mark is always skipped.
+//
+func WithXSSIPrefixes(prefixes ...string) RequestOption {
+	return func(r *Request) {
+		r.xssiPrefixes = append(r.xssiPrefixes, prefixes...)
+	}
+}
+
+// stripJSONPrefix removes a leading BOM and the longest matching XSSI guard from buf, returning what it removed.
+func (req *Request) stripJSONPrefix(buf []byte) ([]byte, string) {
+	rest := bytes.TrimPrefix(buf, utf8BOM)
+
+	var guard string
+	for _, prefixes := range [][]string{defaultXSSIPrefixes, req.xssiPrefixes} {
+		for _, p := range prefixes {
+			if len(p) > len(guard) && bytes.HasPrefix(rest, []byte(p)) {
+				guard = p
+			}
+		}
+	}
+	rest = rest[len(guard):]
+
+	return rest, string(buf[:len(buf)-len(rest)])
+}
@@ -0,0 +1,67 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestGet_json_prefixes(t *testing.T) {
+	t.Parallel()
+	prefixTestCases := []struct {
+		name    string
+		body    string
+		options []RequestOption
+		want    string
+	}{
+		{name: "plain", body: `"ok"`, want: "ok"},
+		{name: "bom", body: "\xef\xbb\xbf\"ok\"", want: "ok"},
+		{name: "guard", body: ")]}'\n\"ok\"", want: "ok"},
+		{name: "guard with comma", body: ")]}',\n\"ok\"", want: "ok"},
+		{name: "while guard", body: `while(1);"ok"`, want: "ok"},
+		{name: "bom and guard", body: "\xef\xbb\xbffor(;;);\"ok\"", want: "ok"},
+		{name: "custom guard", body: `throw 1; <dont be evil> "ok"`, options: []RequestOption{WithXSSIPrefixes("throw 1; <dont be evil>")}, want: "ok"},
+		{name: "resembles guard", body: `"while(1);"`, want: "while(1);"},
+		{name: "guard later in body", body: `" )]}'"`, want: " )]}'"},
+	}
+	for _, tt := range prefixTestCases {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(tt.body))
+			}))
+			defer srv.Close()
+			ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+			defer cancel()
+
+			var got string
+			if err := NewClient().Get(ctx, srv.URL, append(tt.options, WithJSONResponse(&got))...); err != nil {
+				t.Fatalf("Get() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Get() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGet_json_prefix_in_decode_error(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(")]}'\n{bad"))
+	}))
+	defer srv.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	var got interface{}
+	err := NewClient().Get(ctx, srv.URL, WithJSONResponse(&got))
+	var de *DecodeError
+	if !errors.As(err, &de) || de.StrippedPrefix != ")]}'" {
+		t.Errorf("Get() error = %v, want *DecodeError with StrippedPrefix %q", err, ")]}'")
+	}
+}
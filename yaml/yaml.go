@@ -0,0 +1,21 @@
+// Package yaml adds YAML support to the gohttp client.
+package yaml
+
+import (
+	http "github.com/alphaflow/gohttp"
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// ContentType is the media type of YAML documents.
+const ContentType = "application/yaml"
+
+//
+// WithYAMLResponse will YAML Unmarshal the successful HTTP response body into
+// o, sending ContentType as the Accept header.
+//
+// A malformed body is reported as an *http.DecodeError whose Err gives the
+// line of the problem. With NewMockClient, o is the Request's DecodedOutput.
+//
+func WithYAMLResponse(o interface{}) http.RequestOption {
+	return http.WithDecodedResponse("YAML", ContentType, o, yamlv3.Unmarshal)
+}
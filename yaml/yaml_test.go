@@ -0,0 +1,61 @@
+package yaml
+
+import (
+	"context"
+	"errors"
+	nethttp "net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	http "github.com/alphaflow/gohttp"
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+type serviceConfig struct {
+	Name     string   `yaml:"name"`
+	Replicas int      `yaml:"replicas"`
+	Regions  []string `yaml:"regions"`
+}
+
+func TestWithYAMLResponse_round_trip(t *testing.T) {
+	t.Parallel()
+	want := serviceConfig{Name: "api", Replicas: 3, Regions: []string{"eu", "us"}}
+	srv := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		if got := r.Header.Get("Accept"); got != ContentType {
+			t.Errorf("Accept = %q, want %q", got, ContentType)
+		}
+		buf, _ := yamlv3.Marshal(want)
+		w.Header().Set("Content-Type", ContentType)
+		w.Write(buf)
+	}))
+	defer srv.Close()
+
+	var got serviceConfig
+	if err := http.NewClient().Get(context.Background(), srv.URL, WithYAMLResponse(&got)); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Get() = %+v, want %+v", got, want)
+	}
+}
+
+func TestWithYAMLResponse_decode_error(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		w.Header().Set("Content-Type", ContentType)
+		w.Write([]byte("name: api\nreplicas: many\n"))
+	}))
+	defer srv.Close()
+
+	var got serviceConfig
+	err := http.NewClient().Get(context.Background(), srv.URL, WithYAMLResponse(&got))
+	var de *http.DecodeError
+	if !errors.As(err, &de) || de.Format != "YAML" {
+		t.Fatalf("Get() error = %v, want a YAML DecodeError", err)
+	}
+	if !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("Get() error = %v, want the line of the bad value", err)
+	}
+}